@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -26,6 +27,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -105,6 +107,64 @@ func (s *FdfsClient) CheckConnection() bool {
 }
 
 func (s *FdfsClient) callFdfsApi(method, urlPath string, arguments map[string]string) ([]byte, error) {
+	req, err := s.newRequest(method, urlPath, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// execute the request
+	response, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	req.Close = true
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		if response.StatusCode == http.StatusNoContent {
+			return nil, errors.New("no content")
+		}
+		data, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return nil, errors.New("error downloading data")
+		}
+		err = response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var resp jsonhttp.StatusResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, errors.New("error unmarshalling error response")
+		}
+		return nil, errors.New(resp.Message)
+	}
+
+	if len(response.Cookies()) > 0 {
+		s.cookie = response.Cookies()[0]
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.New("error downloading data")
+	}
+	err = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp jsonhttp.StatusResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, errors.New("error unmarshalling response")
+	}
+	if resp.Code == 0 {
+		return data, nil
+	}
+
+	return []byte(resp.Message), nil
+}
+
+func (s *FdfsClient) newRequest(method, urlPath string, arguments map[string]string) (*http.Request, error) {
 	// prepare the  request
 	fullUrl := fmt.Sprintf(s.url + urlPath)
 	var req *http.Request
@@ -141,60 +201,51 @@ func (s *FdfsClient) callFdfsApi(method, urlPath string, arguments map[string]st
 	if s.cookie != nil {
 		req.AddCookie(s.cookie)
 	}
+	return req, nil
+}
 
-	// execute the request
+// callFdfsApiWithProgress calls an api which streams its progress. Every line of a
+// streamed response is passed to onLine. A response which is not streamed is handled
+// like in callFdfsApi and its data returned.
+func (s *FdfsClient) callFdfsApiWithProgress(method, urlPath string, arguments map[string]string, onLine func(line []byte) error) ([]byte, error) {
+	req, err := s.newRequest(method, urlPath, arguments)
+	if err != nil {
+		return nil, err
+	}
 	response, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	req.Close = true
+	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
-		if response.StatusCode == http.StatusNoContent {
-			return nil, errors.New("no content")
-		}
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != api.ProgressContentType {
 		data, err := ioutil.ReadAll(response.Body)
 		if err != nil {
 			return nil, errors.New("error downloading data")
 		}
-		err = response.Body.Close()
-		if err != nil {
-			return nil, err
-		}
 		var resp jsonhttp.StatusResponse
 		err = json.Unmarshal(data, &resp)
 		if err != nil {
-			return nil, errors.New("error unmarshalling error response")
+			return nil, errors.New("error unmarshalling response")
 		}
-		return nil, errors.New(resp.Message)
-	}
-
-	if len(response.Cookies()) > 0 {
-		s.cookie = response.Cookies()[0]
-	}
-
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, errors.New("error downloading data")
-	}
-	err = response.Body.Close()
-	if err != nil {
-		return nil, err
+		if response.StatusCode != http.StatusOK {
+			return nil, errors.New(resp.Message)
+		}
+		return []byte(resp.Message), nil
 	}
 
-	var resp jsonhttp.StatusResponse
-	err = json.Unmarshal(data, &resp)
-	if err != nil {
-		return nil, errors.New("error unmarshalling response")
-	}
-	if resp.Code == 0 {
-		return data, nil
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		err = onLine(scanner.Bytes())
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	return []byte(resp.Message), nil
+	return nil, scanner.Err()
 }
 
-func (s *FdfsClient) uploadMultipartFile(urlPath, fileName string, fileSize int64, fd *os.File, arguments map[string]string, formFileArgument, compression string) ([]byte, error) {
+func (s *FdfsClient) uploadMultipartFile(urlPath, fileName string, fileSize int64, fd *os.File, arguments map[string]string, formFileArgument, compression, chunking string) ([]byte, error) {
 	body := new(bytes.Buffer)
 	writer := multipart.NewWriter(body)
 
@@ -235,6 +286,9 @@ func (s *FdfsClient) uploadMultipartFile(urlPath, fileName string, fileSize int6
 		compValue := strings.ToLower(compression)
 		req.Header.Set(api.CompressionHeader, compValue)
 	}
+	if chunking != "" {
+		req.Header.Set(api.ChunkingHeader, strings.ToLower(chunking))
+	}
 
 	if s.cookie != nil {
 		req.AddCookie(s.cookie)
@@ -320,3 +374,92 @@ func (s *FdfsClient) downloadMultipartFile(method, urlPath string, arguments map
 	}
 	return n, nil
 }
+
+// uploadBody sends the contents of fd as the body of the request, the arguments go in
+// the query of the url. The body of a fd whose length is not known, like a pipe, is
+// sent with chunked transfer encoding.
+func (s *FdfsClient) uploadBody(urlPath string, arguments, headers map[string]string, fd io.Reader) ([]byte, error) {
+	query := url.Values{}
+	for k, v := range arguments {
+		query.Set(k, v)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url+urlPath+"?"+query.Encode(), fd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if s.cookie != nil {
+		req.AddCookie(s.cookie)
+	}
+
+	// execute the request
+	response, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	req.Close = true
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.New("error downloading data")
+	}
+	var resp jsonhttp.StatusResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, errors.New("error unmarshalling response")
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Message)
+	}
+	if resp.Code == 0 {
+		return data, nil
+	}
+	return []byte(resp.Message), nil
+}
+
+// downloadRange downloads the bytes from start to end (inclusive) of a file. It
+// returns the downloaded data and the size of the whole file. If the server sends the
+// whole file instead of the range, all of it is returned.
+func (s *FdfsClient) downloadRange(urlPath string, arguments map[string]string, start, end int64) ([]byte, int64, error) {
+	req, err := s.newRequest(http.MethodPost, urlPath, arguments)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	// execute the request
+	response, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Close = true
+	defer response.Body.Close()
+
+	var size int64
+	switch response.StatusCode {
+	case http.StatusOK:
+		size = response.ContentLength
+	case http.StatusPartialContent:
+		// Content-Range: bytes start-end/size
+		contentRange := response.Header.Get("Content-Range")
+		size, err = strconv.ParseInt(contentRange[strings.LastIndex(contentRange, "/")+1:], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid content range: %s", contentRange)
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, 0, io.EOF
+	default:
+		errStr := fmt.Sprintf("received invalid status: %s", response.Status)
+		return nil, 0, errors.New(errStr)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, 0, errors.New("error downloading data")
+	}
+	return data, size, nil
+}
@@ -21,20 +21,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/dustin/go-humanize"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
@@ -43,11 +47,18 @@ import (
 )
 
 const (
-	DefaultPrompt   = "dfs"
-	UserSeperator   = ">>>"
-	PodSeperator    = ">>"
-	PromptSeperator = "> "
-	APIVersion      = "/v0"
+	DefaultPrompt    = "dfs"
+	UserSeperator    = ">>>"
+	PodSeperator     = ">>"
+	PromptSeperator  = "> "
+	DefaultHeadLines = 10
+	HeadRangeSize    = 4096 // bytes downloaded at a time by head
+	lsPageSize       = 100  // entries listed at a time by ls
+	APIVersion       = "/v0"
+
+	// an upload source of stdinSourcePrefix followed by a file name reads the data
+	// piped to dfs-cli, the prompt itself reads the terminal
+	stdinSourcePrefix = "-:"
 )
 
 var (
@@ -71,6 +82,9 @@ const (
 	apiUserExport      = APIVersion + "/user/export"
 	apiUserDelete      = APIVersion + "/user/delete"
 	apiUserStat        = APIVersion + "/user/stat"
+	apiUserGC          = APIVersion + "/user/gc"
+	apiUserDu          = APIVersion + "/user/du"
+	apiUserBatch       = APIVersion + "/user/batch"
 	apiUserShareInbox  = APIVersion + "/user/share/inbox"
 	apiUserShareOutbox = APIVersion + "/user/share/outbox"
 	apiPodNew          = APIVersion + "/pod/new"
@@ -80,6 +94,13 @@ const (
 	apiPodDelete       = APIVersion + "/pod/delete"
 	apiPodLs           = APIVersion + "/pod/ls"
 	apiPodStat         = APIVersion + "/pod/stat"
+	apiPodVersioning   = APIVersion + "/pod/versioning"
+	apiPodBatch        = APIVersion + "/pod/batch"
+	apiPodSnapshot     = APIVersion + "/pod/snapshot/new"
+	apiPodSnapshotLs   = APIVersion + "/pod/snapshot/ls"
+	apiPodSnapshotOpen = APIVersion + "/pod/snapshot/open"
+	apiPodSnapshotRest = APIVersion + "/pod/snapshot/restore"
+	apiPodSnapshotDel  = APIVersion + "/pod/snapshot/delete"
 	apiPodShare        = APIVersion + "/pod/share"
 	apiPodReceive      = APIVersion + "/pod/receive"
 	apiPodReceiveInfo  = APIVersion + "/pod/receiveinfo"
@@ -87,6 +108,10 @@ const (
 	apiDirMkdir        = APIVersion + "/dir/mkdir"
 	apiDirRmdir        = APIVersion + "/dir/rmdir"
 	apiDirLs           = APIVersion + "/dir/ls"
+	apiDirFind         = APIVersion + "/dir/find"
+	apiDirDu           = APIVersion + "/dir/du"
+	apiPodTree         = APIVersion + "/pod/tree"
+	apiPodDiff         = APIVersion + "/pod/diff"
 	apiDirStat         = APIVersion + "/dir/stat"
 	apiFileDownload    = APIVersion + "/file/download"
 	apiFileUpload      = APIVersion + "/file/upload"
@@ -95,6 +120,14 @@ const (
 	apiFileReceiveInfo = APIVersion + "/file/receiveinfo"
 	apiFileDelete      = APIVersion + "/file/delete"
 	apiFileStat        = APIVersion + "/file/stat"
+	apiFileMove        = APIVersion + "/file/mv"
+	apiFileCopy        = APIVersion + "/file/cp"
+	apiFileVerify      = APIVersion + "/file/verify"
+	apiFileVersions    = APIVersion + "/file/versions"
+	apiFileRestore     = APIVersion + "/file/restore"
+	apiFilePrune       = APIVersion + "/file/prune"
+	apiFileAppend      = APIVersion + "/file/append"
+	apiFileWrite       = APIVersion + "/file/write"
 	apiKVCreate        = APIVersion + "/kv/new"
 	apiKVList          = APIVersion + "/kv/ls"
 	apiKVOpen          = APIVersion + "/kv/open"
@@ -166,6 +199,9 @@ var suggestions = []prompt.Suggest{
 	{Text: "user export ", Description: "exports the user"},
 	{Text: "user import ", Description: "imports the user"},
 	{Text: "user stat ", Description: "shows information about a user"},
+	{Text: "user gc ", Description: "removes the blobs the user no longer references"},
+	{Text: "user du", Description: "shows the storage taken by all the pods of the user"},
+	{Text: "user batch", Description: "sets and gets the postage batch of the user"},
 	{Text: "pod new", Description: "create a new pod for a user"},
 	{Text: "pod del", Description: "delete a existing pod of a user"},
 	{Text: "pod open", Description: "open to a existing pod of a user"},
@@ -173,6 +209,9 @@ var suggestions = []prompt.Suggest{
 	{Text: "pod ls", Description: "list all the existing pods of  auser"},
 	{Text: "pod stat", Description: "show the metadata of a pod of a user"},
 	{Text: "pod sync", Description: "sync the pod from swarm"},
+	{Text: "pod versioning", Description: "turn the version history of the files of the pod on or off"},
+	{Text: "pod batch", Description: "sets the postage batch of a pod"},
+	{Text: "pod snapshot", Description: "freeze, list, open or restore the state of a pod"},
 	{Text: "kv new", Description: "create new key value store"},
 	{Text: "kv delete", Description: "delete the  key value store"},
 	{Text: "kv ls", Description: "lists all the key value stores"},
@@ -203,9 +242,20 @@ var suggestions = []prompt.Suggest{
 	{Text: "help", Description: "show usage"},
 	{Text: "ls", Description: "list all the file and directories in the current path"},
 	{Text: "mkdir", Description: "make a new directory"},
-	{Text: "rmdir", Description: "remove a existing directory"},
+	{Text: "rmdir", Description: "remove a existing directory, -r removes a directory which is not empty"},
 	{Text: "pwd", Description: "show the current working directory"},
 	{Text: "rm", Description: "remove a file"},
+	{Text: "mv", Description: "move or rename a file or directory"},
+	{Text: "cp", Description: "copy a file or directory, also to another pod"},
+	{Text: "verify", Description: "check a file or all the files of the pod for missing or corrupt blocks"},
+	{Text: "versions", Description: "list the versions of a file"},
+	{Text: "restore", Description: "make an older version of a file the current one"},
+	{Text: "prune", Description: "drop all but the newest versions of a file"},
+	{Text: "append", Description: "append a local file to a file in the pod"},
+	{Text: "find", Description: "search the files and directories below a directory"},
+	{Text: "du", Description: "show the storage taken below a directory"},
+	{Text: "diff", Description: "compare the trees of pods, snapshots or local directories"},
+	{Text: "write", Description: "write a local file over a file in the pod from an offset"},
 }
 
 func completer(in prompt.Document) []prompt.Suggest {
@@ -587,6 +637,105 @@ func executor(in string) {
 			fmt.Println("user name: ", resp.Name)
 			fmt.Println("Reference: ", resp.Reference)
 			currentPrompt = getCurrentPrompt()
+		case "gc":
+			if currentUser == "" {
+				fmt.Println("please login as user to do the operation")
+				return
+			}
+			args := make(map[string]string)
+			args["password"] = getPassword()
+			if len(blocks) > 2 && blocks[2] == "dry-run" {
+				args["dry_run"] = "true"
+			}
+			data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiUserGC, args)
+			if err != nil {
+				fmt.Println("user gc: ", err)
+				return
+			}
+			var resp gc.Report
+			err = json.Unmarshal(data, &resp)
+			if err != nil {
+				fmt.Println("user gc: ", err)
+				return
+			}
+			fmt.Println("dry run          : ", resp.DryRun)
+			fmt.Println("recorded blobs   : ", resp.Recorded)
+			fmt.Println("live blobs       : ", resp.Live)
+			fmt.Println("kept blobs       : ", resp.Young+resp.Shared)
+			fmt.Println("orphaned blobs   : ", resp.Orphaned)
+			fmt.Println("reclaimable bytes: ", resp.ReclaimableBytes)
+			fmt.Println("removed blobs    : ", resp.Removed)
+			fmt.Println("failed blobs     : ", resp.Failed)
+			currentPrompt = getCurrentPrompt()
+		case "du":
+			if currentUser == "" {
+				fmt.Println("please login as user to do the operation")
+				return
+			}
+			args := make(map[string]string)
+			args["password"] = getPassword()
+			data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiUserDu, args)
+			if err != nil {
+				fmt.Println("user du: ", err)
+				return
+			}
+			var resp pod.UserDiskUsage
+			err = json.Unmarshal(data, &resp)
+			if err != nil {
+				fmt.Println("user du: ", err)
+				return
+			}
+			var podNames []string
+			for podName := range resp.Pods {
+				podNames = append(podNames, podName)
+			}
+			sort.Strings(podNames)
+			for _, podName := range podNames {
+				printDiskUsage(podName, resp.Pods[podName])
+			}
+			printDiskUsage("total", resp.Total)
+			currentPrompt = getCurrentPrompt()
+		case "batch":
+			if currentUser == "" {
+				fmt.Println("please login as user to do the operation")
+				return
+			}
+			if len(blocks) > 2 {
+				args := make(map[string]string)
+				if blocks[2] != "default" {
+					args["batch"] = blocks[2]
+				}
+				data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiUserBatch, args)
+				if err != nil {
+					fmt.Println("user batch: ", err)
+					return
+				}
+				message := strings.ReplaceAll(string(data), "\n", "")
+				fmt.Println(message)
+				currentPrompt = getCurrentPrompt()
+				return
+			}
+			data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiUserBatch, nil)
+			if err != nil {
+				fmt.Println("user batch: ", err)
+				return
+			}
+			var resp api.PostageBatchResponse
+			err = json.Unmarshal(data, &resp)
+			if err != nil {
+				fmt.Println("user batch: ", err)
+				return
+			}
+			fmt.Println("batch     : ", resp.BatchID)
+			if resp.Usage != nil {
+				fmt.Println("chunks    : ", resp.Usage.Chunks)
+				fmt.Println("bytes     : ", resp.Usage.Bytes)
+				if resp.Usage.Capacity != 0 {
+					fmt.Println("capacity  : ", resp.Usage.Capacity)
+				}
+				fmt.Println("exhausted : ", resp.Usage.Exhausted)
+			}
+			currentPrompt = getCurrentPrompt()
 		case "avatar":
 			if currentUser == "" {
 				fmt.Println("please login as user to do the operation")
@@ -621,7 +770,7 @@ func executor(in string) {
 					fmt.Println("avatar file stat failed: ", err)
 					return
 				}
-				data, err := fdfsAPI.uploadMultipartFile(apiUserAvatar, fileName, fi.Size(), fd, nil, "avatar", "false")
+				data, err := fdfsAPI.uploadMultipartFile(apiUserAvatar, fileName, fi.Size(), fd, nil, "avatar", "false", "")
 				if err != nil {
 					fmt.Println("upload failed: ", err, string(data))
 					return
@@ -788,6 +937,53 @@ func executor(in string) {
 			fmt.Println("Creation Time    :", time.Unix(crTime, 0).String())
 			fmt.Println("Access Time      :", time.Unix(accTime, 0).String())
 			fmt.Println("Modification Time:", time.Unix(modTime, 0).String())
+			fmt.Println("Versioning       : ", resp.Versioning)
+			if resp.Snapshot != "" {
+				fmt.Println("Snapshot         : ", resp.Snapshot)
+			}
+			currentPrompt = getCurrentPrompt()
+		case "versioning":
+			if !isPodOpened() {
+				return
+			}
+			if len(blocks) < 3 || (blocks[2] != "on" && blocks[2] != "off") {
+				fmt.Println("invalid command. Missing or invalid \"on/off\" argument ")
+				return
+			}
+			args := make(map[string]string)
+			args["enable"] = strconv.FormatBool(blocks[2] == "on")
+			data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiPodVersioning, args)
+			if err != nil {
+				fmt.Println("pod versioning failed: ", err)
+				return
+			}
+			message := strings.ReplaceAll(string(data), "\n", "")
+			fmt.Println(message)
+			currentPrompt = getCurrentPrompt()
+		case "batch":
+			if currentUser == "" {
+				fmt.Println("please login as user to do the operation")
+				return
+			}
+			if len(blocks) < 4 {
+				fmt.Println("invalid command. Missing \"pod-name\" or \"batch-id\" argument ")
+				return
+			}
+			args := make(map[string]string)
+			args["pod"] = blocks[2]
+			if blocks[3] != "default" {
+				args["batch"] = blocks[3]
+			}
+			data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiPodBatch, args)
+			if err != nil {
+				fmt.Println("pod batch: ", err)
+				return
+			}
+			message := strings.ReplaceAll(string(data), "\n", "")
+			fmt.Println(message)
+			currentPrompt = getCurrentPrompt()
+		case "snapshot":
+			podSnapshot(blocks[2:])
 			currentPrompt = getCurrentPrompt()
 		case "sync":
 			if !isPodOpened() {
@@ -1078,7 +1274,7 @@ func executor(in string) {
 
 			args := make(map[string]string)
 			args["name"] = tableName
-			data, err := fdfsAPI.uploadMultipartFile(apiKVLoadCSV, fileName, fi.Size(), fd, args, "csv", "false")
+			data, err := fdfsAPI.uploadMultipartFile(apiKVLoadCSV, fileName, fi.Size(), fd, args, "csv", "false", "")
 			if err != nil {
 				fmt.Println("loadcsv: ", err)
 				return
@@ -1433,7 +1629,7 @@ func executor(in string) {
 
 			args := make(map[string]string)
 			args["name"] = tableName
-			data, err := fdfsAPI.uploadMultipartFile(apiDocLoadJson, fileName, fi.Size(), fd, args, "json", "false")
+			data, err := fdfsAPI.uploadMultipartFile(apiDocLoadJson, fileName, fi.Size(), fd, args, "json", "false", "")
 			if err != nil {
 				fmt.Println("loadjson: ", err)
 				return
@@ -1528,23 +1724,62 @@ func executor(in string) {
 		}
 		args := make(map[string]string)
 		args["dir"] = currentDirectory
-		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiDirLs, args)
-		if err != nil {
-			fmt.Println("ls failed: ", err)
-			return
-		}
-		var resp api.ListFileResponse
-		err = json.Unmarshal(data, &resp)
-		if err != nil {
-			fmt.Println("dir ls: ", err)
-			return
+		args["limit"] = strconv.Itoa(lsPageSize)
+		long := false
+		for _, arg := range blocks[1:] {
+			switch arg {
+			case "":
+			case "-l":
+				long = true
+				args["detail"] = "true"
+			case "-S":
+				args["sort"] = "size"
+			case "-t":
+				args["sort"] = "mtime"
+			case "-r":
+				args["order"] = "desc"
+			default:
+				args["prefix"] = arg
+			}
 		}
-		for _, entry := range resp.Entries {
-			if entry.ContentType == "inode/directory" {
-				fmt.Println("<Dir>: ", entry.Name)
-			} else {
-				fmt.Println("<File>: ", entry.Name)
+
+		// the entries are fetched a page at a time
+		for {
+			data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiDirLs, args)
+			if err != nil {
+				fmt.Println("ls failed: ", err)
+				return
+			}
+			var resp api.ListFileResponse
+			err = json.Unmarshal(data, &resp)
+			if err != nil {
+				fmt.Println("dir ls: ", err)
+				return
 			}
+			for _, entry := range resp.Entries {
+				kind := "<File>: "
+				if entry.ContentType == "inode/directory" {
+					kind = "<Dir>: "
+				}
+				if !long {
+					fmt.Println(kind, entry.Name)
+					continue
+				}
+				modTime, err := strconv.ParseInt(entry.ModificationTime, 10, 64)
+				if err != nil {
+					fmt.Println("dir ls: ", err)
+					return
+				}
+				size := entry.Size
+				if size == "" {
+					size = "-"
+				}
+				fmt.Printf("%-8s %12s  %s  %-24s %s\n", kind, size, time.Unix(modTime, 0).Format("2006-01-02 15:04:05"), entry.ContentType, entry.Name)
+			}
+			if resp.NextCursor == "" {
+				break
+			}
+			args["cursor"] = resp.NextCursor
 		}
 		currentPrompt = getCurrentPrompt()
 	case "mkdir":
@@ -1581,6 +1816,11 @@ func executor(in string) {
 		if !isPodOpened() {
 			return
 		}
+		recursive := false
+		if len(blocks) > 1 && blocks[1] == "-r" {
+			recursive = true
+			blocks = append(blocks[:1], blocks[2:]...)
+		}
 		if len(blocks) < 2 {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
@@ -1601,13 +1841,33 @@ func executor(in string) {
 
 		args := make(map[string]string)
 		args["dir"] = dirToRm
-		data, err := fdfsAPI.callFdfsApi(http.MethodDelete, apiDirRmdir, args)
+		args["recursive"] = strconv.FormatBool(recursive)
+		args["progress"] = "true"
+		data, err := fdfsAPI.callFdfsApiWithProgress(http.MethodDelete, apiDirRmdir, args, func(data []byte) error {
+			var line api.RmdirProgressLine
+			err := json.Unmarshal(data, &line)
+			if err != nil {
+				return err
+			}
+			if line.Code == 0 {
+				fmt.Printf("\rremoved %d/%d files, %d/%d dirs", line.FilesRemoved, line.TotalFiles, line.DirsRemoved, line.TotalDirs)
+				return nil
+			}
+			fmt.Println()
+			if line.Code != http.StatusOK {
+				return errors.New(line.Message)
+			}
+			fmt.Println(line.Message)
+			return nil
+		})
 		if err != nil {
 			fmt.Println("rmdir failed: ", err)
 			return
 		}
-		message := strings.ReplaceAll(string(data), "\n", "")
-		fmt.Println(message)
+		if data != nil {
+			message := strings.ReplaceAll(string(data), "\n", "")
+			fmt.Println(message)
+		}
 		currentPrompt = getCurrentPrompt()
 	case "upload":
 		if !isPodOpened() {
@@ -1617,17 +1877,6 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		fileName := filepath.Base(blocks[1])
-		fd, err := os.Open(blocks[1])
-		if err != nil {
-			fmt.Println("upload failed: ", err)
-			return
-		}
-		fi, err := fd.Stat()
-		if err != nil {
-			fmt.Println("upload failed: ", err)
-			return
-		}
 		podDir := blocks[2]
 		if podDir == "." {
 			podDir = currentDirectory
@@ -1637,10 +1886,47 @@ func executor(in string) {
 		if len(blocks) >= 5 {
 			compression = blocks[4]
 		}
+		chunking := ""
+		if len(blocks) >= 6 {
+			chunking = blocks[5]
+		}
 		args := make(map[string]string)
 		args["pod_dir"] = podDir
 		args["block_size"] = blockSize
-		data, err := fdfsAPI.uploadMultipartFile(apiFileUpload, fileName, fi.Size(), fd, args, "files", compression)
+
+		// the data piped to the prompt is streamed as it comes, its length is not known
+		var data []byte
+		var err error
+		if strings.HasPrefix(blocks[1], stdinSourcePrefix) {
+			args["file_name"] = strings.TrimPrefix(blocks[1], stdinSourcePrefix)
+			if args["file_name"] == "" {
+				fmt.Println("invalid command. Missing file name after \"" + stdinSourcePrefix + "\"")
+				return
+			}
+			headers := make(map[string]string)
+			if compression != "" {
+				headers[api.CompressionHeader] = strings.ToLower(compression)
+			}
+			if chunking != "" {
+				headers[api.ChunkingHeader] = strings.ToLower(chunking)
+			}
+			data, err = fdfsAPI.uploadBody(apiFileUpload, args, headers, os.Stdin)
+		} else {
+			var fd *os.File
+			fd, err = os.Open(blocks[1])
+			if err != nil {
+				fmt.Println("upload failed: ", err)
+				return
+			}
+			defer fd.Close()
+			var fi os.FileInfo
+			fi, err = fd.Stat()
+			if err != nil {
+				fmt.Println("upload failed: ", err)
+				return
+			}
+			data, err = fdfsAPI.uploadMultipartFile(apiFileUpload, filepath.Base(blocks[1]), fi.Size(), fd, args, "files", compression, chunking)
+		}
 		if err != nil {
 			fmt.Println("upload failed: ", err)
 			return
@@ -1692,6 +1978,9 @@ func executor(in string) {
 		}
 		args := make(map[string]string)
 		args["file"] = podFile
+		if len(blocks) > 3 {
+			args["version"] = blocks[3]
+		}
 		n, err := fdfsAPI.downloadMultipartFile(http.MethodPost, apiFileDownload, args, out)
 		if err != nil {
 			fmt.Println("download failed: ", err)
@@ -1776,12 +2065,22 @@ func executor(in string) {
 				fmt.Println("File Size	   	: ", resp.FileSize)
 				fmt.Println("Block Size	   	: ", resp.BlockSize)
 				fmt.Println("Compression   		: ", compression)
+				if resp.Chunking != "" {
+					fmt.Println("Chunking   		: ", resp.Chunking)
+				}
 				fmt.Println("Content Type  		: ", resp.ContentType)
 				fmt.Println("Cr. Time	   	: ", time.Unix(crTime, 0).String())
 				fmt.Println("Mo. Time	   	: ", time.Unix(accTime, 0).String())
 				fmt.Println("Ac. Time	   	: ", time.Unix(modTime, 0).String())
+				fmt.Println("Dedup Ratio   		: ", resp.DedupRatio)
+				if resp.Checksum != "" {
+					fmt.Println("Checksum   		: ", resp.Checksum)
+				}
 				for _, b := range resp.Blocks {
 					blkStr := fmt.Sprintf("%s, 0x%s, %s bytes, %s bytes", b.Name, b.Reference, b.Size, b.CompressedSize)
+					if b.Compression != "" {
+						blkStr += ", " + b.Compression
+					}
 					fmt.Println(blkStr)
 				}
 			} else {
@@ -1953,9 +2252,302 @@ func executor(in string) {
 		fmt.Println("SharedTime     : ", shTime)
 		currentPrompt = getCurrentPrompt()
 	case "mv":
-		fmt.Println("not yet implemented")
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		args := make(map[string]string)
+		args["source"] = podPathFromCurrentDirectory(blocks[1])
+		args["destination"] = podPathFromCurrentDirectory(blocks[2])
+		data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiFileMove, args)
+		if err != nil {
+			fmt.Println("mv failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+		currentPrompt = getCurrentPrompt()
+	case "cp":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		args := make(map[string]string)
+		args["source"] = podPathFromCurrentDirectory(blocks[1])
+		if len(blocks) > 3 && blocks[3] != currentPod {
+			// the current directory is not the one of the other pod
+			args["destination"] = blocks[2]
+			args["pod"] = blocks[3]
+		} else {
+			args["destination"] = podPathFromCurrentDirectory(blocks[2])
+		}
+		data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiFileCopy, args)
+		if err != nil {
+			fmt.Println("cp failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+		currentPrompt = getCurrentPrompt()
 	case "head":
-		fmt.Println("not yet implemented")
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 2 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		lines := DefaultHeadLines
+		if len(blocks) > 2 {
+			n, err := strconv.Atoi(blocks[2])
+			if err != nil || n < 0 {
+				fmt.Println("invalid number of lines: ", blocks[2])
+				return
+			}
+			lines = n
+		}
+		args := make(map[string]string)
+		args["file"] = podPathFromCurrentDirectory(blocks[1])
+
+		// download the file a range at a time till there are enough lines
+		var data []byte
+		for offset := int64(0); bytes.Count(data, []byte("\n")) < lines; {
+			part, size, err := fdfsAPI.downloadRange(apiFileDownload, args, offset, offset+HeadRangeSize-1)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fmt.Println("head failed: ", err)
+				return
+			}
+			data = append(data, part...)
+			offset += int64(len(part))
+			if len(part) == 0 || offset >= size {
+				break
+			}
+		}
+		for i := 0; i < lines && len(data) > 0; i++ {
+			line := data
+			if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+				line = data[:idx]
+				data = data[idx+1:]
+			} else {
+				data = nil
+			}
+			fmt.Println(string(line))
+		}
+		currentPrompt = getCurrentPrompt()
+	case "verify":
+		if !isPodOpened() {
+			return
+		}
+		args := make(map[string]string)
+		if len(blocks) > 1 && blocks[1] != "" {
+			args["file"] = podPathFromCurrentDirectory(blocks[1])
+		}
+		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiFileVerify, args)
+		if err != nil {
+			fmt.Println("verify failed: ", err)
+			return
+		}
+		var resp api.FileVerifyResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("verify failed: ", err)
+			return
+		}
+		for _, result := range resp.Files {
+			switch {
+			case result.Ok:
+				fmt.Println(result.FilePath, ": ok")
+			case result.MissingInode:
+				fmt.Println(result.FilePath, ": inode missing")
+			case result.ChecksumMismatch:
+				fmt.Println(result.FilePath, ": file checksum mismatch")
+			default:
+				if len(result.MissingBlocks) > 0 {
+					fmt.Println(result.FilePath, ": missing blocks ", strings.Join(result.MissingBlocks, ", "))
+				}
+				if len(result.CorruptBlocks) > 0 {
+					fmt.Println(result.FilePath, ": corrupt blocks ", strings.Join(result.CorruptBlocks, ", "))
+				}
+			}
+		}
+		currentPrompt = getCurrentPrompt()
+	case "versions":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 2 {
+			fmt.Println("invalid command. Missing \"file name\" argument ")
+			return
+		}
+		args := make(map[string]string)
+		args["file"] = podPathFromCurrentDirectory(blocks[1])
+		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiFileVersions, args)
+		if err != nil {
+			fmt.Println("versions failed: ", err)
+			return
+		}
+		var resp api.FileVersionsResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("versions failed: ", err)
+			return
+		}
+		for _, version := range resp.Versions {
+			modTime, err := strconv.ParseInt(version.ModificationTime, 10, 64)
+			if err != nil {
+				fmt.Println("versions failed: ", err)
+				return
+			}
+			current := ""
+			if version.Current {
+				current = " (current)"
+			}
+			fmt.Println(version.Version, " ", version.FileSize, " bytes ", time.Unix(modTime, 0).String(), current)
+		}
+		currentPrompt = getCurrentPrompt()
+	case "restore":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		args := make(map[string]string)
+		args["file"] = podPathFromCurrentDirectory(blocks[1])
+		args["version"] = blocks[2]
+		data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiFileRestore, args)
+		if err != nil {
+			fmt.Println("restore failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+		currentPrompt = getCurrentPrompt()
+	case "prune":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		args := make(map[string]string)
+		args["file"] = podPathFromCurrentDirectory(blocks[1])
+		args["keep"] = blocks[2]
+		data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiFilePrune, args)
+		if err != nil {
+			fmt.Println("prune failed: ", err)
+			return
+		}
+		var resp api.FilePruneResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("prune failed: ", err)
+			return
+		}
+		fmt.Println("pruned ", resp.Pruned, " versions")
+		currentPrompt = getCurrentPrompt()
+	case "du":
+		if !isPodOpened() {
+			return
+		}
+		dirToDu := currentDirectory
+		if len(blocks) > 1 {
+			dirToDu = podPathFromCurrentDirectory(blocks[1])
+		}
+		args := make(map[string]string)
+		args["dir"] = dirToDu
+		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiDirDu, args)
+		if err != nil {
+			fmt.Println("du failed: ", err)
+			return
+		}
+		var resp pod.DiskUsage
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("du failed: ", err)
+			return
+		}
+		printDiskUsage(resp.Path, &resp)
+		currentPrompt = getCurrentPrompt()
+	case "find":
+		if !isPodOpened() {
+			return
+		}
+		args, err := findArguments(blocks[1:])
+		if err != nil {
+			fmt.Println("find failed: ", err)
+			return
+		}
+		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiDirFind, args)
+		if err != nil {
+			fmt.Println("find failed: ", err)
+			return
+		}
+		var resp api.FindResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("find failed: ", err)
+			return
+		}
+		for _, entry := range resp.Entries {
+			if entry.Type == dir.ListTypeDir {
+				fmt.Println("<Dir>: ", entry.Path)
+			} else {
+				fmt.Println("<File>: ", entry.Path, " ", entry.Size, " bytes")
+			}
+		}
+		if resp.Truncated {
+			fmt.Println("more results left out, raise -limit to see them")
+		}
+		currentPrompt = getCurrentPrompt()
+	case "diff":
+		if currentUser == "" {
+			fmt.Println("please login as user to do the operation")
+			return
+		}
+		podDiff(blocks[1:])
+		currentPrompt = getCurrentPrompt()
+	case "append", "write":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 || (blocks[0] == "write" && len(blocks) < 4) {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		fd, err := os.Open(blocks[1])
+		if err != nil {
+			fmt.Println(blocks[0], " failed: ", err)
+			return
+		}
+		defer fd.Close()
+
+		args := make(map[string]string)
+		args["file"] = podPathFromCurrentDirectory(blocks[2])
+		urlPath := apiFileAppend
+		if blocks[0] == "write" {
+			args["offset"] = blocks[3]
+			urlPath = apiFileWrite
+		}
+		data, err := fdfsAPI.uploadBody(urlPath, args, nil, fd)
+		if err != nil {
+			fmt.Println(blocks[0], " failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+		currentPrompt = getCurrentPrompt()
 	default:
 		fmt.Println("invalid command")
 	}
@@ -1979,6 +2571,10 @@ func help() {
 	fmt.Println(" - user <import> (user-name) (address) - imports the user to another device")
 	fmt.Println(" - user <import> (user-name) (12 word mnemonic) - imports the user if the device is lost")
 	fmt.Println(" - user <stat> - shows information about a user")
+	fmt.Println(" - user <gc> (dry-run) - removes the blobs the user no longer references, dry-run only reports them")
+	fmt.Println(" - user <du> - shows the storage taken by each pod of the user and by all of them")
+	fmt.Println(" - user <batch> - shows the postage batch of the uploads and how much of it was used")
+	fmt.Println(" - user <batch> (batch-id/default) - makes the uploads of the user use the postage batch")
 
 	fmt.Println(" - pod <new> (pod-name) - create a new pod for the logged in user and opens the pod")
 	fmt.Println(" - pod <del> (pod-name) - deletes a already created pod of the user")
@@ -1987,6 +2583,13 @@ func help() {
 	fmt.Println(" - pod <sync> (pod-name) - sync the contents of a logged in pod from Swarm")
 	fmt.Println(" - pod <close>  - close a opened pod")
 	fmt.Println(" - pod <ls> - lists all the pods created for this account")
+	fmt.Println(" - pod <versioning> (on/off) - keeps the older versions of the files uploaded again to the open pod")
+	fmt.Println(" - pod <batch> (pod-name) (batch-id/default) - makes the uploads done in the pod use the postage batch")
+	fmt.Println(" - pod <snapshot> <new> (snapshot-name) - freezes the open pod under the snapshot name")
+	fmt.Println(" - pod <snapshot> <ls> - lists the snapshots of all the pods")
+	fmt.Println(" - pod <snapshot> <open> (snapshot-name) - opens the pod of a snapshot read only, as it was then")
+	fmt.Println(" - pod <snapshot> <restore> (snapshot-name) (pod-name) - creates a new pod from a snapshot and opens it")
+	fmt.Println(" - pod <snapshot> <del> (snapshot-name) - forgets a snapshot")
 
 	fmt.Println(" - kv <new> (table-name) - creates a new key value store")
 	fmt.Println(" - kv <delete> (table-name) - deletes the key value store")
@@ -2012,23 +2615,316 @@ func help() {
 	fmt.Println(" - doc <indexjson> (table-name) (pod json file) - Index the json file in pod to the document db")
 
 	fmt.Println(" - cd <directory name>")
-	fmt.Println(" - ls ")
-	fmt.Println(" - download <destination dir in local fs, relative path of source file in pod>")
-	fmt.Println(" - upload <source file in local fs, destination directory in pod, block size (ex: 1Mb, 64Mb)>, compression true/false")
+	fmt.Println(" - ls (-l) (-S or -t) (-r) (name prefix) - lists the current directory, -l with details, -S by size, -t by modification time, -r in reverse")
+	fmt.Println(" - download <destination dir in local fs, relative path of source file in pod> (version)")
+	fmt.Println(" - upload <source file in local fs, destination directory in pod, block size (ex: 1Mb, 64Mb)>, compression gzip/snappy/zstd/lz4 with an optional level (ex: zstd:19) or auto, chunking cdc for content defined blocks of the average block size")
+	fmt.Println("   a source of -:<file name> uploads the data piped to dfs-cli as <file name>, till the pipe closes")
 	fmt.Println(" - share <file name> -  shares a file with another user")
 	fmt.Println(" - receive <sharing reference> <pod dir> - receives a file from another user")
 	fmt.Println(" - receiveinfo <sharing reference> - shows the received file info before accepting the receive")
 	fmt.Println(" - mkdir <directory name>")
-	fmt.Println(" - rmdir <directory name>")
+	fmt.Println(" - rmdir (-r) <directory name> - removes a directory, -r also removes everything in it")
 	fmt.Println(" - rm <file name>")
+	fmt.Println(" - mv <source> <destination> - moves a file or directory, into the destination if it is a directory")
+	fmt.Println(" - cp <source> <destination> (pod-name) - copies a file or directory, to the destination in another open pod if given")
 	fmt.Println(" - pwd - show present working directory")
 	fmt.Println(" - cat  - stream the file to stdout")
+	fmt.Println(" - head <file name> (lines) - shows the first lines of a file, 10 if not given")
 	fmt.Println(" - stat <file name or directory name> - shows the information about a file or directory")
+	fmt.Println(" - verify (file name) - checks a file, or all the files of the pod, for missing or corrupt blocks")
+	fmt.Println(" - versions <file name> - lists the versions of a file")
+	fmt.Println(" - restore <file name> <version> - makes an older version of a file the current one")
+	fmt.Println(" - prune <file name> <keep> - drops all but the newest versions of a file")
+	fmt.Println(" - du (directory name) - shows the storage taken by the files and directories below a directory")
+	fmt.Println(" - diff (pod:name/snapshot:name/local:dir) (pod:name/snapshot:name/local:dir) (-dir pod directory) (-json) - lists what was added, removed, modified and moved from the first tree to the second, files of the same size without a checksum are unknown")
+	fmt.Println(" - find (directory name) (-name glob) (-type f/d) (-ctype content type prefix) (-min-size 1Mb) (-max-size 1Gb) (-newer 24h) (-older 24h) (-limit n) - searches the files and directories below a directory")
+	fmt.Println(" - append <source file in local fs> <file name> - appends the local file to the file in the pod")
+	fmt.Println(" - write <source file in local fs> <file name> <offset> - writes the local file over the file in the pod from the offset")
 	fmt.Println(" - help - display this help")
 	fmt.Println(" - exit - exits from the prompt")
 
 }
 
+// podSnapshot runs the pod snapshot commands.
+func podSnapshot(blocks []string) {
+	if len(blocks) < 1 {
+		fmt.Println("invalid command. Missing \"new/ls/open/restore/del\" argument ")
+		return
+	}
+	if blocks[0] != "ls" && len(blocks) < 2 {
+		fmt.Println("invalid command. Missing \"snapshot-name\" argument ")
+		return
+	}
+	args := make(map[string]string)
+	switch blocks[0] {
+	case "new":
+		if !isPodOpened() {
+			return
+		}
+		args["snapshot"] = blocks[1]
+		data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiPodSnapshot, args)
+		if err != nil {
+			fmt.Println("pod snapshot failed: ", err)
+			return
+		}
+		var resp api.SnapshotCreateResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("pod snapshot failed: ", err)
+			return
+		}
+		fmt.Println("snapshot reference: ", resp.Reference)
+	case "ls", "open":
+		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiPodSnapshotLs, nil)
+		if err != nil {
+			fmt.Println("error while listing snapshots: ", err)
+			return
+		}
+		var resp api.SnapshotListResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("error while listing snapshots: ", err)
+			return
+		}
+		if blocks[0] == "ls" {
+			for _, snapshot := range resp.Snapshots {
+				fmt.Println(snapshot.Name, " of ", snapshot.PodName, " at ", snapshot.CreationTime)
+			}
+			return
+		}
+
+		podName := ""
+		for _, snapshot := range resp.Snapshots {
+			if snapshot.Name == blocks[1] {
+				podName = snapshot.PodName
+			}
+		}
+		if podName == "" {
+			fmt.Println("invalid snapshot name")
+			return
+		}
+		args["snapshot"] = blocks[1]
+		data, err = fdfsAPI.callFdfsApi(http.MethodPost, apiPodSnapshotOpen, args)
+		if err != nil {
+			fmt.Println("snapshot open failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+		currentPod = podName
+		currentDirectory = utils.PathSeperator
+	case "restore":
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing \"pod-name\" argument ")
+			return
+		}
+		args["snapshot"] = blocks[1]
+		args["pod"] = blocks[2]
+		args["password"] = getPassword()
+		data, err := fdfsAPI.callFdfsApi(http.MethodPost, apiPodSnapshotRest, args)
+		if err != nil {
+			fmt.Println("snapshot restore failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+		currentPod = blocks[2]
+		currentDirectory = utils.PathSeperator
+	case "del":
+		args["snapshot"] = blocks[1]
+		data, err := fdfsAPI.callFdfsApi(http.MethodDelete, apiPodSnapshotDel, args)
+		if err != nil {
+			fmt.Println("snapshot delete failed: ", err)
+			return
+		}
+		message := strings.ReplaceAll(string(data), "\n", "")
+		fmt.Println(message)
+	default:
+		fmt.Println("invalid pod snapshot command")
+	}
+}
+
+// podDiff prints the changes from one tree to another. Local directories are read
+// here and compared against the tree of the other side, the rest is left to the server.
+func podDiff(blocks []string) {
+	var sources []string
+	dirPath := utils.PathSeperator
+	asJSON := false
+	for i := 0; i < len(blocks); i++ {
+		switch blocks[i] {
+		case "":
+		case "-json":
+			asJSON = true
+		case "-dir":
+			if i+1 >= len(blocks) {
+				fmt.Println("diff failed: missing value of -dir")
+				return
+			}
+			i++
+			dirPath = blocks[i]
+		default:
+			sources = append(sources, blocks[i])
+		}
+	}
+	if len(sources) != 2 {
+		fmt.Println("invalid command. Missing \"from\" or \"to\" argument ")
+		return
+	}
+
+	var changes []*pod.DiffEntry
+	fromKind, _ := pod.SplitDiffSource(sources[0])
+	toKind, _ := pod.SplitDiffSource(sources[1])
+	if fromKind == pod.DiffSourceLocal || toKind == pod.DiffSourceLocal {
+		from, err := diffTree(sources[0], dirPath)
+		if err != nil {
+			fmt.Println("diff failed: ", err)
+			return
+		}
+		to, err := diffTree(sources[1], dirPath)
+		if err != nil {
+			fmt.Println("diff failed: ", err)
+			return
+		}
+		changes = pod.DiffTrees(from, to)
+	} else {
+		args := make(map[string]string)
+		args["from"] = sources[0]
+		args["to"] = sources[1]
+		args["dir"] = dirPath
+		data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiPodDiff, args)
+		if err != nil {
+			fmt.Println("diff failed: ", err)
+			return
+		}
+		var resp api.PodDiffResponse
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			fmt.Println("diff failed: ", err)
+			return
+		}
+		changes = resp.Changes
+	}
+
+	if asJSON {
+		if changes == nil {
+			changes = make([]*pod.DiffEntry, 0)
+		}
+		data, err := json.Marshal(changes)
+		if err != nil {
+			fmt.Println("diff failed: ", err)
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+	for _, change := range changes {
+		if change.Change == pod.DiffMoved {
+			fmt.Printf("%s\t%s\t%s\t%s\n", change.Change, change.Type, change.Path, change.OldPath)
+		} else {
+			fmt.Printf("%s\t%s\t%s\n", change.Change, change.Type, change.Path)
+		}
+	}
+}
+
+// diffTree returns the tree of a local directory or asks the server for the one of a
+// pod or snapshot.
+func diffTree(source, dirPath string) ([]*pod.TreeEntry, error) {
+	kind, name := pod.SplitDiffSource(source)
+	if kind == pod.DiffSourceLocal {
+		return pod.LocalTree(name)
+	}
+	args := make(map[string]string)
+	args["source"] = source
+	args["dir"] = dirPath
+	data, err := fdfsAPI.callFdfsApi(http.MethodGet, apiPodTree, args)
+	if err != nil {
+		return nil, err
+	}
+	var resp api.PodTreeResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// printDiskUsage prints the usage of a directory or a pod on one line.
+func printDiskUsage(name string, usage *pod.DiskUsage) {
+	fmt.Printf("%s: %s (%s stored), %d files, %d directories, %d blocks\n", name,
+		humanize.Bytes(usage.Size), humanize.Bytes(usage.CompressedSize), usage.Files, usage.Directories, usage.Blocks)
+}
+
+// findArguments turns the arguments of the find command in to the arguments of the
+// find api. The directory to search is the current one unless one is given.
+func findArguments(blocks []string) (map[string]string, error) {
+	args := make(map[string]string)
+	args["dir"] = currentDirectory
+	for i := 0; i < len(blocks); i++ {
+		arg := blocks[i]
+		if arg == "" {
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			args["dir"] = podPathFromCurrentDirectory(arg)
+			continue
+		}
+		if i+1 >= len(blocks) {
+			return nil, fmt.Errorf("missing value of %s", arg)
+		}
+		i++
+		value := blocks[i]
+		switch arg {
+		case "-name":
+			args["name"] = value
+		case "-type":
+			switch value {
+			case "f":
+				args["type"] = dir.ListTypeFile
+			case "d":
+				args["type"] = dir.ListTypeDir
+			default:
+				return nil, fmt.Errorf("invalid type %s", value)
+			}
+		case "-ctype":
+			args["content_type"] = value
+		case "-min-size", "-max-size":
+			size, err := humanize.ParseBytes(value)
+			if err != nil {
+				return nil, err
+			}
+			args[strings.Replace(strings.TrimPrefix(arg, "-"), "-", "_", 1)] = strconv.FormatUint(size, 10)
+		case "-newer", "-older":
+			age, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			t := strconv.FormatInt(time.Now().Add(-age).Unix(), 10)
+			if arg == "-newer" {
+				args["modified_after"] = t
+			} else {
+				args["modified_before"] = t
+			}
+		case "-limit":
+			args["limit"] = value
+		default:
+			return nil, fmt.Errorf("unknown option %s", arg)
+		}
+	}
+	return args, nil
+}
+
+// podPathFromCurrentDirectory returns the path in the pod of a name relative to the
+// current directory, or the name itself if it is already a path from the pod root.
+func podPathFromCurrentDirectory(name string) string {
+	if strings.HasPrefix(name, utils.PathSeperator) {
+		return name
+	}
+	if currentDirectory == utils.PathSeperator {
+		return currentDirectory + name
+	}
+	return currentDirectory + utils.PathSeperator + name
+}
+
 func getCurrentPrompt() string {
 	currPrompt := getUserPrompt()
 	podPrompt := getPodPrompt()
@@ -19,12 +19,22 @@ package cmd
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"path/filepath"
 	"strings"
+	"time"
 
 	dfs "github.com/fairdatasociety/fairOS-dfs"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/cache"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/local"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/pool"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/retry"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -32,12 +42,35 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	blockStoreBee   = "bee"
+	blockStoreLocal = "local"
+	localStoreDir   = "blockstore"
+	postageFile     = "postage.json"
+	cacheDir        = "cache"
+)
+
 var (
 	httpPort     string
 	pprofPort    string
 	cookieDomain string
 	corsOrigins  []string
-	handler      *api.Handler
+	blockStore   string
+
+	beeNodes            []string
+	healthCheckInterval time.Duration
+
+	retryAttempts    int
+	breakerThreshold int
+	breakerTimeout   time.Duration
+
+	cacheMemory uint64
+	cacheDisk   uint64
+
+	postageBatchId    string
+	postageBatchDepth uint8
+
+	handler *api.Handler
 )
 
 // startCmd represents the start command
@@ -69,14 +102,35 @@ can consume it.`,
 		logger.Info("configuration values")
 		logger.Info("version      : ", dfs.Version)
 		logger.Info("dataDir      : ", dataDir)
-		logger.Info("beeHost      : ", beeHost)
-		logger.Info("beePort      : ", beePort)
+		logger.Info("blockStore   : ", blockStore)
+		if blockStore == blockStoreBee {
+			if len(beeNodes) > 0 {
+				logger.Info("beeNodes     : ", beeNodes)
+			} else {
+				logger.Info("beeHost      : ", beeHost)
+				logger.Info("beePort      : ", beePort)
+			}
+			logger.Info("postageBatch : ", postageBatchId)
+		}
 		logger.Info("verbosity    : ", verbosity)
 		logger.Info("httpPort     : ", httpPort)
 		logger.Info("pprofPort    : ", pprofPort)
 		logger.Info("cookieDomain : ", cookieDomain)
 		logger.Info("corsOrigins  : ", corsOrigins)
-		hdlr, err := api.NewHandler(dataDir, beeHost, beePort, cookieDomain, logger)
+		logger.Info("retryAttempts: ", retryAttempts)
+		logger.Info("cacheMemory  : ", cacheMemory)
+		logger.Info("cacheDisk    : ", cacheDisk)
+		batches, err := newPostageManager()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		client, err := newBlockStoreClient(batches, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		hdlr, err := api.NewHandler(dataDir, client, batches, cookieDomain, logger)
 		if err != nil {
 			logger.Error(err.Error())
 			return
@@ -91,9 +145,96 @@ func init() {
 	serverCmd.Flags().StringVar(&pprofPort, "pprofPort", "9091", "pprof port")
 	serverCmd.Flags().StringVar(&cookieDomain, "cookieDomain", "api.fairos.io", "the domain to use in the cookie")
 	serverCmd.Flags().StringSliceVar(&corsOrigins, "cors-origins", []string{}, "allow CORS headers for the given origins")
+	serverCmd.Flags().StringVar(&blockStore, "blockStore", blockStoreBee, "where to store the data, \"bee\" or \"local\" (stored under dataDir, works offline)")
+	serverCmd.Flags().StringSliceVar(&beeNodes, "beeNodes", []string{}, "host:port of several bee nodes to spread the load over, used instead of beeHost and beePort")
+	serverCmd.Flags().DurationVar(&healthCheckInterval, "healthCheckInterval", 15*time.Second, "how often the bee nodes given with beeNodes are checked")
+	serverCmd.Flags().StringVar(&postageBatchId, "postageBatchId", "", "default postage batch id used to stamp uploads to bee")
+	serverCmd.Flags().Uint8Var(&postageBatchDepth, "postageBatchDepth", 0, "depth of the default postage batch, used to detect that it is exhausted")
+	serverCmd.Flags().IntVar(&retryAttempts, "retryAttempts", 3, "attempts for each blockstore call, 0 disables retries and the circuit breaker")
+	serverCmd.Flags().IntVar(&breakerThreshold, "breakerThreshold", 5, "consecutive blockstore failures which open the circuit breaker")
+	serverCmd.Flags().DurationVar(&breakerTimeout, "breakerTimeout", 30*time.Second, "time the circuit breaker stays open before probing the blockstore again")
+	serverCmd.Flags().Uint64Var(&cacheMemory, "cacheMemory", 64, "size in MB of the in memory read cache, 0 disables it")
+	serverCmd.Flags().Uint64Var(&cacheDisk, "cacheDisk", 0, "size in MB of the on disk read cache kept under dataDir, 0 disables it. Encrypted blobs are only cached in memory")
 	rootCmd.AddCommand(serverCmd)
 }
 
+// newBlockStoreClient creates the blockstore client selected by the --blockStore flag
+// and wraps it with retries, a circuit breaker and the read cache
+func newBlockStoreClient(batches *postage.Manager, logger logging.Logger) (blockstore.Client, error) {
+	var client blockstore.Client
+	switch blockStore {
+	case blockStoreBee:
+		if len(beeNodes) == 0 {
+			client = bee.NewBeeClient(beeHost, beePort, batches, logger)
+			break
+		}
+		p, err := newBeePool(batches, logger)
+		if err != nil {
+			return nil, err
+		}
+		client = p
+	case blockStoreLocal:
+		c, err := local.NewLocalClient(filepath.Join(dataDir, localStoreDir), logger)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	default:
+		return nil, fmt.Errorf("unknown blockStore %q", blockStore)
+	}
+
+	if retryAttempts > 0 {
+		breaker := retry.NewBreaker(breakerThreshold, breakerTimeout)
+		client = retry.NewRetryClient(client, retry.DefaultPolicies(retryAttempts), breaker, logger)
+	}
+
+	if cacheMemory > 0 || cacheDisk > 0 {
+		c, err := cache.NewCacheClient(client, int64(cacheMemory)<<20, filepath.Join(dataDir, cacheDir), int64(cacheDisk)<<20, logger)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+	return client, nil
+}
+
+// newBeePool creates a pool of the bee nodes given with --beeNodes. The server starts
+// as long as one of them is up.
+func newBeePool(batches *postage.Manager, logger logging.Logger) (*pool.PoolClient, error) {
+	nodes := make([]*pool.Node, 0, len(beeNodes))
+	for _, addr := range beeNodes {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bee node %q: %w", addr, err)
+		}
+		nodes = append(nodes, pool.NewNode(addr, bee.NewBeeClient(host, port, batches, logger)))
+	}
+	p, err := pool.NewPoolClient(nodes, healthCheckInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+	if healthy := p.HealthyNodes(); healthy < len(nodes) {
+		logger.Warningf("starting degraded, %d of %d bee nodes are up", healthy, len(nodes))
+	}
+	return p, nil
+}
+
+// newPostageManager creates the postage batch manager from the flags, the batches
+// chosen for users and pods are kept under dataDir
+func newPostageManager() (*postage.Manager, error) {
+	batches, err := postage.NewManager(postageBatchId, filepath.Join(dataDir, postageFile))
+	if err != nil {
+		return nil, err
+	}
+	if postageBatchId != "" && postageBatchDepth > 0 {
+		err = batches.SetDepth(postageBatchId, postageBatchDepth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
 func startHttpService(logger logging.Logger) {
 	router := mux.NewRouter()
 
@@ -125,6 +266,7 @@ func startHttpService(logger logging.Logger) {
 
 	// User account related handlers which does not login need middleware
 	baseRouter.Use(handler.LogMiddleware)
+	baseRouter.HandleFunc("/health", handler.HealthHandler).Methods("GET")
 	baseRouter.HandleFunc("/user/signup", handler.UserSignupHandler).Methods("POST")
 	baseRouter.HandleFunc("/user/login", handler.UserLoginHandler).Methods("POST")
 	baseRouter.HandleFunc("/user/import", handler.ImportUserHandler).Methods("POST")
@@ -140,6 +282,9 @@ func startHttpService(logger logging.Logger) {
 	userRouter.HandleFunc("/name", handler.SaveUserNameHandler).Methods("POST")
 	userRouter.HandleFunc("/contact", handler.SaveUserContactHandler).Methods("POST")
 	userRouter.HandleFunc("/export", handler.ExportUserHandler).Methods("POST")
+	userRouter.HandleFunc("/gc", handler.UserGCHandler).Methods("POST")
+	userRouter.HandleFunc("/du", handler.UserDiskUsageHandler).Methods("POST")
+	userRouter.HandleFunc("/batch", handler.UserSetBatchHandler).Methods("POST")
 
 	userRouter.HandleFunc("/delete", handler.UserDeleteHandler).Methods("DELETE")
 	userRouter.HandleFunc("/stat", handler.GetUserStatHandler).Methods("GET")
@@ -148,6 +293,7 @@ func startHttpService(logger logging.Logger) {
 	userRouter.HandleFunc("/contact", handler.GetUserContactHandler).Methods("GET")
 	userRouter.HandleFunc("/share/inbox", handler.GetUserSharingInboxHandler).Methods("GET")
 	userRouter.HandleFunc("/share/outbox", handler.GetUserSharingOutboxHandler).Methods("GET")
+	userRouter.HandleFunc("/batch", handler.UserBatchHandler).Methods("GET")
 
 	// pod related handlers
 	baseRouter.HandleFunc("/pod/receive", handler.PodReceiveHandler).Methods("GET")
@@ -163,6 +309,15 @@ func startHttpService(logger logging.Logger) {
 	podRouter.HandleFunc("/delete", handler.PodDeleteHandler).Methods("DELETE")
 	podRouter.HandleFunc("/ls", handler.PodListHandler).Methods("GET")
 	podRouter.HandleFunc("/stat", handler.PodStatHandler).Methods("GET")
+	podRouter.HandleFunc("/versioning", handler.PodVersioningHandler).Methods("POST")
+	podRouter.HandleFunc("/batch", handler.PodBatchHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot/new", handler.PodSnapshotHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot/ls", handler.PodSnapshotListHandler).Methods("GET")
+	podRouter.HandleFunc("/snapshot/open", handler.PodSnapshotOpenHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot/restore", handler.PodSnapshotRestoreHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot/delete", handler.PodSnapshotDeleteHandler).Methods("DELETE")
+	podRouter.HandleFunc("/tree", handler.PodTreeHandler).Methods("GET")
+	podRouter.HandleFunc("/diff", handler.PodDiffHandler).Methods("GET")
 
 	// directory related handlers
 	dirRouter := baseRouter.PathPrefix("/dir/").Subrouter()
@@ -171,6 +326,8 @@ func startHttpService(logger logging.Logger) {
 	dirRouter.HandleFunc("/mkdir", handler.DirectoryMkdirHandler).Methods("POST")
 	dirRouter.HandleFunc("/rmdir", handler.DirectoryRmdirHandler).Methods("DELETE")
 	dirRouter.HandleFunc("/ls", handler.DirectoryLsHandler).Methods("GET")
+	dirRouter.HandleFunc("/find", handler.DirectoryFindHandler).Methods("GET")
+	dirRouter.HandleFunc("/du", handler.DirectoryDiskUsageHandler).Methods("GET")
 	dirRouter.HandleFunc("/stat", handler.DirectoryStatHandler).Methods("GET")
 	dirRouter.HandleFunc("/present", handler.DirectoryPresentHandler).Methods("GET")
 
@@ -178,13 +335,26 @@ func startHttpService(logger logging.Logger) {
 	fileRouter := baseRouter.PathPrefix("/file/").Subrouter()
 	fileRouter.Use(handler.LoginMiddleware)
 	fileRouter.Use(handler.LogMiddleware)
-	fileRouter.HandleFunc("/download", handler.FileDownloadHandler).Methods("POST")
+	fileRouter.HandleFunc("/download", handler.FileDownloadHandler).Methods("GET", "HEAD", "POST")
 	fileRouter.HandleFunc("/upload", handler.FileUploadHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/session", handler.FileUploadSessionHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/session", handler.FileUploadSessionStatusHandler).Methods("GET")
+	fileRouter.HandleFunc("/upload/session", handler.FileUploadSessionDeleteHandler).Methods("DELETE")
+	fileRouter.HandleFunc("/upload/block", handler.FileUploadBlockHandler).Methods("PUT")
+	fileRouter.HandleFunc("/upload/finish", handler.FileUploadFinishHandler).Methods("POST")
 	fileRouter.HandleFunc("/share", handler.FileShareHandler).Methods("POST")
 	fileRouter.HandleFunc("/receive", handler.FileReceiveHandler).Methods("GET")
 	fileRouter.HandleFunc("/receiveinfo", handler.FileReceiveInfoHandler).Methods("GET")
 	fileRouter.HandleFunc("/delete", handler.FileDeleteHandler).Methods("DELETE")
+	fileRouter.HandleFunc("/mv", handler.FileMoveHandler).Methods("POST")
+	fileRouter.HandleFunc("/cp", handler.FileCopyHandler).Methods("POST")
 	fileRouter.HandleFunc("/stat", handler.FileStatHandler).Methods("GET")
+	fileRouter.HandleFunc("/verify", handler.FileVerifyHandler).Methods("GET")
+	fileRouter.HandleFunc("/versions", handler.FileVersionsHandler).Methods("GET")
+	fileRouter.HandleFunc("/restore", handler.FileRestoreHandler).Methods("POST")
+	fileRouter.HandleFunc("/prune", handler.FilePruneHandler).Methods("POST")
+	fileRouter.HandleFunc("/append", handler.FileAppendHandler).Methods("POST")
+	fileRouter.HandleFunc("/write", handler.FileWriteHandler).Methods("POST")
 
 	kvRouter := baseRouter.PathPrefix("/kv/").Subrouter()
 	kvRouter.Use(handler.LoginMiddleware)
@@ -225,8 +395,9 @@ func startHttpService(logger logging.Logger) {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   origins,
 		AllowCredentials: true,
-		AllowedHeaders:   []string{"Origin", "Accept", "Authorization", "Content-Type", "X-Requested-With", "Access-Control-Request-Headers", "Access-Control-Request-Method"},
-		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedHeaders:   []string{"Origin", "Accept", "Authorization", "Content-Type", "X-Requested-With", "Access-Control-Request-Headers", "Access-Control-Request-Method", "Range", "If-Range", "fairOS-dfs-Compression", "fairOS-dfs-Chunking", "fairOS-dfs-Checksum"},
+		ExposedHeaders:   []string{"Accept-Ranges", "Content-Range", "Content-Length", "ETag"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "DELETE"},
 		MaxAge:           3600,
 	})
 
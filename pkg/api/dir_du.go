@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+// DirectoryDiskUsageHandler returns the storage taken by the files and directories
+// below a directory of the open pod, "/" for the whole pod.
+func (h *Handler) DirectoryDiskUsageHandler(w http.ResponseWriter, r *http.Request) {
+	dir := r.FormValue("dir")
+	if dir == "" {
+		h.logger.Errorf("dir du: \"dir\" argument missing")
+		jsonhttp.BadRequest(w, "dir du: \"dir\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("dir du: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("dir du: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "dir du: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// add up the usage of the directory
+	usage, err := h.dfsAPI.DirectoryDiskUsage(dir, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
+			err == p.ErrPodNotOpened {
+			h.logger.Errorf("dir du: %v", err)
+			jsonhttp.BadRequest(w, "dir du: "+err.Error())
+			return
+		}
+		h.logger.Errorf("dir du: %v", err)
+		jsonhttp.InternalServerError(w, "dir du: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, usage)
+}
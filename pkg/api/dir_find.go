@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+type FindResponse struct {
+	Entries   []*p.FindResult `json:"entries"`
+	Truncated bool            `json:"truncated"`
+}
+
+// DirectoryFindHandler searches the files and directories below a directory of the
+// open pod. All the conditions given have to be met: a name glob, the type, a content
+// type prefix, a size range and a range of modification times.
+func (h *Handler) DirectoryFindHandler(w http.ResponseWriter, r *http.Request) {
+	opts := &p.FindOptions{
+		Path:        r.FormValue("dir"),
+		Name:        r.FormValue("name"),
+		Type:        r.FormValue("type"),
+		ContentType: r.FormValue("content_type"),
+	}
+	for _, arg := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"min_size", &opts.MinSize},
+		{"max_size", &opts.MaxSize},
+	} {
+		if v := r.FormValue(arg.name); v != "" {
+			var err error
+			*arg.value, err = strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				h.logger.Errorf("find: invalid \"%s\" argument", arg.name)
+				jsonhttp.BadRequest(w, "find: invalid \""+arg.name+"\" argument")
+				return
+			}
+		}
+	}
+	for _, arg := range []struct {
+		name  string
+		value *int64
+	}{
+		{"modified_after", &opts.ModifiedAfter},
+		{"modified_before", &opts.ModifiedBefore},
+	} {
+		if v := r.FormValue(arg.name); v != "" {
+			var err error
+			*arg.value, err = strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				h.logger.Errorf("find: invalid \"%s\" argument", arg.name)
+				jsonhttp.BadRequest(w, "find: invalid \""+arg.name+"\" argument")
+				return
+			}
+		}
+	}
+	if limit := r.FormValue("limit"); limit != "" {
+		var err error
+		opts.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			h.logger.Errorf("find: invalid \"limit\" argument")
+			jsonhttp.BadRequest(w, "find: invalid \"limit\" argument")
+			return
+		}
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("find: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("find: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "find: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	results, truncated, err := h.dfsAPI.Find(opts, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
+			err == p.ErrPodNotOpened || err == p.ErrInvalidFindOption {
+			h.logger.Errorf("find: %v", err)
+			jsonhttp.BadRequest(w, "find: "+err.Error())
+			return
+		}
+		h.logger.Errorf("find: %v", err)
+		jsonhttp.InternalServerError(w, "find: "+err.Error())
+		return
+	}
+
+	if results == nil {
+		results = make([]*p.FindResult, 0)
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &FindResponse{
+		Entries:   results,
+		Truncated: truncated,
+	})
+}
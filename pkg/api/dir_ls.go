@@ -18,6 +18,7 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"resenje.org/jsonhttp"
 
@@ -28,7 +29,8 @@ import (
 )
 
 type ListFileResponse struct {
-	Entries []dir.DirOrFileEntry `json:"entries"`
+	Entries    []dir.DirOrFileEntry `json:"entries"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 }
 
 type DirOrFileEntry struct {
@@ -40,6 +42,9 @@ type DirOrFileEntry struct {
 	AccessTime       string `json:"access_time"`
 }
 
+// DirectoryLsHandler lists the entries of a directory. The optional arguments filter
+// them by name prefix and type, sort them by name, size or mtime, include the details
+// of the files and page them with limit and the next_cursor of the previous page.
 func (h *Handler) DirectoryLsHandler(w http.ResponseWriter, r *http.Request) {
 	directory := r.FormValue("dir")
 	if directory == "" {
@@ -47,6 +52,39 @@ func (h *Handler) DirectoryLsHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.BadRequest(w, "ls: \"dir\" argument missing")
 		return
 	}
+	opts := &dir.ListOptions{
+		Prefix: r.FormValue("prefix"),
+		Type:   r.FormValue("type"),
+		SortBy: r.FormValue("sort"),
+		Cursor: r.FormValue("cursor"),
+	}
+	switch r.FormValue("order") {
+	case "", "asc":
+	case "desc":
+		opts.Reverse = true
+	default:
+		h.logger.Errorf("ls: invalid \"order\" argument")
+		jsonhttp.BadRequest(w, "ls: invalid \"order\" argument")
+		return
+	}
+	if limit := r.FormValue("limit"); limit != "" {
+		var err error
+		opts.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			h.logger.Errorf("ls: invalid \"limit\" argument")
+			jsonhttp.BadRequest(w, "ls: invalid \"limit\" argument")
+			return
+		}
+	}
+	if detail := r.FormValue("detail"); detail != "" {
+		var err error
+		opts.Detail, err = strconv.ParseBool(detail)
+		if err != nil {
+			h.logger.Errorf("ls: invalid \"detail\" argument")
+			jsonhttp.BadRequest(w, "ls: invalid \"detail\" argument")
+			return
+		}
+	}
 
 	// get values from cookie
 	sessionId, err := cookie.GetSessionIdFromCookie(r)
@@ -62,10 +100,10 @@ func (h *Handler) DirectoryLsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// list directory
-	entries, err := h.dfsAPI.ListDir(directory, sessionId)
+	page, err := h.dfsAPI.ListDir(directory, opts, sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
-			err == p.ErrPodNotOpened {
+			err == p.ErrPodNotOpened || err == dir.ErrInvalidListOption || err == dir.ErrInvalidCursor {
 			h.logger.Errorf("ls: %v", err)
 			jsonhttp.BadRequest(w, "ls: "+err.Error())
 			return
@@ -75,11 +113,13 @@ func (h *Handler) DirectoryLsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	entries := page.Entries
 	if entries == nil {
 		entries = make([]dir.DirOrFileEntry, 0)
 	}
 	w.Header().Set("Content-Type", " application/json")
 	jsonhttp.OK(w, &ListFileResponse{
-		Entries: entries,
+		Entries:    entries,
+		NextCursor: page.NextCursor,
 	})
 }
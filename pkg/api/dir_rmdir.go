@@ -17,15 +17,31 @@ limitations under the License.
 package api
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"resenje.org/jsonhttp"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
 )
 
+const (
+	ProgressContentType = "application/x-ndjson"
+)
+
+// RmdirProgressLine is one line of a rmdir response with progress. Every removal is
+// reported in a line with the progress fields, the last line holds the message and
+// the code of the response.
+type RmdirProgressLine struct {
+	d.RemoveProgress
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
 func (h *Handler) DirectoryRmdirHandler(w http.ResponseWriter, r *http.Request) {
 	dir := r.FormValue("dir")
 	if dir == "" {
@@ -47,11 +63,56 @@ func (h *Handler) DirectoryRmdirHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	recursive := false
+	recursiveStr := r.FormValue("recursive")
+	if recursiveStr != "" {
+		recursive, err = strconv.ParseBool(recursiveStr)
+		if err != nil {
+			h.logger.Errorf("rmdir: invalid \"recursive\" argument")
+			jsonhttp.BadRequest(w, "rmdir: invalid \"recursive\" argument")
+			return
+		}
+	}
+	withProgress := false
+	progressStr := r.FormValue("progress")
+	if progressStr != "" {
+		withProgress, err = strconv.ParseBool(progressStr)
+		if err != nil {
+			h.logger.Errorf("rmdir: invalid \"progress\" argument")
+			jsonhttp.BadRequest(w, "rmdir: invalid \"progress\" argument")
+			return
+		}
+	}
+
+	// with progress, the response is streamed from the first removal on. Errors
+	// found before that still get their own status code.
+	streaming := false
+	var progress func(*d.RemoveProgress)
+	if withProgress {
+		progress = func(rp *d.RemoveProgress) {
+			if !streaming {
+				streaming = true
+				w.Header().Set("Content-Type", ProgressContentType)
+				w.WriteHeader(http.StatusOK)
+			}
+			writeProgressLine(w, &RmdirProgressLine{RemoveProgress: *rp})
+		}
+	}
+
 	// remove directory
-	err = h.dfsAPI.RmDir(dir, sessionId)
+	err = h.dfsAPI.RmDir(dir, sessionId, recursive, progress)
+	if streaming {
+		if err != nil {
+			h.logger.Errorf("rmdir: %v", err)
+			writeProgressLine(w, &RmdirProgressLine{Message: "rmdir: " + err.Error(), Code: http.StatusInternalServerError})
+			return
+		}
+		writeProgressLine(w, &RmdirProgressLine{Message: "directory removed successfully", Code: http.StatusOK})
+		return
+	}
 	if err != nil {
 		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
-			err == p.ErrPodNotOpened {
+			err == p.ErrPodNotOpened || err == p.ErrDirectoryNotEmpty {
 			h.logger.Errorf("rmdir: %v", err)
 			jsonhttp.BadRequest(w, "rmdir: "+err.Error())
 			return
@@ -62,3 +123,14 @@ func (h *Handler) DirectoryRmdirHandler(w http.ResponseWriter, r *http.Request)
 	}
 	jsonhttp.OK(w, "directory removed successfully")
 }
+
+func writeProgressLine(w http.ResponseWriter, line interface{}) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+	_, _ = w.Write(append(data, '\n'))
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -65,7 +65,7 @@ func (h *Handler) DocIndexJsonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.dfsAPI.DocIndexJson(sessionId, name, podFile)
+	err = h.dfsAPI.DocIndexJson(r.Context(), sessionId, name, podFile)
 	if err != nil {
 		h.logger.Errorf("doc indexjson: %v", err)
 		jsonhttp.InternalServerError(w, "doc indexjson: "+err.Error())
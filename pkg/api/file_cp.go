@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+func (h *Handler) FileCopyHandler(w http.ResponseWriter, r *http.Request) {
+	source := r.FormValue("source")
+	if source == "" {
+		h.logger.Errorf("cp: \"source\" argument missing")
+		jsonhttp.BadRequest(w, "cp: \"source\" argument missing")
+		return
+	}
+	destination := r.FormValue("destination")
+	if destination == "" {
+		h.logger.Errorf("cp: \"destination\" argument missing")
+		jsonhttp.BadRequest(w, "cp: \"destination\" argument missing")
+		return
+	}
+
+	// the pod to copy to, if not the open one, has to be open too
+	pod := r.FormValue("pod")
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("cp: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("cp: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "cp: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// copy the file or directory
+	err = h.dfsAPI.Copy(source, pod, destination, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrInvalidPodName || err == p.ErrFileOrDirNotFound ||
+			err == p.ErrDestinationExists || err == p.ErrCopyIntoItself || err == p.ErrTooLongDirectoryName ||
+			err == p.ErrReadOnlyPod {
+			h.logger.Errorf("cp: %v", err)
+			jsonhttp.BadRequest(w, "cp: "+err.Error())
+			return
+		}
+		h.logger.Errorf("cp: %v", err)
+		jsonhttp.InternalServerError(w, "cp: "+err.Error())
+		return
+	}
+
+	jsonhttp.OK(w, "copied successfully")
+}
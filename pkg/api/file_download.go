@@ -20,8 +20,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
 
 	"resenje.org/jsonhttp"
 
@@ -49,10 +53,22 @@ func (h *Handler) FileDownloadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// download file from bee
-	reader, reference, size, err := h.dfsAPI.DownloadFile(podFile, sessionId)
+	// download file from bee, an older version of it if one is given
+	var reader io.ReadCloser
+	var reference, size string
+	if versionStr := r.FormValue("version"); versionStr != "" {
+		version, err := strconv.ParseUint(versionStr, 10, 32)
+		if err != nil {
+			h.logger.Errorf("download: invalid \"version\" argument")
+			jsonhttp.BadRequest(w, "download: invalid \"version\" argument")
+			return
+		}
+		reader, reference, size, err = h.dfsAPI.DownloadFileVersion(r.Context(), podFile, uint32(version), sessionId)
+	} else {
+		reader, reference, size, err = h.dfsAPI.DownloadFile(r.Context(), podFile, sessionId)
+	}
 	if err != nil {
-		if err == dfs.ErrPodNotOpen {
+		if err == dfs.ErrPodNotOpen || err == file.ErrVersionNotFound {
 			h.logger.Errorf("download: %v", err)
 			jsonhttp.BadRequest(w, "download: "+err.Error())
 			return
@@ -62,7 +78,16 @@ func (h *Handler) FileDownloadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	defer reader.Close()
 	w.Header().Set("ETag", fmt.Sprintf("%q", reference))
+
+	// a seekable reader serves Range and If-Range requests, only the blocks
+	// covering the requested ranges are downloaded from bee
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, filepath.Base(podFile), time.Time{}, seeker)
+		return
+	}
+
 	w.Header().Set("Content-Length", size)
 
 	_, err = io.Copy(w, reader)
@@ -71,5 +96,4 @@ func (h *Handler) FileDownloadHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", " application/json")
 		jsonhttp.InternalServerError(w, "stat dir: "+err.Error())
 	}
-	_ = reader.Close()
 }
@@ -0,0 +1,71 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+func (h *Handler) FileMoveHandler(w http.ResponseWriter, r *http.Request) {
+	source := r.FormValue("source")
+	if source == "" {
+		h.logger.Errorf("mv: \"source\" argument missing")
+		jsonhttp.BadRequest(w, "mv: \"source\" argument missing")
+		return
+	}
+	destination := r.FormValue("destination")
+	if destination == "" {
+		h.logger.Errorf("mv: \"destination\" argument missing")
+		jsonhttp.BadRequest(w, "mv: \"destination\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("mv: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("mv: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "mv: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// move the file or directory
+	err = h.dfsAPI.Move(source, destination, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrFileOrDirNotFound || err == p.ErrDestinationExists ||
+			err == p.ErrMoveIntoItself || err == p.ErrTooLongDirectoryName || err == p.ErrReadOnlyPod {
+			h.logger.Errorf("mv: %v", err)
+			jsonhttp.BadRequest(w, "mv: "+err.Error())
+			return
+		}
+		h.logger.Errorf("mv: %v", err)
+		jsonhttp.InternalServerError(w, "mv: "+err.Error())
+		return
+	}
+
+	jsonhttp.OK(w, "moved successfully")
+}
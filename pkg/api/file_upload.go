@@ -17,12 +17,14 @@ limitations under the License.
 package api
 
 import (
+	"mime"
 	"net/http"
 
 	"resenje.org/jsonhttp"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
 )
 
 type UploadFileResponse struct {
@@ -38,12 +40,14 @@ type Reference struct {
 const (
 	defaultMaxMemory  = 32 << 20 // 32 MB
 	CompressionHeader = "fairOS-dfs-Compression"
+	ChunkingHeader    = "fairOS-dfs-Chunking"
 )
 
 func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 	podDir := r.FormValue("pod_dir")
 	blockSize := r.FormValue("block_size")
 	compression := r.Header.Get(CompressionHeader)
+	chunking := r.Header.Get(ChunkingHeader)
 	if podDir == "" {
 		h.logger.Errorf("file upload: \"pod_dir\" argument missing")
 		jsonhttp.BadRequest(w, "file upload: \"pod_dir\" argument missing")
@@ -56,12 +60,17 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if compression != "" {
-		if compression != "snappy" && compression != "gzip" {
+		if _, _, err := f.ParseCompression(compression); err != nil {
 			h.logger.Errorf("file upload: invalid value for \"compression\" header")
 			jsonhttp.BadRequest(w, "file upload: invalid value for \"compression\" header")
 			return
 		}
 	}
+	if chunking != "" && chunking != f.ChunkingCDC {
+		h.logger.Errorf("file upload: invalid value for \"chunking\" header")
+		jsonhttp.BadRequest(w, "file upload: invalid value for \"chunking\" header")
+		return
+	}
 
 	// get values from cookie
 	sessionId, err := cookie.GetSessionIdFromCookie(r)
@@ -76,6 +85,14 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// a body which is the data itself is streamed in to one file, its length need not
+	// be known when the upload starts, as with chunked transfer encoding
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "application/octet-stream" {
+		h.fileUploadStream(w, r, sessionId, podDir, blockSize, compression, chunking)
+		return
+	}
+
 	//  get the files parameter from the multi part
 	err = r.ParseMultipartForm(defaultMaxMemory)
 	if err != nil {
@@ -107,7 +124,7 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		//upload file to bee
-		reference, err := h.dfsAPI.UploadFile(file.Filename, sessionId, file.Size, fd, podDir, blockSize, compression)
+		reference, err := h.dfsAPI.UploadFile(r.Context(), file.Filename, sessionId, file.Size, fd, podDir, blockSize, compression, chunking)
 		if err != nil {
 			if err == dfs.ErrPodNotOpen {
 				h.logger.Errorf("file upload: %v", err)
@@ -126,3 +143,37 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 		References: references,
 	})
 }
+
+// fileUploadStream uploads the body of the request as the file named in the file_name
+// argument. The upload ends at the end of the body.
+func (h *Handler) fileUploadStream(w http.ResponseWriter, r *http.Request, sessionId, podDir, blockSize, compression, chunking string) {
+	fileName := r.FormValue("file_name")
+	if fileName == "" {
+		h.logger.Errorf("file upload: \"file_name\" argument missing")
+		jsonhttp.BadRequest(w, "file upload: \"file_name\" argument missing")
+		return
+	}
+	fileSize := r.ContentLength
+	if fileSize < 0 {
+		fileSize = f.UnknownFileSize
+	}
+
+	var references []Reference
+	reference, err := h.dfsAPI.UploadFile(r.Context(), fileName, sessionId, fileSize, r.Body, podDir, blockSize, compression, chunking)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen {
+			h.logger.Errorf("file upload: %v", err)
+			jsonhttp.BadRequest(w, "file upload: "+err.Error())
+			return
+		}
+		h.logger.Errorf("file upload: %v", err)
+		references = append(references, Reference{FileName: fileName, Error: err.Error()})
+	} else {
+		references = append(references, Reference{FileName: fileName, Reference: reference})
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &UploadFileResponse{
+		References: references,
+	})
+}
@@ -0,0 +1,341 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+const (
+	ChecksumHeader = "fairOS-dfs-Checksum"
+)
+
+type UploadSessionResponse struct {
+	UploadId   string          `json:"upload_id"`
+	FileSize   uint64          `json:"file_size"`
+	BlockSize  uint32          `json:"block_size"`
+	NoOfBlocks int             `json:"no_of_blocks"`
+	Received   []ReceivedBlock `json:"received"`
+	Missing    []int           `json:"missing"`
+}
+
+type ReceivedBlock struct {
+	Index    int    `json:"index"`
+	Checksum string `json:"checksum"`
+}
+
+func newUploadSessionResponse(session *file.UploadSession) *UploadSessionResponse {
+	received := make([]ReceivedBlock, 0, len(session.Blocks))
+	for index, block := range session.Blocks {
+		received = append(received, ReceivedBlock{Index: index, Checksum: hex.EncodeToString(block.Checksum)})
+	}
+	sort.Slice(received, func(i, j int) bool {
+		return received[i].Index < received[j].Index
+	})
+	missing := session.MissingBlocks()
+	if missing == nil {
+		missing = []int{}
+	}
+	return &UploadSessionResponse{
+		UploadId:   session.Id,
+		FileSize:   session.FileSize,
+		BlockSize:  session.BlockSize,
+		NoOfBlocks: session.NoOfBlocks(),
+		Received:   received,
+		Missing:    missing,
+	}
+}
+
+// isUploadSessionError tells if err is caused by the request to an upload session.
+func isUploadSessionError(err error) bool {
+	return err == dfs.ErrPodNotOpen || err == dfs.ErrUploadPodNotOpen || err == p.ErrReadOnlyPod ||
+		err == p.ErrDestinationExists || err == p.ErrFileOrDirNotFound || err == file.ErrInvalidFileSize ||
+		err == file.ErrInvalidBlockSize || err == file.ErrInvalidBlockIndex || err == file.ErrInvalidBlockLength ||
+		err == file.ErrChecksumMismatch || err == file.ErrMissingBlocks || err == file.ErrTooManyBlocks
+}
+
+// FileUploadSessionHandler starts the upload of a file in blocks.
+func (h *Handler) FileUploadSessionHandler(w http.ResponseWriter, r *http.Request) {
+	podDir := r.FormValue("pod_dir")
+	fileName := r.FormValue("file_name")
+	fileSize := r.FormValue("file_size")
+	blockSize := r.FormValue("block_size")
+	compression := r.Header.Get(CompressionHeader)
+	if podDir == "" {
+		h.logger.Errorf("upload session: \"pod_dir\" argument missing")
+		jsonhttp.BadRequest(w, "upload session: \"pod_dir\" argument missing")
+		return
+	}
+	if fileName == "" {
+		h.logger.Errorf("upload session: \"file_name\" argument missing")
+		jsonhttp.BadRequest(w, "upload session: \"file_name\" argument missing")
+		return
+	}
+	size, err := strconv.ParseInt(fileSize, 10, 64)
+	if err != nil {
+		h.logger.Errorf("upload session: invalid \"file_size\" argument")
+		jsonhttp.BadRequest(w, "upload session: invalid \"file_size\" argument")
+		return
+	}
+	if blockSize == "" {
+		h.logger.Errorf("upload session: \"block_size\" argument missing")
+		jsonhttp.BadRequest(w, "upload session: \"block_size\" argument missing")
+		return
+	}
+	if compression != "" {
+		if _, _, err := file.ParseCompression(compression); err != nil {
+			h.logger.Errorf("upload session: invalid value for \"compression\" header")
+			jsonhttp.BadRequest(w, "upload session: invalid value for \"compression\" header")
+			return
+		}
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("upload session: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("upload session: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "upload session: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	session, err := h.dfsAPI.NewUploadSession(fileName, sessionId, size, podDir, blockSize, compression)
+	if err != nil {
+		if isUploadSessionError(err) {
+			h.logger.Errorf("upload session: %v", err)
+			jsonhttp.BadRequest(w, "upload session: "+err.Error())
+			return
+		}
+		h.logger.Errorf("upload session: %v", err)
+		jsonhttp.InternalServerError(w, "upload session: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.Created(w, newUploadSessionResponse(session))
+}
+
+// FileUploadSessionStatusHandler tells which blocks of an upload arrived and which
+// are still missing.
+func (h *Handler) FileUploadSessionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("upload session status: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "upload session status: \"upload_id\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("upload session status: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("upload session status: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "upload session status: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	session, err := h.dfsAPI.GetUploadSession(uploadId, sessionId)
+	if err != nil {
+		if err == file.ErrUploadSessionNotFound {
+			h.logger.Errorf("upload session status: %v", err)
+			jsonhttp.NotFound(w, "upload session status: "+err.Error())
+			return
+		}
+		h.logger.Errorf("upload session status: %v", err)
+		jsonhttp.InternalServerError(w, "upload session status: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// FileUploadBlockHandler receives one block of an upload as the body of the request.
+// The block is identified by its index and checked against the sha256 in the
+// checksum header.
+func (h *Handler) FileUploadBlockHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("upload block: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "upload block: \"upload_id\" argument missing")
+		return
+	}
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		h.logger.Errorf("upload block: invalid \"index\" argument")
+		jsonhttp.BadRequest(w, "upload block: invalid \"index\" argument")
+		return
+	}
+	checksum := r.Header.Get(ChecksumHeader)
+	if checksum == "" {
+		h.logger.Errorf("upload block: \"%s\" header missing", ChecksumHeader)
+		jsonhttp.BadRequest(w, "upload block: \""+ChecksumHeader+"\" header missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("upload block: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("upload block: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "upload block: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	session, err := h.dfsAPI.GetUploadSession(uploadId, sessionId)
+	if err != nil {
+		if err == file.ErrUploadSessionNotFound {
+			h.logger.Errorf("upload block: %v", err)
+			jsonhttp.NotFound(w, "upload block: "+err.Error())
+			return
+		}
+		h.logger.Errorf("upload block: %v", err)
+		jsonhttp.InternalServerError(w, "upload block: "+err.Error())
+		return
+	}
+
+	// a body longer than a block is cut, its length then does not match the block
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(session.BlockSize)+1))
+	if err != nil {
+		h.logger.Errorf("upload block: %v", err)
+		jsonhttp.BadRequest(w, "upload block: "+err.Error())
+		return
+	}
+
+	err = h.dfsAPI.UploadSessionBlock(r.Context(), uploadId, sessionId, index, data, checksum)
+	if err != nil {
+		if err == file.ErrUploadSessionNotFound {
+			h.logger.Errorf("upload block: %v", err)
+			jsonhttp.NotFound(w, "upload block: "+err.Error())
+			return
+		}
+		if isUploadSessionError(err) {
+			h.logger.Errorf("upload block: %v", err)
+			jsonhttp.BadRequest(w, "upload block: "+err.Error())
+			return
+		}
+		h.logger.Errorf("upload block: %v", err)
+		jsonhttp.InternalServerError(w, "upload block: "+err.Error())
+		return
+	}
+
+	jsonhttp.OK(w, "block uploaded")
+}
+
+// FileUploadFinishHandler creates the file once all the blocks of the upload arrived.
+func (h *Handler) FileUploadFinishHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("upload finish: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "upload finish: \"upload_id\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("upload finish: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("upload finish: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "upload finish: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	reference, err := h.dfsAPI.FinishUploadSession(r.Context(), uploadId, sessionId)
+	if err != nil {
+		if err == file.ErrUploadSessionNotFound {
+			h.logger.Errorf("upload finish: %v", err)
+			jsonhttp.NotFound(w, "upload finish: "+err.Error())
+			return
+		}
+		if isUploadSessionError(err) {
+			h.logger.Errorf("upload finish: %v", err)
+			jsonhttp.BadRequest(w, "upload finish: "+err.Error())
+			return
+		}
+		h.logger.Errorf("upload finish: %v", err)
+		jsonhttp.InternalServerError(w, "upload finish: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &Reference{Reference: reference})
+}
+
+// FileUploadSessionDeleteHandler gives up an upload.
+func (h *Handler) FileUploadSessionDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("upload session delete: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "upload session delete: \"upload_id\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("upload session delete: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("upload session delete: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "upload session delete: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.DeleteUploadSession(uploadId, sessionId)
+	if err != nil {
+		if err == file.ErrUploadSessionNotFound {
+			h.logger.Errorf("upload session delete: %v", err)
+			jsonhttp.NotFound(w, "upload session delete: "+err.Error())
+			return
+		}
+		h.logger.Errorf("upload session delete: %v", err)
+		jsonhttp.InternalServerError(w, "upload session delete: "+err.Error())
+		return
+	}
+
+	jsonhttp.OK(w, "upload session deleted")
+}
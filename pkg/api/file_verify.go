@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+type FileVerifyResponse struct {
+	Ok    bool                 `json:"ok"`
+	Files []*file.VerifyResult `json:"files"`
+}
+
+// FileVerifyHandler downloads a file, or all the files of the open pod when no file
+// is given, and reports the blocks which are missing or corrupt.
+func (h *Handler) FileVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file verify: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file verify: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file verify: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// verify the file or the pod
+	results, err := h.dfsAPI.VerifyFile(r.Context(), podFile, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrPodNotOpened {
+			h.logger.Errorf("file verify: %v", err)
+			jsonhttp.BadRequest(w, "file verify: "+err.Error())
+			return
+		}
+		h.logger.Errorf("file verify: %v", err)
+		jsonhttp.InternalServerError(w, "file verify: "+err.Error())
+		return
+	}
+
+	ok := true
+	for _, result := range results {
+		ok = ok && result.Ok
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &FileVerifyResponse{
+		Ok:    ok,
+		Files: results,
+	})
+}
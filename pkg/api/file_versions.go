@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+type FileVersionsResponse struct {
+	Versions []*file.VersionInfo `json:"versions"`
+}
+
+type FilePruneResponse struct {
+	Pruned int `json:"pruned"`
+}
+
+// FileVersionsHandler lists the versions of a file, oldest first.
+func (h *Handler) FileVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file versions: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file versions: \"file\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file versions: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file versions: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file versions: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	versions, err := h.dfsAPI.ListFileVersions(podFile, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrPodNotOpened {
+			h.logger.Errorf("file versions: %v", err)
+			jsonhttp.BadRequest(w, "file versions: "+err.Error())
+			return
+		}
+		h.logger.Errorf("file versions: %v", err)
+		jsonhttp.InternalServerError(w, "file versions: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &FileVersionsResponse{Versions: versions})
+}
+
+// FileRestoreHandler makes a version of a file its current one again.
+func (h *Handler) FileRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file restore: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file restore: \"file\" argument missing")
+		return
+	}
+	version, err := strconv.ParseUint(r.FormValue("version"), 10, 32)
+	if err != nil {
+		h.logger.Errorf("file restore: invalid \"version\" argument")
+		jsonhttp.BadRequest(w, "file restore: invalid \"version\" argument")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file restore: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file restore: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file restore: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.RestoreFileVersion(podFile, uint32(version), sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrPodNotOpened || err == p.ErrReadOnlyPod ||
+			err == file.ErrVersionNotFound {
+			h.logger.Errorf("file restore: %v", err)
+			jsonhttp.BadRequest(w, "file restore: "+err.Error())
+			return
+		}
+		h.logger.Errorf("file restore: %v", err)
+		jsonhttp.InternalServerError(w, "file restore: "+err.Error())
+		return
+	}
+	jsonhttp.OK(w, "file restored")
+}
+
+// FilePruneHandler drops all but the newest versions of a file.
+func (h *Handler) FilePruneHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file prune: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file prune: \"file\" argument missing")
+		return
+	}
+	keep, err := strconv.Atoi(r.FormValue("keep"))
+	if err != nil {
+		h.logger.Errorf("file prune: invalid \"keep\" argument")
+		jsonhttp.BadRequest(w, "file prune: invalid \"keep\" argument")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file prune: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file prune: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file prune: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	pruned, err := h.dfsAPI.PruneFileVersions(podFile, keep, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrPodNotOpened || err == p.ErrReadOnlyPod ||
+			err == file.ErrInvalidVersionCount {
+			h.logger.Errorf("file prune: %v", err)
+			jsonhttp.BadRequest(w, "file prune: "+err.Error())
+			return
+		}
+		h.logger.Errorf("file prune: %v", err)
+		jsonhttp.InternalServerError(w, "file prune: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &FilePruneResponse{Pruned: pruned})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+// FileAppendHandler appends the body of the request to a file.
+func (h *Handler) FileAppendHandler(w http.ResponseWriter, r *http.Request) {
+	h.fileWrite(w, r, "file append", false)
+}
+
+// FileWriteHandler writes the body of the request over a file starting at the given
+// offset, the file grows if the body goes past its end.
+func (h *Handler) FileWriteHandler(w http.ResponseWriter, r *http.Request) {
+	h.fileWrite(w, r, "file write", true)
+}
+
+func (h *Handler) fileWrite(w http.ResponseWriter, r *http.Request, op string, withOffset bool) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("%s: \"file\" argument missing", op)
+		jsonhttp.BadRequest(w, op+": \"file\" argument missing")
+		return
+	}
+	var offset int64
+	if withOffset {
+		var err error
+		offset, err = strconv.ParseInt(r.FormValue("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			h.logger.Errorf("%s: invalid \"offset\" argument", op)
+			jsonhttp.BadRequest(w, op+": invalid \"offset\" argument")
+			return
+		}
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("%s: invalid cookie: %v", op, err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("%s: \"cookie-id\" parameter missing in cookie", op)
+		jsonhttp.BadRequest(w, op+": \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	if withOffset {
+		err = h.dfsAPI.WriteFileAt(r.Context(), podFile, offset, r.Body, sessionId)
+	} else {
+		err = h.dfsAPI.AppendFile(r.Context(), podFile, r.Body, sessionId)
+	}
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == p.ErrPodNotOpened || err == p.ErrReadOnlyPod ||
+			err == file.ErrInvalidOffset {
+			h.logger.Errorf("%s: %v", op, err)
+			jsonhttp.BadRequest(w, op+": "+err.Error())
+			return
+		}
+		h.logger.Errorf("%s: %v", op, err)
+		jsonhttp.InternalServerError(w, op+": "+err.Error())
+		return
+	}
+	jsonhttp.OK(w, "file written")
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package api
 
 import (
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 )
@@ -26,8 +28,8 @@ type Handler struct {
 	logger logging.Logger
 }
 
-func NewHandler(dataDir, beeHost, beePort, cookieDomain string, logger logging.Logger) (*Handler, error) {
-	api, err := dfs.NewDfsAPI(dataDir, beeHost, beePort, cookieDomain, logger)
+func NewHandler(dataDir string, client blockstore.Client, batches *postage.Manager, cookieDomain string, logger logging.Logger) (*Handler, error) {
+	api, err := dfs.NewDfsAPI(dataDir, client, batches, cookieDomain, logger)
 	if err != nil {
 		return nil, dfs.ErrBeeClient
 	}
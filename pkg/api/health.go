@@ -0,0 +1,46 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"resenje.org/jsonhttp"
+)
+
+type HealthResponse struct {
+	Healthy    bool              `json:"healthy"`
+	BlockStore blockstore.Health `json:"blockStore"`
+}
+
+// HealthHandler reports the health of the blockstore, including the state of the
+// circuit breaker. It answers 503 when the blockstore can not be used.
+func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	health := h.dfsAPI.BlockStoreHealth()
+	resp := &HealthResponse{
+		Healthy:    health.Healthy,
+		BlockStore: health,
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	if !health.Healthy {
+		jsonhttp.ServiceUnavailable(w, resp)
+		return
+	}
+	jsonhttp.OK(w, resp)
+}
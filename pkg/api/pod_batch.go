@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+// PodBatchHandler makes the uploads done in a pod of the user go to the postage batch.
+// An empty batch goes back to the batch of the user.
+func (h *Handler) PodBatchHandler(w http.ResponseWriter, r *http.Request) {
+	podName := r.FormValue("pod")
+	if podName == "" {
+		h.logger.Errorf("pod batch: \"pod\" argument missing")
+		jsonhttp.BadRequest(w, "pod batch: \"pod\" argument missing")
+		return
+	}
+	batchID := r.FormValue("batch")
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod batch: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod batch: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod batch: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.SetPodPostageBatch(sessionId, podName, batchID)
+	if err != nil {
+		if err == dfs.ErrUserNotLoggedIn || err == dfs.ErrNoPostage ||
+			err == p.ErrInvalidPodName || errors.Is(err, postage.ErrInvalidBatchID) {
+			h.logger.Errorf("pod batch: %v", err)
+			jsonhttp.BadRequest(w, "pod batch: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod batch: %v", err)
+		jsonhttp.InternalServerError(w, "pod batch: "+err.Error())
+		return
+	}
+	jsonhttp.OK(w, "pod batch set")
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+type PodTreeResponse struct {
+	Entries []*p.TreeEntry `json:"entries"`
+}
+
+type PodDiffResponse struct {
+	Changes []*p.DiffEntry `json:"changes"`
+}
+
+// isDiffUserError tells if err is caused by the request rather than the server.
+func isDiffUserError(err error) bool {
+	return err == dfs.ErrUserNotLoggedIn || err == p.ErrPodNotOpened ||
+		err == p.ErrInvalidDiffSource || err == p.ErrSnapshotNotFound
+}
+
+// PodTreeHandler lists the files and directories below a directory of a pod or a
+// snapshot, with what is needed to diff them against another tree.
+func (h *Handler) PodTreeHandler(w http.ResponseWriter, r *http.Request) {
+	source := r.FormValue("source")
+	if source == "" {
+		h.logger.Errorf("pod tree: \"source\" argument missing")
+		jsonhttp.BadRequest(w, "pod tree: \"source\" argument missing")
+		return
+	}
+	dir := r.FormValue("dir")
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod tree: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod tree: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod tree: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	entries, err := h.dfsAPI.PodTree(source, dir, sessionId)
+	if err != nil {
+		if isDiffUserError(err) {
+			h.logger.Errorf("pod tree: %v", err)
+			jsonhttp.BadRequest(w, "pod tree: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod tree: %v", err)
+		jsonhttp.InternalServerError(w, "pod tree: "+err.Error())
+		return
+	}
+
+	if entries == nil {
+		entries = make([]*p.TreeEntry, 0)
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodTreeResponse{
+		Entries: entries,
+	})
+}
+
+// PodDiffHandler returns the files and directories added, removed, modified and moved
+// from one pod or snapshot to another, below the same directory of both.
+func (h *Handler) PodDiffHandler(w http.ResponseWriter, r *http.Request) {
+	from := r.FormValue("from")
+	if from == "" {
+		h.logger.Errorf("pod diff: \"from\" argument missing")
+		jsonhttp.BadRequest(w, "pod diff: \"from\" argument missing")
+		return
+	}
+	to := r.FormValue("to")
+	if to == "" {
+		h.logger.Errorf("pod diff: \"to\" argument missing")
+		jsonhttp.BadRequest(w, "pod diff: \"to\" argument missing")
+		return
+	}
+	dir := r.FormValue("dir")
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod diff: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod diff: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod diff: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	changes, err := h.dfsAPI.PodDiff(from, to, dir, sessionId)
+	if err != nil {
+		if isDiffUserError(err) {
+			h.logger.Errorf("pod diff: %v", err)
+			jsonhttp.BadRequest(w, "pod diff: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod diff: %v", err)
+		jsonhttp.InternalServerError(w, "pod diff: "+err.Error())
+		return
+	}
+
+	if changes == nil {
+		changes = make([]*p.DiffEntry, 0)
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodDiffResponse{
+		Changes: changes,
+	})
+}
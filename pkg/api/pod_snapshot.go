@@ -0,0 +1,247 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+type SnapshotCreateResponse struct {
+	Reference string `json:"reference"`
+}
+
+type SnapshotListResponse struct {
+	Snapshots []*p.Snapshot `json:"snapshots"`
+}
+
+// isSnapshotUserError tells if err is caused by the request rather than the server.
+func isSnapshotUserError(err error) bool {
+	return err == dfs.ErrUserNotLoggedIn || err == dfs.ErrPodNotOpen ||
+		err == p.ErrPodNotOpened || err == p.ErrReadOnlyPod ||
+		err == p.ErrInvalidSnapshotName || err == p.ErrSnapshotExists ||
+		err == p.ErrSnapshotNotFound || err == p.ErrSnapshotPodOpened ||
+		err == p.ErrInvalidPodName || err == p.ErrTooLongPodName ||
+		err == p.ErrPodAlreadyExists || err == p.ErrMaxPodsReached
+}
+
+// PodSnapshotHandler freezes the open pod under the given snapshot name.
+func (h *Handler) PodSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot := r.FormValue("snapshot")
+	if snapshot == "" {
+		h.logger.Errorf("pod snapshot: \"snapshot\" argument missing")
+		jsonhttp.BadRequest(w, "pod snapshot: \"snapshot\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod snapshot: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod snapshot: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod snapshot: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// snapshot the pod
+	s, err := h.dfsAPI.CreateSnapshot(snapshot, sessionId)
+	if err != nil {
+		if isSnapshotUserError(err) {
+			h.logger.Errorf("pod snapshot: %v", err)
+			jsonhttp.BadRequest(w, "pod snapshot: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod snapshot: %v", err)
+		jsonhttp.InternalServerError(w, "pod snapshot: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.Created(w, &SnapshotCreateResponse{
+		Reference: s.Reference,
+	})
+}
+
+// PodSnapshotListHandler lists the snapshots of all the pods of the user.
+func (h *Handler) PodSnapshotListHandler(w http.ResponseWriter, r *http.Request) {
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod snapshots: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod snapshots: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod snapshots: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	snapshots, err := h.dfsAPI.ListSnapshots(sessionId)
+	if err != nil {
+		if isSnapshotUserError(err) {
+			h.logger.Errorf("pod snapshots: %v", err)
+			jsonhttp.BadRequest(w, "pod snapshots: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod snapshots: %v", err)
+		jsonhttp.InternalServerError(w, "pod snapshots: "+err.Error())
+		return
+	}
+
+	if snapshots == nil {
+		snapshots = make([]*p.Snapshot, 0)
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &SnapshotListResponse{
+		Snapshots: snapshots,
+	})
+}
+
+// PodSnapshotOpenHandler opens the pod of a snapshot read only, as it was when the
+// snapshot was taken.
+func (h *Handler) PodSnapshotOpenHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot := r.FormValue("snapshot")
+	if snapshot == "" {
+		h.logger.Errorf("snapshot open: \"snapshot\" argument missing")
+		jsonhttp.BadRequest(w, "snapshot open: \"snapshot\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("snapshot open: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("snapshot open: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "snapshot open: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	_, err = h.dfsAPI.OpenSnapshot(snapshot, sessionId)
+	if err != nil {
+		if isSnapshotUserError(err) {
+			h.logger.Errorf("snapshot open: %v", err)
+			jsonhttp.BadRequest(w, "snapshot open: "+err.Error())
+			return
+		}
+		h.logger.Errorf("snapshot open: %v", err)
+		jsonhttp.InternalServerError(w, "snapshot open: "+err.Error())
+		return
+	}
+
+	jsonhttp.OK(w, "snapshot opened successfully")
+}
+
+// PodSnapshotRestoreHandler creates a new pod from a snapshot and opens it.
+func (h *Handler) PodSnapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot := r.FormValue("snapshot")
+	if snapshot == "" {
+		h.logger.Errorf("snapshot restore: \"snapshot\" argument missing")
+		jsonhttp.BadRequest(w, "snapshot restore: \"snapshot\" argument missing")
+		return
+	}
+	pod := r.FormValue("pod")
+	if pod == "" {
+		h.logger.Errorf("snapshot restore: \"pod\" argument missing")
+		jsonhttp.BadRequest(w, "snapshot restore: \"pod\" argument missing")
+		return
+	}
+	password := r.FormValue("password")
+	if password == "" {
+		h.logger.Errorf("snapshot restore: \"password\" argument missing")
+		jsonhttp.BadRequest(w, "snapshot restore: \"password\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("snapshot restore: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("snapshot restore: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "snapshot restore: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	_, err = h.dfsAPI.RestoreSnapshot(snapshot, pod, password, sessionId)
+	if err != nil {
+		if isSnapshotUserError(err) {
+			h.logger.Errorf("snapshot restore: %v", err)
+			jsonhttp.BadRequest(w, "snapshot restore: "+err.Error())
+			return
+		}
+		h.logger.Errorf("snapshot restore: %v", err)
+		jsonhttp.InternalServerError(w, "snapshot restore: "+err.Error())
+		return
+	}
+
+	jsonhttp.Created(w, "pod restored successfully")
+}
+
+// PodSnapshotDeleteHandler forgets a snapshot, its blobs are removed by the garbage
+// collector.
+func (h *Handler) PodSnapshotDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot := r.FormValue("snapshot")
+	if snapshot == "" {
+		h.logger.Errorf("snapshot delete: \"snapshot\" argument missing")
+		jsonhttp.BadRequest(w, "snapshot delete: \"snapshot\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("snapshot delete: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("snapshot delete: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "snapshot delete: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.DeleteSnapshot(snapshot, sessionId)
+	if err != nil {
+		if isSnapshotUserError(err) {
+			h.logger.Errorf("snapshot delete: %v", err)
+			jsonhttp.BadRequest(w, "snapshot delete: "+err.Error())
+			return
+		}
+		h.logger.Errorf("snapshot delete: %v", err)
+		jsonhttp.InternalServerError(w, "snapshot delete: "+err.Error())
+		return
+	}
+
+	jsonhttp.OK(w, "snapshot deleted successfully")
+}
@@ -33,6 +33,8 @@ type PodStatResponse struct {
 	CreationTime     string `json:"cTime"`
 	AccessTime       string `json:"aTime"`
 	ModificationTime string `json:"mTime"`
+	Versioning       bool   `json:"versioning"`
+	Snapshot         string `json:"snapshot,omitempty"`
 }
 
 func (h *Handler) PodStatHandler(w http.ResponseWriter, r *http.Request) {
@@ -77,5 +79,7 @@ func (h *Handler) PodStatHandler(w http.ResponseWriter, r *http.Request) {
 		CreationTime:     stat.CreationTime,
 		AccessTime:       stat.AccessTime,
 		ModificationTime: stat.ModificationTime,
+		Versioning:       stat.Versioning,
+		Snapshot:         stat.Snapshot,
 	})
 }
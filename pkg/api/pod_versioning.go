@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	p "github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+// PodVersioningHandler turns the versioning of the files of the open pod on or off.
+func (h *Handler) PodVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	enableStr := r.FormValue("enable")
+	if enableStr == "" {
+		h.logger.Errorf("pod versioning: \"enable\" argument missing")
+		jsonhttp.BadRequest(w, "pod versioning: \"enable\" argument missing")
+		return
+	}
+	enable, err := strconv.ParseBool(enableStr)
+	if err != nil {
+		h.logger.Errorf("pod versioning: invalid \"enable\" argument")
+		jsonhttp.BadRequest(w, "pod versioning: invalid \"enable\" argument")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod versioning: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod versioning: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod versioning: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.SetPodVersioning(enable, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
+			err == p.ErrPodNotOpened || err == p.ErrReadOnlyPod {
+			h.logger.Errorf("pod versioning: %v", err)
+			jsonhttp.BadRequest(w, "pod versioning: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod versioning: %v", err)
+		jsonhttp.InternalServerError(w, "pod versioning: "+err.Error())
+		return
+	}
+	if enable {
+		jsonhttp.OK(w, "versioning enabled")
+	} else {
+		jsonhttp.OK(w, "versioning disabled")
+	}
+}
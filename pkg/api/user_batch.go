@@ -0,0 +1,110 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+)
+
+type PostageBatchResponse struct {
+	BatchID string              `json:"batchID"`
+	Usage   *postage.BatchUsage `json:"usage,omitempty"`
+}
+
+// UserBatchHandler returns the postage batch the uploads of the user go to, given the
+// pod that is open, and how much of it this server used.
+func (h *Handler) UserBatchHandler(w http.ResponseWriter, r *http.Request) {
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("user batch: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("user batch: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "user batch: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	batchID, err := h.dfsAPI.GetPostageBatch(sessionId)
+	if err != nil {
+		if err == dfs.ErrUserNotLoggedIn || err == dfs.ErrNoPostage {
+			h.logger.Errorf("user batch: %v", err)
+			jsonhttp.BadRequest(w, "user batch: "+err.Error())
+			return
+		}
+		h.logger.Errorf("user batch: %v", err)
+		jsonhttp.InternalServerError(w, "user batch: "+err.Error())
+		return
+	}
+
+	// there is no usage to report when no batch is set at all
+	resp := &PostageBatchResponse{BatchID: batchID}
+	if batchID != "" {
+		usage, err := h.dfsAPI.PostageBatchUsage(batchID)
+		if err != nil {
+			h.logger.Errorf("user batch: %v", err)
+			jsonhttp.InternalServerError(w, "user batch: "+err.Error())
+			return
+		}
+		resp.Usage = usage
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, resp)
+}
+
+// UserSetBatchHandler makes the uploads of the user go to the postage batch, unless
+// the pod has a batch of its own. An empty batch goes back to the default batch.
+func (h *Handler) UserSetBatchHandler(w http.ResponseWriter, r *http.Request) {
+	batchID := r.FormValue("batch")
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("user set batch: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("user set batch: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "user set batch: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.SetUserPostageBatch(sessionId, batchID)
+	if err != nil {
+		if err == dfs.ErrUserNotLoggedIn || err == dfs.ErrNoPostage ||
+			errors.Is(err, postage.ErrInvalidBatchID) {
+			h.logger.Errorf("user set batch: %v", err)
+			jsonhttp.BadRequest(w, "user set batch: "+err.Error())
+			return
+		}
+		h.logger.Errorf("user set batch: %v", err)
+		jsonhttp.InternalServerError(w, "user set batch: "+err.Error())
+		return
+	}
+	jsonhttp.OK(w, "user batch set")
+}
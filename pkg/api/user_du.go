@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	u "github.com/fairdatasociety/fairOS-dfs/pkg/user"
+)
+
+// UserDiskUsageHandler returns the storage taken by each pod of the user and by all
+// of them together.
+func (h *Handler) UserDiskUsageHandler(w http.ResponseWriter, r *http.Request) {
+	password := r.FormValue("password")
+	if password == "" {
+		h.logger.Errorf("user du: \"password\" argument missing")
+		jsonhttp.BadRequest(w, "user du: \"password\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("user du: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("user du: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "user du: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// add up the usage of all the pods
+	usage, err := h.dfsAPI.DiskUsage(password, sessionId)
+	if err != nil {
+		if err == dfs.ErrUserNotLoggedIn ||
+			err == u.ErrInvalidPassword {
+			h.logger.Errorf("user du: %v", err)
+			jsonhttp.BadRequest(w, "user du: "+err.Error())
+			return
+		}
+		h.logger.Errorf("user du: %v", err)
+		jsonhttp.InternalServerError(w, "user du: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, usage)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/cookie"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	u "github.com/fairdatasociety/fairOS-dfs/pkg/user"
+)
+
+func (h *Handler) UserGCHandler(w http.ResponseWriter, r *http.Request) {
+	password := r.FormValue("password")
+	if password == "" {
+		h.logger.Errorf("user gc: \"password\" argument missing")
+		jsonhttp.BadRequest(w, "user gc: \"password\" argument missing")
+		return
+	}
+
+	dryRun := false
+	dryRunStr := r.FormValue("dry_run")
+	if dryRunStr != "" {
+		dry, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			h.logger.Errorf("user gc: invalid \"dry_run\" argument")
+			jsonhttp.BadRequest(w, "user gc: invalid \"dry_run\" argument")
+			return
+		}
+		dryRun = dry
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("user gc: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("user gc: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "user gc: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// collect the garbage of the user
+	report, err := h.dfsAPI.CollectGarbage(password, sessionId, dryRun)
+	if err != nil {
+		if err == dfs.ErrUserNotLoggedIn ||
+			err == u.ErrInvalidPassword {
+			h.logger.Errorf("user gc: %v", err)
+			jsonhttp.BadRequest(w, "user gc: "+err.Error())
+			return
+		}
+		h.logger.Errorf("user gc: %v", err)
+		jsonhttp.InternalServerError(w, "user gc: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, report)
+}
@@ -0,0 +1,334 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package beetest runs a fake bee node in the test process, so that the bee client and
+// everything built on it can be tested over real HTTP without a bee node:
+//
+//	srv, err := beetest.NewServer()
+//	...
+//	defer srv.Close()
+//	host, port := srv.HostPort()
+//	client := bee.NewBeeClient(host, port, nil, logger)
+//
+// The chunks are kept by a local blockstore in a temporary directory, so blob
+// references are the same BMT addresses bee returns. Encrypted uploads get a reference
+// of the length bee gives them, but the data is stored as it is.
+package beetest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/local"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/gorilla/mux"
+	"resenje.org/jsonhttp"
+)
+
+const (
+	pinHeader     = "Swarm-Pin"
+	encryptHeader = "Swarm-Encrypt"
+	postageHeader = "Swarm-Postage-Batch-Id"
+)
+
+// Request is a request the server received.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+}
+
+type batch struct {
+	capacity uint64
+	used     uint64
+}
+
+// Server is a fake bee node serving the chunk, bytes and pinning endpoints.
+type Server struct {
+	*httptest.Server
+	dir   string
+	store *local.LocalClient
+
+	mu       sync.Mutex
+	requests []Request
+	failures []int
+	batches  map[string]*batch
+}
+
+// NewServer starts a fake bee node. Close stops it and removes its data.
+func NewServer() (*Server, error) {
+	dir, err := ioutil.TempDir("", "beetest")
+	if err != nil {
+		return nil, err
+	}
+	store, err := local.NewLocalClient(dir, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	s := &Server{
+		dir:     dir,
+		store:   store,
+		batches: make(map[string]*batch),
+	}
+
+	router := mux.NewRouter()
+	router.Use(s.middleware)
+	router.HandleFunc("/", s.healthHandler).Methods("GET")
+	router.HandleFunc("/chunks/{address}", s.chunkUploadHandler).Methods("POST")
+	router.HandleFunc("/chunks/{address}", s.chunkDownloadHandler).Methods("GET")
+	router.HandleFunc("/bytes", s.bytesUploadHandler).Methods("POST")
+	router.HandleFunc("/bytes/{address}", s.bytesDownloadHandler).Methods("GET")
+	router.HandleFunc("/pin/chunks/{address}", s.pinChunkHandler).Methods("POST")
+	router.HandleFunc("/pin/chunks/{address}", s.unpinChunkHandler).Methods("DELETE")
+	router.HandleFunc("/pin/bytes/{address}", s.unpinBytesHandler).Methods("DELETE")
+	s.Server = httptest.NewServer(router)
+	return s, nil
+}
+
+// Close stops the server and removes the stored chunks.
+func (s *Server) Close() {
+	s.Server.Close()
+	_ = os.RemoveAll(s.dir)
+}
+
+// HostPort returns the host and port to give to bee.NewBeeClient.
+func (s *Server) HostPort() (host, port string) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return "", ""
+	}
+	host, port, _ = net.SplitHostPort(u.Host)
+	return host, port
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// FailNext makes the next n requests fail with the given status code.
+func (s *Server) FailNext(n, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// AddBatch makes the node accept the postage batch for the given number of chunks.
+// Once a batch is added, uploads without a known batch are refused.
+func (s *Server) AddBatch(batchID string, chunks uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches[batchID] = &batch{capacity: chunks}
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone()})
+		status := 0
+		if len(s.failures) > 0 {
+			status = s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+		if status != 0 {
+			jsonhttp.Respond(w, status, nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "Ethereum Swarm Bee\n")
+}
+
+func (s *Server) chunkUploadHandler(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(w, r)
+	if !ok {
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		jsonhttp.BadRequest(w, "invalid chunk data")
+		return
+	}
+	if !s.stamp(w, r, 1) {
+		return
+	}
+	_, err = s.store.UploadChunkContext(r.Context(), swarm.NewChunk(addr, data), r.Header.Get(pinHeader) == "true")
+	if err != nil {
+		jsonhttp.BadRequest(w, "chunk write error")
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+func (s *Server) chunkDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(w, r)
+	if !ok {
+		return
+	}
+	data, err := s.store.DownloadChunk(r.Context(), addr.Bytes())
+	if err != nil {
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	w.Header().Set("Content-Type", "binary/octet-stream")
+	_, _ = w.Write(data)
+}
+
+type bytesPostResponse struct {
+	Reference swarm.Address `json:"reference"`
+}
+
+func (s *Server) bytesUploadHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		jsonhttp.BadRequest(w, "invalid body")
+		return
+	}
+	encrypt := r.Header.Get(encryptHeader) == "true"
+	if !s.stamp(w, r, postage.ChunkCount(int64(len(data)), encrypt)) {
+		return
+	}
+	ref, err := s.store.UploadBlobContext(r.Context(), data, r.Header.Get(pinHeader) == "true", encrypt)
+	if err != nil {
+		jsonhttp.InternalServerError(w, "upload error")
+		return
+	}
+	if encrypt {
+		key := make([]byte, swarm.HashSize)
+		_, err = rand.Read(key)
+		if err != nil {
+			jsonhttp.InternalServerError(w, "upload error")
+			return
+		}
+		ref = append(ref, key...)
+	}
+	jsonhttp.OK(w, bytesPostResponse{Reference: swarm.NewAddress(ref)})
+}
+
+func (s *Server) bytesDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(w, r)
+	if !ok {
+		return
+	}
+	rc, _, err := s.store.DownloadBlobStream(r.Context(), addr.Bytes())
+	if err != nil {
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = io.Copy(w, rc)
+}
+
+func (s *Server) pinChunkHandler(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(w, r)
+	if !ok {
+		return
+	}
+	data, err := s.store.DownloadChunk(r.Context(), addr.Bytes())
+	if err != nil {
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	_, err = s.store.UploadChunkContext(r.Context(), swarm.NewChunk(addr, data), true)
+	if err != nil {
+		jsonhttp.InternalServerError(w, "pin chunk failed")
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+func (s *Server) unpinChunkHandler(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(w, r)
+	if !ok {
+		return
+	}
+	err := s.store.DeleteChunkContext(r.Context(), addr.Bytes())
+	if err != nil {
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+func (s *Server) unpinBytesHandler(w http.ResponseWriter, r *http.Request) {
+	addr, ok := parseAddress(w, r)
+	if !ok {
+		return
+	}
+	err := s.store.DeleteBlobContext(r.Context(), addr.Bytes())
+	if err != nil {
+		jsonhttp.NotFound(w, nil)
+		return
+	}
+	jsonhttp.OK(w, nil)
+}
+
+// stamp checks the postage batch of an upload of the given number of chunks, if the
+// node has batches.
+func (s *Server) stamp(w http.ResponseWriter, r *http.Request, chunks uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.batches) == 0 {
+		return true
+	}
+	batchID := r.Header.Get(postageHeader)
+	b, ok := s.batches[batchID]
+	if !ok {
+		jsonhttp.BadRequest(w, "invalid postage batch id")
+		return false
+	}
+	if b.used+chunks > b.capacity {
+		jsonhttp.PaymentRequired(w, "batch is overissued")
+		return false
+	}
+	b.used += chunks
+	return true
+}
+
+// parseAddress reads the address of the request path. An encrypted reference is
+// followed by its key, which is dropped since the data is not encrypted.
+func parseAddress(w http.ResponseWriter, r *http.Request) (swarm.Address, bool) {
+	str := strings.ToLower(mux.Vars(r)["address"])
+	b, err := hex.DecodeString(str)
+	if err != nil || (len(b) != swarm.HashSize && len(b) != 2*swarm.HashSize) {
+		jsonhttp.BadRequest(w, "invalid address")
+		return swarm.ZeroAddress, false
+	}
+	return swarm.NewAddress(b[:swarm.HashSize]), true
+}
@@ -23,13 +23,17 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/ethersphere/bee/pkg/swarm"
 	bmtlegacy "github.com/ethersphere/bmt/legacy"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/sirupsen/logrus"
@@ -49,6 +53,7 @@ const (
 	pinBlobsUrl            = "/pin/bytes/" // need to change this when bee supports it
 	SwarmPinHeader         = "Swarm-Pin"
 	SwarmEncryptHeader     = "Swarm-Encrypt"
+	SwarmPostageHeader     = "Swarm-Postage-Batch-Id"
 )
 
 type BeeClient struct {
@@ -60,6 +65,7 @@ type BeeClient struct {
 	chunkCache         *lru.Cache
 	uploadBlockCache   *lru.Cache
 	downloadBlockCache *lru.Cache
+	batches            *postage.Manager
 	logger             logging.Logger
 }
 
@@ -71,7 +77,10 @@ type bytesPostResponse struct {
 	Reference swarm.Address `json:"reference"`
 }
 
-func NewBeeClient(host, port string, logger logging.Logger) *BeeClient {
+// NewBeeClient creates a client for the bee node at host:port. Uploads are stamped with
+// the batch in their context or else the default batch of batches, which can be nil
+// for nodes that do not need postage.
+func NewBeeClient(host, port string, batches *postage.Manager, logger logging.Logger) *BeeClient {
 	p := bmtlegacy.NewTreePool(hashFunc, swarm.Branches, bmtlegacy.PoolSize)
 	cache, err := lru.New(chunkCacheSize)
 	if err != nil {
@@ -95,6 +104,7 @@ func NewBeeClient(host, port string, logger logging.Logger) *BeeClient {
 		chunkCache:         cache,
 		uploadBlockCache:   uploadBlockCache,
 		downloadBlockCache: downloadBlockCache,
+		batches:            batches,
 		logger:             logger,
 	}
 }
@@ -132,10 +142,14 @@ func (s *BeeClient) CheckConnection() bool {
 
 // upload a chunk in bee
 func (s *BeeClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err error) {
+	return s.UploadChunkContext(context.Background(), ch, pin)
+}
+
+func (s *BeeClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
 	to := time.Now()
 	path := filepath.Join(ChunkUploadDownloadUrl, ch.Address().String())
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodPost, fullUrl, bytes.NewBuffer(ch.Data()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullUrl, bytes.NewBuffer(ch.Data()))
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +157,10 @@ func (s *BeeClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err e
 	if pin {
 		req.Header.Set(SwarmPinHeader, "true")
 	}
+	batchID, err := s.setBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := s.client.Do(req)
 	if err != nil {
@@ -151,8 +169,14 @@ func (s *BeeClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err e
 	req.Close = true
 
 	if response.StatusCode != http.StatusOK {
+		err := s.batchError(batchID, response)
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
 		return nil, errors.New("error uploading data")
 	}
+	s.recordUpload(batchID, 1, int64(len(ch.Data())))
 	err = response.Body.Close()
 	if err != nil {
 		return nil, err
@@ -179,13 +203,11 @@ func (s *BeeClient) DownloadChunk(ctx context.Context, address []byte) (data []b
 
 	path := filepath.Join(ChunkUploadDownloadUrl, addrString)
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodGet, fullUrl, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req = req.WithContext(ctx)
-
 	response, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -193,6 +215,10 @@ func (s *BeeClient) DownloadChunk(ctx context.Context, address []byte) (data []b
 	req.Close = true
 
 	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		if response.StatusCode == http.StatusNotFound {
+			return nil, blockstore.ErrDataNotFound
+		}
 		return nil, errors.New("error downloading data")
 	}
 
@@ -214,20 +240,43 @@ func (s *BeeClient) DownloadChunk(ctx context.Context, address []byte) (data []b
 	return data, nil
 }
 
-// upload a chunk in bee
+// upload a blob in bee
 func (s *BeeClient) UploadBlob(data []byte, pin, encrypt bool) (address []byte, err error) {
-	to := time.Now()
+	return s.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
 
+func (s *BeeClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) (address []byte, err error) {
 	// return the ref if this data is already in swarm
 	if s.inBlockCache(s.uploadBlockCache, string(data)) {
 		return s.getFromBlockCache(s.uploadBlockCache, string(data)), nil
 	}
 
+	ref, err := s.UploadBlobStream(ctx, bytes.NewReader(data), pin, encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	// add the data and ref if itis not in cache
+	if !s.inBlockCache(s.uploadBlockCache, string(data)) {
+		s.addToBlockCache(s.uploadBlockCache, string(data), ref)
+	}
+	return ref, nil
+}
+
+// UploadBlobStream sends the data from the reader to bee as it is read, without
+// buffering it in memory. Readers which know their length (like bytes.Reader) are sent
+// with a Content-Length, the rest with chunked transfer encoding.
+func (s *BeeClient) UploadBlobStream(ctx context.Context, r io.Reader, pin, encrypt bool) (address []byte, err error) {
+	to := time.Now()
+	counter := &countingReader{r: r}
 	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl)
-	req, err := http.NewRequest(http.MethodPost, fullUrl, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullUrl, counter)
 	if err != nil {
 		return nil, err
 	}
+	if l, ok := r.(interface{ Len() int }); ok {
+		req.ContentLength = int64(l.Len())
+	}
 
 	if pin {
 		req.Header.Set(SwarmPinHeader, "true")
@@ -236,6 +285,10 @@ func (s *BeeClient) UploadBlob(data []byte, pin, encrypt bool) (address []byte,
 	if encrypt {
 		req.Header.Set(SwarmEncryptHeader, "true")
 	}
+	batchID, err := s.setBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	response, err := s.client.Do(req)
 	if err != nil {
@@ -244,6 +297,11 @@ func (s *BeeClient) UploadBlob(data []byte, pin, encrypt bool) (address []byte,
 	req.Close = true
 
 	if response.StatusCode != http.StatusOK {
+		err := s.batchError(batchID, response)
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
 		return nil, errors.New("error uploading blob")
 	}
 
@@ -261,21 +319,21 @@ func (s *BeeClient) UploadBlob(data []byte, pin, encrypt bool) (address []byte,
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling response")
 	}
+	s.recordUpload(batchID, postage.ChunkCount(counter.n, encrypt), counter.n)
 	fields := logrus.Fields{
 		"reference": resp.Reference.String(),
-		"size":      len(data),
+		"size":      counter.n,
 		"duration":  time.Since(to).String(),
 	}
 	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload blob: ")
-
-	// add the data and ref if itis not in cache
-	if !s.inBlockCache(s.uploadBlockCache, string(data)) {
-		s.addToBlockCache(s.uploadBlockCache, string(data), resp.Reference.Bytes())
-	}
 	return resp.Reference.Bytes(), nil
 }
 
 func (s *BeeClient) DownloadBlob(address []byte) ([]byte, int, error) {
+	return s.DownloadBlobContext(context.Background(), address)
+}
+
+func (s *BeeClient) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
 	to := time.Now()
 
 	// return the data if this address is already in cache
@@ -284,27 +342,16 @@ func (s *BeeClient) DownloadBlob(address []byte) ([]byte, int, error) {
 		return s.getFromBlockCache(s.downloadBlockCache, addrString), 200, nil
 	}
 
-	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl + "/" + addrString)
-	req, err := http.NewRequest(http.MethodGet, fullUrl, nil)
-	if err != nil {
-		return nil, http.StatusNotFound, err
-	}
-
-	response, err := s.client.Do(req)
+	body, respCode, err := s.downloadBlobBody(ctx, address)
 	if err != nil {
-		return nil, http.StatusNotFound, err
-	}
-	req.Close = true
-
-	if response.StatusCode != http.StatusOK {
-		return nil, response.StatusCode, errors.New("error downloading blob ")
+		return nil, respCode, err
 	}
 
-	respData, err := ioutil.ReadAll(response.Body)
+	respData, err := ioutil.ReadAll(body)
 	if err != nil {
-		return nil, response.StatusCode, errors.New("error downloading blob")
+		return nil, respCode, errors.New("error downloading blob")
 	}
-	err = response.Body.Close()
+	err = body.Close()
 	if err != nil {
 		return nil, http.StatusOK, err
 	}
@@ -320,15 +367,51 @@ func (s *BeeClient) DownloadBlob(address []byte) ([]byte, int, error) {
 	if !s.inBlockCache(s.downloadBlockCache, addrString) {
 		s.addToBlockCache(s.downloadBlockCache, addrString, respData)
 	}
-	return respData, response.StatusCode, nil
+	return respData, respCode, nil
+}
+
+// DownloadBlobStream returns the body of the bee response as it arrives. The caller
+// has to close the returned reader.
+func (s *BeeClient) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	addrString := swarm.NewAddress(address).String()
+	if s.inBlockCache(s.downloadBlockCache, addrString) {
+		data := s.getFromBlockCache(s.downloadBlockCache, addrString)
+		return ioutil.NopCloser(bytes.NewReader(data)), http.StatusOK, nil
+	}
+	return s.downloadBlobBody(ctx, address)
+}
+
+func (s *BeeClient) downloadBlobBody(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	addrString := swarm.NewAddress(address).String()
+	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl + "/" + addrString)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullUrl, nil)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+
+	response, err := s.client.Do(req)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	req.Close = true
+
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, response.StatusCode, errors.New("error downloading blob ")
+	}
+	return response.Body, response.StatusCode, nil
 }
 
 func (s *BeeClient) DeleteChunk(address []byte) error {
+	return s.DeleteChunkContext(context.Background(), address)
+}
+
+func (s *BeeClient) DeleteChunkContext(ctx context.Context, address []byte) error {
 	to := time.Now()
 	addrString := swarm.NewAddress(address).String()
 	path := filepath.Join(pinChunksUrl, addrString)
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodDelete, fullUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fullUrl, nil)
 	if err != nil {
 		return err
 	}
@@ -340,7 +423,11 @@ func (s *BeeClient) DeleteChunk(address []byte) error {
 	req.Close = true
 
 	if response.StatusCode != http.StatusOK {
-		return err
+		_ = response.Body.Close()
+		if response.StatusCode == http.StatusNotFound {
+			return blockstore.ErrChunkNotFound
+		}
+		return errors.New("error deleting chunk")
 	}
 	err = response.Body.Close()
 	if err != nil {
@@ -355,11 +442,15 @@ func (s *BeeClient) DeleteChunk(address []byte) error {
 }
 
 func (s *BeeClient) DeleteBlob(address []byte) error {
+	return s.DeleteBlobContext(context.Background(), address)
+}
+
+func (s *BeeClient) DeleteBlobContext(ctx context.Context, address []byte) error {
 	to := time.Now()
 	addrString := swarm.NewAddress(address).String()
 	path := filepath.Join(pinBlobsUrl, addrString)
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodDelete, fullUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fullUrl, nil)
 	if err != nil {
 		return err
 	}
@@ -371,7 +462,11 @@ func (s *BeeClient) DeleteBlob(address []byte) error {
 	req.Close = true
 
 	if response.StatusCode != http.StatusOK {
-		return err
+		_ = response.Body.Close()
+		if response.StatusCode == http.StatusNotFound {
+			return blockstore.ErrBlobNotFound
+		}
+		return errors.New("error deleting blob")
 	}
 	err = response.Body.Close()
 	if err != nil {
@@ -386,6 +481,63 @@ func (s *BeeClient) DeleteBlob(address []byte) error {
 	return nil
 }
 
+// setBatch adds the postage batch header to the upload request and returns the batch
+// used, if any.
+func (s *BeeClient) setBatch(ctx context.Context, req *http.Request) (string, error) {
+	batchID, ok := postage.FromContext(ctx)
+	if !ok && s.batches != nil {
+		batchID = s.batches.DefaultBatch()
+	}
+	if batchID == "" {
+		return "", nil
+	}
+	if s.batches != nil {
+		if err := s.batches.CheckUsable(batchID); err != nil {
+			return "", err
+		}
+	}
+	req.Header.Set(SwarmPostageHeader, batchID)
+	return batchID, nil
+}
+
+// batchError converts the answers bee gives for postage problems in to errors which
+// name the batch. Bee answers 402 both for batches which are used up and for ones which
+// can not be used yet or do not exist, the message tells them apart.
+func (s *BeeClient) batchError(batchID string, response *http.Response) error {
+	if response.StatusCode != http.StatusPaymentRequired {
+		return nil
+	}
+	if batchID == "" {
+		return fmt.Errorf("%w: node needs a postage batch for uploads", postage.ErrBatchNotUsable)
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+	if strings.Contains(string(msg), "overissued") || strings.Contains(string(msg), "exhausted") {
+		if s.batches != nil {
+			s.batches.MarkExhausted(batchID)
+		}
+		return fmt.Errorf("%w: %s", postage.ErrBatchExhausted, batchID)
+	}
+	return fmt.Errorf("%w: %s: %s", postage.ErrBatchNotUsable, batchID, strings.TrimSpace(string(msg)))
+}
+
+func (s *BeeClient) recordUpload(batchID string, chunks uint64, size int64) {
+	if batchID != "" && s.batches != nil {
+		s.batches.RecordUpload(batchID, chunks, uint64(size))
+	}
+}
+
+// countingReader counts the bytes read through it, for logging streamed uploads
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // createHTTPClient for connection re-use
 func createHTTPClient() *http.Client {
 	client := &http.Client{
@@ -0,0 +1,227 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bee_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/beetest"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/local"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+func newFakeBee(t *testing.T) (*beetest.Server, *bee.BeeClient) {
+	t.Helper()
+	srv, err := beetest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	host, port := srv.HostPort()
+	return srv, bee.NewBeeClient(host, port, nil, logging.New(ioutil.Discard, 0))
+}
+
+// lastRequest returns the last request with the method
+func lastRequest(t *testing.T, srv *beetest.Server, method string) beetest.Request {
+	t.Helper()
+	requests := srv.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Method == method {
+			return requests[i]
+		}
+	}
+	t.Fatalf("no %s request", method)
+	return beetest.Request{}
+}
+
+func TestBeeClient(t *testing.T) {
+	t.Run("check-connection", func(t *testing.T) {
+		srv, client := newFakeBee(t)
+		if !client.CheckConnection() {
+			t.Fatalf("fake bee not reachable")
+		}
+		srv.FailNext(1, http.StatusInternalServerError)
+		if client.CheckConnection() {
+			t.Fatalf("failing bee reported as reachable")
+		}
+	})
+
+	t.Run("chunk-round-trip", func(t *testing.T) {
+		srv, client := newFakeBee(t)
+		ch, err := content.NewChunk([]byte("some chunk"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := client.UploadChunk(ch, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := lastRequest(t, srv, http.MethodPost)
+		if req.Path != "/chunks/"+ch.Address().String() || req.Header.Get(bee.SwarmPinHeader) != "true" {
+			t.Fatalf("unexpected request %+v", req)
+		}
+		data, err := client.DownloadChunk(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, ch.Data()) {
+			t.Fatalf("downloaded chunk differs")
+		}
+		err = client.DeleteChunk(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = lastRequest(t, srv, http.MethodDelete)
+		if req.Path != "/pin/chunks/"+ch.Address().String() {
+			t.Fatalf("unexpected request %+v", req)
+		}
+	})
+
+	t.Run("missing-chunk", func(t *testing.T) {
+		_, client := newFakeBee(t)
+		_, err := client.DownloadChunk(context.Background(), make([]byte, 32))
+		if !errors.Is(err, blockstore.ErrDataNotFound) {
+			t.Fatalf("unexpected error %v", err)
+		}
+		err = client.DeleteChunk(make([]byte, 32))
+		if !errors.Is(err, blockstore.ErrChunkNotFound) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("blob-round-trip", func(t *testing.T) {
+		srv, client := newFakeBee(t)
+		data := randomBytes(t, 5*swarm.ChunkSize+100)
+		addr, err := client.UploadBlob(data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := lastRequest(t, srv, http.MethodPost)
+		if req.Path != bee.BytesUploadDownloadUrl || req.Header.Get(bee.SwarmPinHeader) != "true" || req.Header.Get(bee.SwarmEncryptHeader) != "" {
+			t.Fatalf("unexpected request %+v", req)
+		}
+
+		// the reference is the one the local store gives for the same data
+		store, err := local.NewLocalClient(t.TempDir(), logging.New(ioutil.Discard, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		localAddr, err := store.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, localAddr) {
+			t.Fatalf("reference differs from the local store")
+		}
+
+		got, respCode, err := client.DownloadBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if respCode != http.StatusOK || !bytes.Equal(got, data) {
+			t.Fatalf("downloaded blob differs")
+		}
+		err = client.DeleteBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.DeleteBlob(addr)
+		if !errors.Is(err, blockstore.ErrBlobNotFound) {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("encrypted-blob", func(t *testing.T) {
+		srv, client := newFakeBee(t)
+		data := randomBytes(t, 1000)
+		addr, err := client.UploadBlob(data, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastRequest(t, srv, http.MethodPost).Header.Get(bee.SwarmEncryptHeader) != "true" {
+			t.Fatalf("encrypt header not sent")
+		}
+		if len(addr) != 2*swarm.HashSize {
+			t.Fatalf("encrypted reference has %d bytes", len(addr))
+		}
+		rc, _, err := client.DownloadBlobStream(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		got, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("downloaded blob differs")
+		}
+	})
+
+	t.Run("missing-blob", func(t *testing.T) {
+		_, client := newFakeBee(t)
+		_, respCode, err := client.DownloadBlob(make([]byte, 32))
+		if err == nil || respCode != http.StatusNotFound {
+			t.Fatalf("unexpected answer %d %v", respCode, err)
+		}
+	})
+
+	t.Run("server-errors", func(t *testing.T) {
+		srv, client := newFakeBee(t)
+		srv.FailNext(1, http.StatusInternalServerError)
+		_, err := client.UploadBlob([]byte("failing"), false, false)
+		if err == nil || err.Error() != "error uploading blob" {
+			t.Fatalf("unexpected error %v", err)
+		}
+		srv.FailNext(1, http.StatusInternalServerError)
+		err = client.DeleteBlob(make([]byte, 32))
+		if err == nil || err.Error() != "error deleting blob" {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("caches", func(t *testing.T) {
+		srv, client := newFakeBee(t)
+		data := []byte("cached blob")
+		addr, err := client.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			_, _, err = client.DownloadBlob(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := len(srv.Requests()); n != 2 {
+			t.Fatalf("expected 2 requests to bee, got %d", n)
+		}
+	})
+}
@@ -17,14 +17,17 @@ limitations under the License.
 package mock
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
-	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"sync"
 
 	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
 )
 
 type MockBeeClient struct {
@@ -56,7 +59,7 @@ func (m *MockBeeClient) DownloadChunk(ctx context.Context, address []byte) (data
 	if data, ok := m.storer[swarm.NewAddress(address).String()]; ok {
 		return data, nil
 	}
-	return nil, fmt.Errorf("error downloading data")
+	return nil, blockstore.ErrDataNotFound
 }
 
 func (m *MockBeeClient) UploadBlob(data []byte, pin, encrypt bool) (address []byte, err error) {
@@ -84,7 +87,7 @@ func (m *MockBeeClient) DeleteChunk(address []byte) error {
 		delete(m.storer, swarm.NewAddress(address).String())
 		return nil
 	}
-	return errors.New("chunk not found")
+	return blockstore.ErrChunkNotFound
 }
 
 func (m *MockBeeClient) DeleteBlob(address []byte) error {
@@ -94,5 +97,56 @@ func (m *MockBeeClient) DeleteBlob(address []byte) error {
 		delete(m.storer, swarm.NewAddress(address).String())
 		return nil
 	}
-	return errors.New("blob not found")
+	return blockstore.ErrBlobNotFound
+}
+
+func (m *MockBeeClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.UploadChunk(ch, pin)
+}
+
+func (m *MockBeeClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) (address []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.UploadBlob(data, pin, encrypt)
+}
+
+func (m *MockBeeClient) UploadBlobStream(ctx context.Context, r io.Reader, pin, encrypt bool) (address []byte, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return m.UploadBlobContext(ctx, data, pin, encrypt)
+}
+
+func (m *MockBeeClient) DownloadBlobContext(ctx context.Context, address []byte) (data []byte, respCode int, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, http.StatusRequestTimeout, err
+	}
+	return m.DownloadBlob(address)
+}
+
+func (m *MockBeeClient) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	data, respCode, err := m.DownloadBlobContext(ctx, address)
+	if err != nil {
+		return nil, respCode, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), respCode, nil
+}
+
+func (m *MockBeeClient) DeleteChunkContext(ctx context.Context, address []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.DeleteChunk(address)
+}
+
+func (m *MockBeeClient) DeleteBlobContext(ctx context.Context, address []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.DeleteBlob(address)
 }
@@ -0,0 +1,190 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bee_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+var (
+	defaultBatch = strings.Repeat("a", 64)
+	podBatch     = strings.Repeat("b", 64)
+	fullBatch    = strings.Repeat("c", 64)
+)
+
+// fakeBee answers blob uploads like bee, rejecting the uploads stamped with fullBatch
+type fakeBee struct {
+	mu      sync.Mutex
+	batches []string
+}
+
+func (f *fakeBee) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, _ = ioutil.ReadAll(r.Body)
+	batchID := r.Header.Get(bee.SwarmPostageHeader)
+	f.mu.Lock()
+	f.batches = append(f.batches, batchID)
+	f.mu.Unlock()
+	switch batchID {
+	case "":
+		http.Error(w, `{"message":"invalid postage batch id","code":400}`, http.StatusBadRequest)
+	case fullBatch:
+		http.Error(w, `{"message":"batch is overissued","code":402}`, http.StatusPaymentRequired)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"reference":"` + strings.Repeat("1", 64) + `"}`))
+	}
+}
+
+func (f *fakeBee) lastBatch() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batches[len(f.batches)-1]
+}
+
+func (f *fakeBee) requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.batches)
+}
+
+func newTestClient(t *testing.T, batches *postage.Manager) (*bee.BeeClient, *fakeBee) {
+	t.Helper()
+	fake := &fakeBee{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bee.NewBeeClient(host, port, batches, logging.New(ioutil.Discard, 0)), fake
+}
+
+func randomBytes(t *testing.T, size int) []byte {
+	t.Helper()
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestPostageBatch(t *testing.T) {
+	t.Run("default-batch", func(t *testing.T) {
+		batches, err := postage.NewManager(defaultBatch, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, fake := newTestClient(t, batches)
+		data := randomBytes(t, 10000)
+		_, err = client.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fake.lastBatch() != defaultBatch {
+			t.Fatalf("expected batch %s, got %s", defaultBatch, fake.lastBatch())
+		}
+		usage := batches.Usage(defaultBatch)
+		if usage.Bytes != 10000 || usage.Chunks != 4 {
+			t.Fatalf("unexpected usage %+v", usage)
+		}
+	})
+
+	t.Run("batch-from-context", func(t *testing.T) {
+		batches, err := postage.NewManager(defaultBatch, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, fake := newTestClient(t, batches)
+		data := randomBytes(t, 100)
+		_, err = client.UploadBlobContext(postage.WithBatch(context.Background(), podBatch), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fake.lastBatch() != podBatch {
+			t.Fatalf("expected batch %s, got %s", podBatch, fake.lastBatch())
+		}
+		if batches.Usage(defaultBatch).Chunks != 0 {
+			t.Fatalf("default batch should not be used")
+		}
+	})
+
+	t.Run("exhausted-batch", func(t *testing.T) {
+		batches, err := postage.NewManager(fullBatch, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, fake := newTestClient(t, batches)
+		data := randomBytes(t, 100)
+		_, err = client.UploadBlob(data, false, false)
+		if !errors.Is(err, postage.ErrBatchExhausted) {
+			t.Fatalf("expected exhausted batch, got %v", err)
+		}
+		if !batches.Usage(fullBatch).Exhausted {
+			t.Fatalf("batch not marked exhausted")
+		}
+
+		// the next upload should fail without asking the node
+		data[0]++
+		_, err = client.UploadBlob(data, false, false)
+		if !errors.Is(err, postage.ErrBatchExhausted) {
+			t.Fatalf("expected exhausted batch, got %v", err)
+		}
+		if fake.requests() != 1 {
+			t.Fatalf("expected 1 request to bee, got %d", fake.requests())
+		}
+	})
+
+	t.Run("capacity-reached", func(t *testing.T) {
+		batches, err := postage.NewManager(defaultBatch, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = batches.SetDepth(defaultBatch, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, _ := newTestClient(t, batches)
+		data := randomBytes(t, 10000)
+		_, err = client.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[0]++
+		_, err = client.UploadBlob(data, false, false)
+		if !errors.Is(err, postage.ErrBatchExhausted) {
+			t.Fatalf("expected exhausted batch, got %v", err)
+		}
+	})
+}
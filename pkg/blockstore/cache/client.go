@@ -0,0 +1,300 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+const (
+	// an entry can take at most this fraction of a tier
+	entryFraction = 8
+	tmpSuffix     = ".tmp"
+
+	kindBlob  = "blobs"
+	kindChunk = "chunks"
+
+	encryptedRefLength = 64
+)
+
+// Stats counts how the reads were served.
+type Stats struct {
+	MemoryHits      uint64 `json:"memoryHits"`
+	DiskHits        uint64 `json:"diskHits"`
+	Misses          uint64 `json:"misses"`
+	MemoryEntries   int    `json:"memoryEntries"`
+	MemoryBytes     int64  `json:"memoryBytes"`
+	MemoryEvictions uint64 `json:"memoryEvictions"`
+	DiskEntries     int    `json:"diskEntries"`
+	DiskBytes       int64  `json:"diskBytes"`
+	DiskEvictions   uint64 `json:"diskEvictions"`
+}
+
+// CacheClient is a blockstore.Client decorator which keeps the data it reads in a
+// memory tier and, if it has a directory, in a disk tier which survives a restart.
+// Only data that can not change under its address is cached: blobs and content
+// addressed chunks. Feed updates are single owner chunks, whose content changes with
+// every update, so they are always read from the backend, as are missing data.
+type CacheClient struct {
+	client blockstore.ClientV2
+	memory *memoryTier
+	disk   *diskTier
+	logger logging.Logger
+
+	memoryHits uint64
+	diskHits   uint64
+	misses     uint64
+}
+
+// NewCacheClient creates a cache which holds up to memoryLimit bytes in memory and
+// diskLimit bytes in files under diskDir. A limit of 0 turns the tier off.
+func NewCacheClient(client blockstore.Client, memoryLimit int64, diskDir string, diskLimit int64, logger logging.Logger) (*CacheClient, error) {
+	c := &CacheClient{
+		client: blockstore.ToV2(client),
+		logger: logger,
+	}
+	if memoryLimit > 0 {
+		c.memory = newMemoryTier(memoryLimit)
+	}
+	if diskLimit > 0 {
+		disk, err := newDiskTier(diskDir, diskLimit)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = disk
+	}
+	return c, nil
+}
+
+func (c *CacheClient) CheckConnection() bool {
+	return c.client.CheckConnection()
+}
+
+func (c *CacheClient) Stats() Stats {
+	s := Stats{
+		MemoryHits: atomic.LoadUint64(&c.memoryHits),
+		DiskHits:   atomic.LoadUint64(&c.diskHits),
+		Misses:     atomic.LoadUint64(&c.misses),
+	}
+	if c.memory != nil {
+		s.MemoryEntries, s.MemoryBytes, s.MemoryEvictions = c.memory.stats()
+	}
+	if c.disk != nil {
+		s.DiskEntries, s.DiskBytes, s.DiskEvictions = c.disk.stats()
+	}
+	return s
+}
+
+func (c *CacheClient) Health() blockstore.Health {
+	backend := blockstore.GetHealth(c.client)
+	return blockstore.Health{
+		Name:    "cache",
+		Healthy: backend.Healthy,
+		Status:  "ok",
+		Details: map[string]interface{}{
+			"stats": c.Stats(),
+		},
+		Backends: []blockstore.Health{backend},
+	}
+}
+
+func (c *CacheClient) UploadChunk(ch swarm.Chunk, pin bool) ([]byte, error) {
+	return c.UploadChunkContext(context.Background(), ch, pin)
+}
+
+// UploadChunkContext drops the chunk from the cache, a single owner chunk can be
+// written again with new content.
+func (c *CacheClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	c.remove(cacheKey(kindChunk, ch.Address().Bytes()))
+	return c.client.UploadChunkContext(ctx, ch, pin)
+}
+
+func (c *CacheClient) DownloadChunk(ctx context.Context, addr []byte) ([]byte, error) {
+	key := cacheKey(kindChunk, addr)
+	if data, ok := c.get(key); ok {
+		return data, nil
+	}
+	data, err := c.client.DownloadChunk(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if content.NewValidator().Validate(swarm.NewChunk(swarm.NewAddress(addr), data)) {
+		c.put(key, data)
+	}
+	return data, nil
+}
+
+func (c *CacheClient) UploadBlob(data []byte, pin, encrypt bool) ([]byte, error) {
+	return c.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
+
+// UploadBlobContext caches the uploaded data, it is usually read again soon.
+func (c *CacheClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	addr, err := c.client.UploadBlobContext(ctx, data, pin, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	c.put(cacheKey(kindBlob, addr), data)
+	return addr, nil
+}
+
+func (c *CacheClient) UploadBlobStream(ctx context.Context, r io.Reader, pin, encrypt bool) ([]byte, error) {
+	return c.client.UploadBlobStream(ctx, r, pin, encrypt)
+}
+
+func (c *CacheClient) DownloadBlob(addr []byte) ([]byte, int, error) {
+	return c.DownloadBlobContext(context.Background(), addr)
+}
+
+func (c *CacheClient) DownloadBlobContext(ctx context.Context, addr []byte) ([]byte, int, error) {
+	key := cacheKey(kindBlob, addr)
+	if data, ok := c.get(key); ok {
+		return data, http.StatusOK, nil
+	}
+	data, respCode, err := c.client.DownloadBlobContext(ctx, addr)
+	if err != nil {
+		return data, respCode, err
+	}
+	c.put(key, data)
+	return data, respCode, nil
+}
+
+// DownloadBlobStream caches a stream that is read to the end, if it is small enough
+// for one of the tiers.
+func (c *CacheClient) DownloadBlobStream(ctx context.Context, addr []byte) (io.ReadCloser, int, error) {
+	key := cacheKey(kindBlob, addr)
+	if data, ok := c.get(key); ok {
+		return ioutil.NopCloser(bytes.NewReader(data)), http.StatusOK, nil
+	}
+	rc, respCode, err := c.client.DownloadBlobStream(ctx, addr)
+	if err != nil {
+		return rc, respCode, err
+	}
+	return &cachingReader{ReadCloser: rc, cache: c, key: key, limit: c.maxEntry()}, respCode, nil
+}
+
+func (c *CacheClient) DeleteChunk(addr []byte) error {
+	return c.DeleteChunkContext(context.Background(), addr)
+}
+
+func (c *CacheClient) DeleteChunkContext(ctx context.Context, addr []byte) error {
+	c.remove(cacheKey(kindChunk, addr))
+	return c.client.DeleteChunkContext(ctx, addr)
+}
+
+func (c *CacheClient) DeleteBlob(addr []byte) error {
+	return c.DeleteBlobContext(context.Background(), addr)
+}
+
+func (c *CacheClient) DeleteBlobContext(ctx context.Context, addr []byte) error {
+	c.remove(cacheKey(kindBlob, addr))
+	return c.client.DeleteBlobContext(ctx, addr)
+}
+
+// get looks in memory and then on disk, data found on disk is kept in memory too.
+func (c *CacheClient) get(key string) ([]byte, bool) {
+	if c.memory != nil {
+		if data, ok := c.memory.get(key); ok {
+			atomic.AddUint64(&c.memoryHits, 1)
+			return data, true
+		}
+	}
+	if c.disk != nil {
+		if data, ok := c.disk.get(key); ok {
+			atomic.AddUint64(&c.diskHits, 1)
+			if c.memory != nil {
+				c.memory.put(key, data)
+			}
+			return data, true
+		}
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return nil, false
+}
+
+func (c *CacheClient) put(key string, data []byte) {
+	if c.memory != nil {
+		c.memory.put(key, data)
+	}
+	if c.disk != nil {
+		c.disk.put(key, data)
+	}
+}
+
+func (c *CacheClient) remove(key string) {
+	if c.memory != nil {
+		c.memory.remove(key)
+	}
+	if c.disk != nil {
+		c.disk.remove(key)
+	}
+}
+
+// maxEntry is the size of the largest entry one of the tiers takes.
+func (c *CacheClient) maxEntry() int64 {
+	var max int64
+	if c.memory != nil {
+		max = c.memory.limit / entryFraction
+	}
+	if c.disk != nil && c.disk.limit/entryFraction > max {
+		max = c.disk.limit / entryFraction
+	}
+	return max
+}
+
+func cacheKey(kind string, addr []byte) string {
+	return kind + "/" + hex.EncodeToString(addr)
+}
+
+// cachingReader collects what is read from a blob stream and caches it once the
+// stream ends, unless it grows over limit.
+type cachingReader struct {
+	io.ReadCloser
+	cache *CacheClient
+	key   string
+	limit int64
+	buf   bytes.Buffer
+	full  bool
+}
+
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if !r.full {
+		if int64(r.buf.Len()+n) > r.limit {
+			r.full = true
+			r.buf = bytes.Buffer{}
+		} else {
+			r.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !r.full {
+		r.cache.put(r.key, r.buf.Bytes())
+		r.full = true
+	}
+	return n, err
+}
@@ -0,0 +1,312 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/cache"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+// countingClient counts the reads that reach the backend
+type countingClient struct {
+	*mock.MockBeeClient
+	reads int
+}
+
+func (c *countingClient) DownloadChunk(ctx context.Context, address []byte) ([]byte, error) {
+	c.reads++
+	return c.MockBeeClient.DownloadChunk(ctx, address)
+}
+
+func (c *countingClient) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
+	c.reads++
+	return c.MockBeeClient.DownloadBlobContext(ctx, address)
+}
+
+// encryptingClient returns references of the length of encrypted ones for blobs
+// uploaded with encrypt, like bee does.
+type encryptingClient struct {
+	*mock.MockBeeClient
+}
+
+func (c *encryptingClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	addr, err := c.MockBeeClient.UploadBlobContext(ctx, data, pin, encrypt)
+	if err != nil || !encrypt {
+		return addr, err
+	}
+	return append(addr, make([]byte, swarm.HashSize)...), nil
+}
+
+func (c *encryptingClient) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
+	return c.MockBeeClient.DownloadBlobContext(ctx, address[:swarm.HashSize])
+}
+
+func TestCacheClient(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	t.Run("memory-hit", func(t *testing.T) {
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 1<<20, "", 0, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := backend.UploadBlob([]byte("hello"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			data, _, err := c.DownloadBlob(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "hello" {
+				t.Fatalf("unexpected data %q", data)
+			}
+		}
+		if backend.reads != 1 {
+			t.Fatalf("expected 1 backend read, got %d", backend.reads)
+		}
+		stats := c.Stats()
+		if stats.MemoryHits != 2 || stats.Misses != 1 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("disk-survives-restart", func(t *testing.T) {
+		dir := t.TempDir()
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 1<<20, dir, 1<<20, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := c.UploadBlob([]byte("persisted"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err = cache.NewCacheClient(backend, 1<<20, dir, 1<<20, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _, err := c.DownloadBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "persisted" || backend.reads != 0 {
+			t.Fatalf("blob not served from disk")
+		}
+		if c.Stats().DiskHits != 1 {
+			t.Fatalf("unexpected stats %+v", c.Stats())
+		}
+		_, _, _ = c.DownloadBlob(addr)
+		if c.Stats().MemoryHits != 1 {
+			t.Fatalf("disk hit not kept in memory %+v", c.Stats())
+		}
+	})
+
+	t.Run("encrypted-not-on-disk", func(t *testing.T) {
+		dir := t.TempDir()
+		backend := &encryptingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 1<<20, dir, 1<<20, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := c.UploadBlob([]byte("secret"), false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addr) != 2*swarm.HashSize {
+			t.Fatalf("unexpected reference length %d", len(addr))
+		}
+		data, _, err := c.DownloadBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "secret" || c.Stats().MemoryHits != 1 {
+			t.Fatalf("encrypted blob not cached in memory %+v", c.Stats())
+		}
+		if c.Stats().DiskEntries != 0 {
+			t.Fatalf("encrypted blob written to disk")
+		}
+	})
+
+	t.Run("memory-limit", func(t *testing.T) {
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 8*100, "", 0, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			_, err := c.UploadBlob(bytes.Repeat([]byte{byte(i)}, 100), false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		stats := c.Stats()
+		if stats.MemoryBytes > 800 || stats.MemoryEntries != 8 || stats.MemoryEvictions != 12 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("disk-limit", func(t *testing.T) {
+		dir := t.TempDir()
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 0, dir, 8*100, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var first []byte
+		for i := 0; i < 10; i++ {
+			addr, err := c.UploadBlob(bytes.Repeat([]byte{byte(i)}, 100), false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				first = addr
+			}
+		}
+		stats := c.Stats()
+		if stats.DiskBytes > 800 || stats.DiskEvictions != 2 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		_, _, err = c.DownloadBlob(first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if backend.reads != 1 {
+			t.Fatalf("evicted blob was not read from the backend")
+		}
+	})
+
+	t.Run("feed-chunks-not-cached", func(t *testing.T) {
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 1<<20, "", 0, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a feed update lives at an address that is not the hash of its data
+		addr := swarm.MustParseHexAddress("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		_, err = c.UploadChunk(swarm.NewChunk(addr, []byte("first update")), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.DownloadChunk(context.Background(), addr.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.UploadChunk(swarm.NewChunk(addr, []byte("second update")), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := c.DownloadChunk(context.Background(), addr.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "second update" || backend.reads != 2 {
+			t.Fatalf("feed chunk served from the cache")
+		}
+
+		// missing chunks are not remembered either
+		missing := make([]byte, 32)
+		for i := 0; i < 2; i++ {
+			_, err = c.DownloadChunk(context.Background(), missing)
+			if err == nil {
+				t.Fatalf("expected missing chunk")
+			}
+		}
+		if backend.reads != 4 {
+			t.Fatalf("missing chunk served from the cache")
+		}
+
+		// content addressed chunks do not change
+		ch, err := content.NewChunk([]byte("immutable"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = backend.UploadChunk(ch, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			_, err = c.DownloadChunk(context.Background(), ch.Address().Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if backend.reads != 5 {
+			t.Fatalf("content addressed chunk not cached")
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 1<<20, "", 0, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := backend.UploadBlob([]byte("streamed"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			rc, _, err := c.DownloadBlobStream(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = rc.Close()
+			if string(data) != "streamed" {
+				t.Fatalf("unexpected data %q", data)
+			}
+		}
+		if c.Stats().MemoryHits != 1 {
+			t.Fatalf("stream not cached %+v", c.Stats())
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		backend := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		c, err := cache.NewCacheClient(backend, 1<<20, t.TempDir(), 1<<20, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := c.UploadBlob([]byte("deleted"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.DeleteBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats := c.Stats()
+		if stats.MemoryEntries != 0 || stats.DiskEntries != 0 {
+			t.Fatalf("deleted blob still cached %+v", stats)
+		}
+	})
+}
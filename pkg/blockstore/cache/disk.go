@@ -0,0 +1,213 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskTier is an LRU cache of files under dir, bounded by their total size. The
+// modification time of a file is its last use, so that the order survives a restart.
+// Blobs uploaded encrypted are never written, their contents would be on disk in
+// plain text.
+type diskTier struct {
+	mu        sync.Mutex
+	dir       string
+	limit     int64
+	size      int64
+	ll        *list.List
+	items     map[string]*list.Element
+	evictions uint64
+}
+
+type diskEntry struct {
+	key  string
+	size int64
+}
+
+func newDiskTier(dir string, limit int64) (*diskTier, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	d := &diskTier{
+		dir:   dir,
+		limit: limit,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+	err = d.load()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load indexes the files left by an earlier run, oldest first, and removes the ones
+// that do not fit in the limit anymore.
+func (d *diskTier) load() error {
+	type found struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []found
+	err := filepath.Walk(d.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(path, tmpSuffix) {
+			return os.Remove(path)
+		}
+		rel, err := filepath.Rel(d.dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) != 3 {
+			return nil
+		}
+		key := parts[0] + "/" + parts[2]
+		if isEncryptedKey(key) {
+			return os.Remove(path)
+		}
+		files = append(files, found{key: key, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, f := range files {
+		d.items[f.key] = d.ll.PushFront(&diskEntry{key: f.key, size: f.size})
+		d.size += f.size
+	}
+	d.evict()
+	return nil
+}
+
+// path spreads the files over directories named after the first byte of the address.
+func (d *diskTier) path(key string) string {
+	i := strings.IndexByte(key, '/')
+	kind, name := key[:i], key[i+1:]
+	return filepath.Join(d.dir, kind, name[:2], name)
+}
+
+func (d *diskTier) get(key string) ([]byte, bool) {
+	d.mu.Lock()
+	el, ok := d.items[key]
+	if ok {
+		d.ll.MoveToFront(el)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := d.path(key)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		d.remove(key)
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (d *diskTier) put(key string, data []byte) {
+	size := int64(len(data))
+	if size > d.limit/entryFraction || isEncryptedKey(key) {
+		return
+	}
+	path := d.path(key)
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return
+	}
+	tmp := path + tmpSuffix
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err = os.Rename(tmp, path)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return
+	}
+	if el, ok := d.items[key]; ok {
+		d.size -= el.Value.(*diskEntry).size
+		d.ll.Remove(el)
+	}
+	d.items[key] = d.ll.PushFront(&diskEntry{key: key, size: size})
+	d.size += size
+	d.evict()
+}
+
+func (d *diskTier) remove(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if el, ok := d.items[key]; ok {
+		d.removeElement(el)
+	}
+}
+
+// evict removes the least recently used files until the tier fits in its limit,
+// d.mu must be held.
+func (d *diskTier) evict() {
+	for d.size > d.limit {
+		d.removeElement(d.ll.Back())
+		d.evictions++
+	}
+}
+
+func (d *diskTier) removeElement(el *list.Element) {
+	e := d.ll.Remove(el).(*diskEntry)
+	delete(d.items, e.key)
+	d.size -= e.size
+	_ = os.Remove(d.path(e.key))
+}
+
+func (d *diskTier) stats() (entries int, size int64, evictions uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.items), d.size, d.evictions
+}
+
+// isEncryptedKey tells if the key is of an encrypted blob, whose reference also holds
+// the key it was encrypted with.
+func isEncryptedKey(key string) bool {
+	return len(key)-strings.IndexByte(key, '/')-1 == 2*encryptedRefLength
+}
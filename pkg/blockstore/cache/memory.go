@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// memoryTier is an LRU cache bounded by the size of the data it holds.
+type memoryTier struct {
+	mu        sync.Mutex
+	limit     int64
+	size      int64
+	ll        *list.List
+	items     map[string]*list.Element
+	evictions uint64
+}
+
+type memoryEntry struct {
+	key  string
+	data []byte
+}
+
+func newMemoryTier(limit int64) *memoryTier {
+	return &memoryTier{
+		limit: limit,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (m *memoryTier) get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.ll.MoveToFront(el)
+	return copyBytes(el.Value.(*memoryEntry).data), true
+}
+
+func (m *memoryTier) put(key string, data []byte) {
+	size := int64(len(data))
+	if size > m.limit/entryFraction {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.removeElement(el)
+	}
+	m.items[key] = m.ll.PushFront(&memoryEntry{key: key, data: copyBytes(data)})
+	m.size += size
+	for m.size > m.limit {
+		m.removeElement(m.ll.Back())
+		m.evictions++
+	}
+}
+
+func (m *memoryTier) remove(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.removeElement(el)
+	}
+}
+
+func (m *memoryTier) removeElement(el *list.Element) {
+	e := m.ll.Remove(el).(*memoryEntry)
+	delete(m.items, e.key)
+	m.size -= int64(len(e.data))
+}
+
+func (m *memoryTier) stats() (entries int, size int64, evictions uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.items), m.size, m.evictions
+}
+
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
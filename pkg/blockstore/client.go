@@ -18,10 +18,20 @@ package blockstore
 
 import (
 	"context"
+	"errors"
+	"io"
 
 	"github.com/ethersphere/bee/pkg/swarm"
 )
 
+var (
+	// ErrDataNotFound is returned by DownloadChunk when the chunk is not stored. It has
+	// the text of the error the bee client always returned, the feed lookup checks for it.
+	ErrDataNotFound  = errors.New("error downloading data")
+	ErrChunkNotFound = errors.New("chunk not found")
+	ErrBlobNotFound  = errors.New("blob not found")
+)
+
 type Client interface {
 	CheckConnection() bool
 	UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err error)
@@ -31,3 +41,16 @@ type Client interface {
 	DeleteChunk(address []byte) error
 	DeleteBlob(address []byte) error
 }
+
+// ClientV2 is a Client whose operations take a context, so that they can be cancelled
+// or given a deadline, and which can stream blobs instead of holding them in memory.
+type ClientV2 interface {
+	Client
+	UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error)
+	UploadBlobContext(ctx context.Context, data []byte, pin bool, encrypt bool) (address []byte, err error)
+	UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) (address []byte, err error)
+	DownloadBlobContext(ctx context.Context, address []byte) (data []byte, respCode int, err error)
+	DownloadBlobStream(ctx context.Context, address []byte) (rc io.ReadCloser, respCode int, err error)
+	DeleteChunkContext(ctx context.Context, address []byte) error
+	DeleteBlobContext(ctx context.Context, address []byte) error
+}
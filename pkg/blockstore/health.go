@@ -0,0 +1,45 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockstore
+
+// Health describes the state of a blockstore client. Decorators report their own
+// state and include the health of the clients they wrap in Backends.
+type Health struct {
+	Name     string                 `json:"name"`
+	Healthy  bool                   `json:"healthy"`
+	Status   string                 `json:"status"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Backends []Health               `json:"backends,omitempty"`
+}
+
+// HealthReporter is implemented by clients which know more about their health than
+// what CheckConnection tells.
+type HealthReporter interface {
+	Health() Health
+}
+
+// GetHealth returns the health reported by the client, or the result of
+// CheckConnection if the client does not report it.
+func GetHealth(c Client) Health {
+	if hr, ok := c.(HealthReporter); ok {
+		return hr.Health()
+	}
+	if c.CheckConnection() {
+		return Health{Name: "blockstore", Healthy: true, Status: "ok"}
+	}
+	return Health{Name: "blockstore", Healthy: false, Status: "unreachable"}
+}
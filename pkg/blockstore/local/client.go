@@ -0,0 +1,460 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/soc"
+	"github.com/ethersphere/bee/pkg/swarm"
+	bmtlegacy "github.com/ethersphere/bmt/legacy"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	chunksDir = "chunks"
+	pinsDir   = "pins"
+	tmpDir    = "tmp"
+)
+
+var (
+	ErrInvalidChunk = errors.New("invalid chunk")
+)
+
+// LocalClient is a blockstore.Client which keeps all the chunks in the local filesystem.
+// Blobs are split in to a swarm chunk tree, so the references returned are the same
+// BMT addresses that bee would return for unencrypted data. Encryption is not done
+// locally, the data never leaves the machine.
+type LocalClient struct {
+	root      string
+	hashPool  *bmtlegacy.TreePool
+	validator swarm.Validator
+	pinMu     sync.Mutex
+	logger    logging.Logger
+}
+
+func hashFunc() hash.Hash {
+	return sha3.NewLegacyKeccak256()
+}
+
+func NewLocalClient(root string, logger logging.Logger) (*LocalClient, error) {
+	for _, dir := range []string{chunksDir, pinsDir, tmpDir} {
+		err := os.MkdirAll(filepath.Join(root, dir), 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &LocalClient{
+		root:      root,
+		hashPool:  bmtlegacy.NewTreePool(hashFunc, swarm.Branches, bmtlegacy.PoolSize),
+		validator: swarm.NewChunkValidator(content.NewValidator(), soc.NewValidator()),
+		logger:    logger,
+	}, nil
+}
+
+func (s *LocalClient) CheckConnection() bool {
+	fi, err := os.Stat(filepath.Join(s.root, chunksDir))
+	if err != nil {
+		return false
+	}
+	return fi.IsDir()
+}
+
+// upload a chunk in to the local store
+func (s *LocalClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err error) {
+	return s.UploadChunkContext(context.Background(), ch, pin)
+}
+
+func (s *LocalClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	to := time.Now()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !s.validator.Validate(ch) {
+		return nil, ErrInvalidChunk
+	}
+	err = s.putChunk(ch.Address(), ch.Data(), pin)
+	if err != nil {
+		return nil, err
+	}
+	fields := logrus.Fields{
+		"reference": ch.Address().String(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload chunk: ")
+	return ch.Address().Bytes(), nil
+}
+
+// download a chunk from the local store
+func (s *LocalClient) DownloadChunk(ctx context.Context, address []byte) (data []byte, err error) {
+	to := time.Now()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	addr := swarm.NewAddress(address)
+	data, err = s.getChunk(addr)
+	if err != nil {
+		return nil, err
+	}
+	fields := logrus.Fields{
+		"reference": addr.String(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download chunk: ")
+	return data, nil
+}
+
+// upload a blob in to the local store. The blob is split in to content addressed chunks
+// and the root chunk address is returned as the reference.
+func (s *LocalClient) UploadBlob(data []byte, pin, encrypt bool) (address []byte, err error) {
+	return s.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
+
+func (s *LocalClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) (address []byte, err error) {
+	return s.UploadBlobStream(ctx, bytes.NewReader(data), pin, encrypt)
+}
+
+// UploadBlobStream splits the data in to chunks as it is read, so only one branch of
+// the chunk tree is held in memory at any time.
+func (s *LocalClient) UploadBlobStream(ctx context.Context, r io.Reader, pin, encrypt bool) (address []byte, err error) {
+	to := time.Now()
+	sp := newSplitter(s, pin)
+	var size int64
+	buf := make([]byte, swarm.ChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || size == 0 {
+			size += int64(n)
+			if err := sp.write(buf[:n]); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	root, err := sp.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := logrus.Fields{
+		"reference": root.String(),
+		"size":      size,
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload blob: ")
+	return root.Bytes(), nil
+}
+
+func (s *LocalClient) DownloadBlob(address []byte) ([]byte, int, error) {
+	return s.DownloadBlobContext(context.Background(), address)
+}
+
+func (s *LocalClient) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
+	to := time.Now()
+	addr := swarm.NewAddress(address)
+	var buf bytes.Buffer
+	err := s.join(ctx, addr, &buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, http.StatusRequestTimeout, ctx.Err()
+		}
+		if errors.Is(err, blockstore.ErrDataNotFound) {
+			return nil, http.StatusNotFound, blockstore.ErrBlobNotFound
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	fields := logrus.Fields{
+		"reference": addr.String(),
+		"size":      buf.Len(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download blob: ")
+	return buf.Bytes(), http.StatusOK, nil
+}
+
+// DownloadBlobStream returns a reader which walks the chunk tree as it is read.
+func (s *LocalClient) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	addr := swarm.NewAddress(address)
+	if !s.hasChunk(addr) {
+		return nil, http.StatusNotFound, blockstore.ErrBlobNotFound
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.join(ctx, addr, pw))
+	}()
+	return pr, http.StatusOK, nil
+}
+
+// DeleteChunk unpins the chunk and removes it if nobody else has it pinned.
+func (s *LocalClient) DeleteChunk(address []byte) error {
+	return s.DeleteChunkContext(context.Background(), address)
+}
+
+func (s *LocalClient) DeleteChunkContext(ctx context.Context, address []byte) error {
+	to := time.Now()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	addr := swarm.NewAddress(address)
+	if !s.hasChunk(addr) {
+		return blockstore.ErrChunkNotFound
+	}
+	err := s.unpinAndRemove(addr)
+	if err != nil {
+		return err
+	}
+	fields := logrus.Fields{
+		"reference": addr.String(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "delete chunk: ")
+	return nil
+}
+
+// DeleteBlob unpins all the chunks of the blob tree and removes the ones which are not
+// pinned by some other blob.
+func (s *LocalClient) DeleteBlob(address []byte) error {
+	return s.DeleteBlobContext(context.Background(), address)
+}
+
+func (s *LocalClient) DeleteBlobContext(ctx context.Context, address []byte) error {
+	to := time.Now()
+	addr := swarm.NewAddress(address)
+	addrs, err := s.treeAddresses(addr)
+	if err != nil {
+		return blockstore.ErrBlobNotFound
+	}
+	for _, a := range addrs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err = s.unpinAndRemove(a)
+		if err != nil {
+			return err
+		}
+	}
+	fields := logrus.Fields{
+		"reference": addr.String(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "delete Blob: ")
+	return nil
+}
+
+// join writes the data of the chunk tree rooted at the given address to w.
+func (s *LocalClient) join(ctx context.Context, addr swarm.Address, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := s.getChunk(addr)
+	if err != nil {
+		return err
+	}
+	if len(data) < swarm.SpanSize {
+		return ErrInvalidChunk
+	}
+	span := binary.LittleEndian.Uint64(data[:swarm.SpanSize])
+	payload := data[swarm.SpanSize:]
+	if span <= swarm.ChunkSize {
+		_, err = w.Write(payload)
+		return err
+	}
+
+	for i := 0; i+swarm.HashSize <= len(payload); i += swarm.HashSize {
+		err = s.join(ctx, swarm.NewAddress(payload[i:i+swarm.HashSize]), w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treeAddresses returns the address of all the chunks in a chunk tree.
+func (s *LocalClient) treeAddresses(addr swarm.Address) ([]swarm.Address, error) {
+	data, err := s.getChunk(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < swarm.SpanSize {
+		return nil, ErrInvalidChunk
+	}
+	addrs := []swarm.Address{addr}
+	span := binary.LittleEndian.Uint64(data[:swarm.SpanSize])
+	if span <= swarm.ChunkSize {
+		return addrs, nil
+	}
+	payload := data[swarm.SpanSize:]
+	for i := 0; i+swarm.HashSize <= len(payload); i += swarm.HashSize {
+		children, err := s.treeAddresses(swarm.NewAddress(payload[i : i+swarm.HashSize]))
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, children...)
+	}
+	return addrs, nil
+}
+
+// newChunk creates a content addressed chunk with address BMT(span, payload).
+func (s *LocalClient) newChunk(payload []byte, span int64) (swarm.Chunk, error) {
+	hasher := bmtlegacy.New(s.hashPool)
+	hasher.Reset()
+	spanBytes := make([]byte, swarm.SpanSize)
+	binary.LittleEndian.PutUint64(spanBytes, uint64(span))
+	err := hasher.SetSpanBytes(spanBytes)
+	if err != nil {
+		return nil, err
+	}
+	_, err = hasher.Write(payload)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, len(spanBytes)+len(payload))
+	data = append(data, spanBytes...)
+	data = append(data, payload...)
+	return swarm.NewChunk(swarm.NewAddress(hasher.Sum(nil)), data), nil
+}
+
+func (s *LocalClient) chunkPath(addr swarm.Address) string {
+	hexAddr := addr.String()
+	return filepath.Join(s.root, chunksDir, hexAddr[:2], hexAddr)
+}
+
+func (s *LocalClient) pinPath(addr swarm.Address) string {
+	return filepath.Join(s.root, pinsDir, addr.String())
+}
+
+func (s *LocalClient) hasChunk(addr swarm.Address) bool {
+	_, err := os.Stat(s.chunkPath(addr))
+	return err == nil
+}
+
+func (s *LocalClient) getChunk(addr swarm.Address) ([]byte, error) {
+	if addr.IsZero() {
+		return nil, blockstore.ErrDataNotFound
+	}
+	data, err := ioutil.ReadFile(s.chunkPath(addr))
+	if err != nil {
+		return nil, blockstore.ErrDataNotFound
+	}
+	return data, nil
+}
+
+func (s *LocalClient) putChunk(addr swarm.Address, data []byte, pin bool) error {
+	if !s.hasChunk(addr) {
+		err := s.writeFile(s.chunkPath(addr), data)
+		if err != nil {
+			return err
+		}
+	}
+	if pin {
+		s.pinMu.Lock()
+		defer s.pinMu.Unlock()
+		count, err := s.pinCount(addr)
+		if err != nil {
+			return err
+		}
+		return s.writeFile(s.pinPath(addr), []byte(strconv.FormatUint(count+1, 10)))
+	}
+	return nil
+}
+
+func (s *LocalClient) unpinAndRemove(addr swarm.Address) error {
+	s.pinMu.Lock()
+	defer s.pinMu.Unlock()
+	count, err := s.pinCount(addr)
+	if err != nil {
+		return err
+	}
+	if count > 1 {
+		return s.writeFile(s.pinPath(addr), []byte(strconv.FormatUint(count-1, 10)))
+	}
+	err = os.Remove(s.pinPath(addr))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.Remove(s.chunkPath(addr))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalClient) pinCount(addr swarm.Address) (uint64, error) {
+	data, err := ioutil.ReadFile(s.pinPath(addr))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	count, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pin counter for %s: %w", addr.String(), err)
+	}
+	return count, nil
+}
+
+// writeFile writes the data to a temporary file first and renames it, so that a
+// crash never leaves a partially written chunk behind.
+func (s *LocalClient) writeFile(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Join(s.root, tmpDir), "write-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,213 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/local"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestLocalClient(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	root, err := ioutil.TempDir("", "blockstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	client, err := local.NewLocalClient(root, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !client.CheckConnection() {
+		t.Fatalf("connection check failed")
+	}
+
+	t.Run("blob-round-trip", func(t *testing.T) {
+		// sizes cover a single chunk, a chunk boundary and a tree deeper than one level
+		for _, size := range []int{1, 4096, 4097, 4096*128 + 10} {
+			data := randomBytes(t, size)
+			addr, err := client.UploadBlob(data, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := client.DownloadBlob(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, got) {
+				t.Fatalf("blob of size %d does not match", size)
+			}
+		}
+	})
+
+	t.Run("persists-across-clients", func(t *testing.T) {
+		data := randomBytes(t, 10000)
+		addr, err := client.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client2, err := local.NewLocalClient(root, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := client2.DownloadBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, got) {
+			t.Fatalf("blob does not match after reopening")
+		}
+	})
+
+	t.Run("delete-pinned-blob", func(t *testing.T) {
+		data := randomBytes(t, 5000)
+		addr, err := client.UploadBlob(data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.UploadBlob(data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// pinned twice, so the first delete only drops a pin
+		err = client.DeleteBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.DownloadBlob(addr)
+		if err != nil {
+			t.Fatalf("blob removed while still pinned: %v", err)
+		}
+
+		err = client.DeleteBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.DownloadBlob(addr)
+		if !errors.Is(err, blockstore.ErrBlobNotFound) {
+			t.Fatalf("unexpected error for deleted blob: %v", err)
+		}
+		_, _, err = client.DownloadBlobStream(context.Background(), addr)
+		if !errors.Is(err, blockstore.ErrBlobNotFound) {
+			t.Fatalf("unexpected error for deleted blob stream: %v", err)
+		}
+		err = client.DeleteBlob(addr)
+		if !errors.Is(err, blockstore.ErrBlobNotFound) {
+			t.Fatalf("unexpected error deleting deleted blob: %v", err)
+		}
+	})
+
+	t.Run("feed-chunks", func(t *testing.T) {
+		acc := account.New(logger)
+		_, _, err := acc.CreateUserAccount("password", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+		user := acc.GetAddress(account.UserAccountIndex)
+		topic := utils.HashString("topic1")
+
+		_, _, err = fd.GetFeedData(topic, user)
+		if err == nil {
+			t.Fatalf("feed found before creation")
+		}
+		_, err = fd.CreateFeed(topic, user, []byte("data1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.UpdateFeed(topic, user, []byte("data2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := fd.GetFeedData(topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, []byte("data2")) {
+			t.Fatalf("feed data mismatch, got %s", string(data))
+		}
+	})
+
+	t.Run("stream-round-trip", func(t *testing.T) {
+		data := randomBytes(t, 4096*130+7)
+		addr, err := client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr2, err := client.UploadBlob(data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, addr2) {
+			t.Fatalf("streamed and buffered uploads gave different references")
+		}
+
+		rc, _, err := client.DownloadBlobStream(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = rc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, got) {
+			t.Fatalf("streamed blob does not match")
+		}
+	})
+
+	t.Run("cancelled-context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.UploadBlobContext(ctx, randomBytes(t, 100), false, false)
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("missing-chunk", func(t *testing.T) {
+		_, err := client.DownloadChunk(context.Background(), randomBytes(t, 32))
+		if !errors.Is(err, blockstore.ErrDataNotFound) {
+			t.Fatalf("unexpected error for missing chunk: %v", err)
+		}
+	})
+}
+
+func randomBytes(t *testing.T, size int) []byte {
+	t.Helper()
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+type treeRef struct {
+	addr swarm.Address
+	span int64
+}
+
+// splitter builds the swarm chunk tree of a blob one data chunk at a time. Every level
+// keeps at most swarm.Branches references, a full level is wrapped in to an intermediate
+// chunk straight away and its reference is pushed to the level above.
+type splitter struct {
+	client *LocalClient
+	pin    bool
+	levels [][]treeRef
+}
+
+func newSplitter(client *LocalClient, pin bool) *splitter {
+	return &splitter{
+		client: client,
+		pin:    pin,
+	}
+}
+
+// write stores one data chunk of at most swarm.ChunkSize bytes.
+func (sp *splitter) write(data []byte) error {
+	ch, err := sp.client.newChunk(data, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	err = sp.client.putChunk(ch.Address(), ch.Data(), sp.pin)
+	if err != nil {
+		return err
+	}
+	return sp.add(0, treeRef{addr: ch.Address(), span: int64(len(data))})
+}
+
+func (sp *splitter) add(level int, ref treeRef) error {
+	for len(sp.levels) <= level {
+		sp.levels = append(sp.levels, nil)
+	}
+	sp.levels[level] = append(sp.levels[level], ref)
+	if len(sp.levels[level]) < swarm.Branches {
+		return nil
+	}
+	parent, err := sp.wrap(sp.levels[level])
+	if err != nil {
+		return err
+	}
+	sp.levels[level] = nil
+	return sp.add(level+1, parent)
+}
+
+func (sp *splitter) wrap(refs []treeRef) (treeRef, error) {
+	payload := make([]byte, 0, len(refs)*swarm.HashSize)
+	var span int64
+	for _, ref := range refs {
+		payload = append(payload, ref.addr.Bytes()...)
+		span += ref.span
+	}
+	ch, err := sp.client.newChunk(payload, span)
+	if err != nil {
+		return treeRef{}, err
+	}
+	err = sp.client.putChunk(ch.Address(), ch.Data(), sp.pin)
+	if err != nil {
+		return treeRef{}, err
+	}
+	return treeRef{addr: ch.Address(), span: span}, nil
+}
+
+// finish wraps the partially filled levels from the bottom up and returns the root.
+// A lone reference left on a level is carried up as it is, wrapping it would create
+// an intermediate chunk which looks like a data chunk.
+func (sp *splitter) finish() (swarm.Address, error) {
+	for level := 0; level < len(sp.levels); level++ {
+		refs := sp.levels[level]
+		sp.levels[level] = nil
+		if level == len(sp.levels)-1 && len(refs) == 1 {
+			return refs[0].addr, nil
+		}
+		switch len(refs) {
+		case 0:
+			continue
+		case 1:
+			err := sp.add(level+1, refs[0])
+			if err != nil {
+				return swarm.ZeroAddress, err
+			}
+		default:
+			parent, err := sp.wrap(refs)
+			if err != nil {
+				return swarm.ZeroAddress, err
+			}
+			err = sp.add(level+1, parent)
+			if err != nil {
+				return swarm.ZeroAddress, err
+			}
+		}
+	}
+	return swarm.ZeroAddress, ErrInvalidChunk
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+)
+
+// Node is one backend of the pool, usually a bee node.
+type Node struct {
+	name   string
+	client blockstore.ClientV2
+
+	mu        sync.RWMutex
+	healthy   bool
+	checking  bool
+	lastCheck time.Time
+	lastError string
+	requests  uint64
+	failures  uint64
+}
+
+func NewNode(name string, client blockstore.Client) *Node {
+	return &Node{
+		name:   name,
+		client: blockstore.ToV2(client),
+	}
+}
+
+func (n *Node) Name() string {
+	return n.name
+}
+
+func (n *Node) IsHealthy() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.healthy
+}
+
+// setHealthy records the result of a health check or of a failed request and
+// returns true if it changed the state of the node.
+func (n *Node) setHealthy(healthy bool, reason string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	changed := n.healthy != healthy
+	n.healthy = healthy
+	n.lastCheck = time.Now()
+	if !healthy {
+		n.lastError = reason
+	}
+	return changed
+}
+
+func (n *Node) countRequest(failed bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.requests++
+	if failed {
+		n.failures++
+	}
+}
+
+// check asks the node if it is up. A node which does not answer within timeout is
+// taken as down, the check still running is waited for by the next one.
+func (n *Node) check(timeout time.Duration) (healthy, changed bool) {
+	n.mu.Lock()
+	if n.checking {
+		healthy = n.healthy
+		n.mu.Unlock()
+		return healthy, false
+	}
+	n.checking = true
+	n.mu.Unlock()
+
+	done := make(chan bool, 1)
+	go func() {
+		ok := n.client.CheckConnection()
+		n.mu.Lock()
+		n.checking = false
+		n.mu.Unlock()
+		done <- ok
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ok := <-done:
+		return ok, n.setHealthy(ok, "health check failed")
+	case <-timer.C:
+		return false, n.setHealthy(false, "health check timed out")
+	}
+}
+
+func (n *Node) health() blockstore.Health {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	status := "ok"
+	if !n.healthy {
+		status = "down"
+	}
+	details := map[string]interface{}{
+		"requests":  n.requests,
+		"failures":  n.failures,
+		"lastCheck": n.lastCheck,
+	}
+	if n.lastError != "" {
+		details["lastError"] = n.lastError
+	}
+	return blockstore.Health{
+		Name:    n.name,
+		Healthy: n.healthy,
+		Status:  status,
+		Details: details,
+	}
+}
+
+// score ranks the node for an address, the node with the highest score is the home
+// of the address. Removing a node only moves the addresses it was the home of.
+func (n *Node) score(address []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(n.name))
+	_, _ = h.Write(address)
+	return h.Sum64()
+}
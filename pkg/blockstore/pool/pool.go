@@ -0,0 +1,428 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// time a node gets to answer a health check
+	checkTimeout = 5 * time.Second
+)
+
+var (
+	ErrNoNodes = errors.New("blockstore pool has no nodes")
+)
+
+type operation string
+
+const (
+	opUploadChunk   operation = "uploadChunk"
+	opDownloadChunk operation = "downloadChunk"
+	opUploadBlob    operation = "uploadBlob"
+	opDownloadBlob  operation = "downloadBlob"
+	opDeleteChunk   operation = "deleteChunk"
+	opDeleteBlob    operation = "deleteBlob"
+)
+
+// PoolClient is a blockstore.Client which spreads the calls over several nodes that
+// share the same network, like bee nodes of one swarm. Blobs go to the nodes in turns.
+// Chunks, which are mostly feed updates, stick to the node chosen by hashing their
+// address, so that an update is looked up on the node it was written to. A call that
+// fails on a node is tried on the next one, and nodes which do not answer are left out
+// until the periodic health check finds them up again.
+type PoolClient struct {
+	nodes         []*Node
+	next          uint64
+	checkInterval time.Duration
+	logger        logging.Logger
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+// NewPoolClient checks the nodes once and, if checkInterval is not zero, keeps
+// checking them in the background until Close is called. Nodes that are down do not
+// stop the pool from being created, it works as long as one node is up.
+func NewPoolClient(nodes []*Node, checkInterval time.Duration, logger logging.Logger) (*PoolClient, error) {
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+	p := &PoolClient{
+		nodes:         nodes,
+		checkInterval: checkInterval,
+		logger:        logger,
+		stop:          make(chan struct{}),
+	}
+	p.checkNodes()
+	if checkInterval > 0 {
+		go p.checkLoop()
+	}
+	return p, nil
+}
+
+// Close stops the health checks.
+func (p *PoolClient) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+func (p *PoolClient) checkLoop() {
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkNodes()
+		}
+	}
+}
+
+func (p *PoolClient) checkNodes() {
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *Node) {
+			defer wg.Done()
+			healthy, changed := n.check(checkTimeout)
+			if changed {
+				p.logNodeState(n, healthy, "health check")
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func (p *PoolClient) logNodeState(n *Node, healthy bool, reason string) {
+	fields := logrus.Fields{
+		"node":   n.name,
+		"reason": reason,
+	}
+	if healthy {
+		p.logger.WithFields(fields).Log(logrus.InfoLevel, "blockstore node is up: ")
+	} else {
+		p.logger.WithFields(fields).Log(logrus.WarnLevel, "blockstore node is down: ")
+	}
+}
+
+// CheckConnection is true while at least one node is up.
+func (p *PoolClient) CheckConnection() bool {
+	for _, n := range p.nodes {
+		if n.IsHealthy() {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthyNodes returns the number of nodes that are up.
+func (p *PoolClient) HealthyNodes() int {
+	count := 0
+	for _, n := range p.nodes {
+		if n.IsHealthy() {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *PoolClient) Health() blockstore.Health {
+	healthy := p.HealthyNodes()
+	status := "ok"
+	switch {
+	case healthy == 0:
+		status = "down"
+	case healthy < len(p.nodes):
+		status = "degraded"
+	}
+	backends := make([]blockstore.Health, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		backends = append(backends, n.health())
+	}
+	return blockstore.Health{
+		Name:    "pool",
+		Healthy: healthy > 0,
+		Status:  status,
+		Details: map[string]interface{}{
+			"nodes":        len(p.nodes),
+			"healthyNodes": healthy,
+		},
+		Backends: backends,
+	}
+}
+
+func (p *PoolClient) UploadChunk(ch swarm.Chunk, pin bool) ([]byte, error) {
+	return p.UploadChunkContext(context.Background(), ch, pin)
+}
+
+func (p *PoolClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	nodes := p.byAddress(ch.Address().Bytes())
+	err = p.try(ctx, opUploadChunk, nodes, nodes[0], func(n *Node) (int, error) {
+		address, err = n.client.UploadChunkContext(ctx, ch, pin)
+		return 0, err
+	})
+	return address, err
+}
+
+func (p *PoolClient) DownloadChunk(ctx context.Context, addr []byte) (data []byte, err error) {
+	nodes := p.byAddress(addr)
+	err = p.try(ctx, opDownloadChunk, nodes, nodes[0], func(n *Node) (int, error) {
+		data, err = n.client.DownloadChunk(ctx, addr)
+		return 0, err
+	})
+	return data, err
+}
+
+func (p *PoolClient) UploadBlob(data []byte, pin, encrypt bool) ([]byte, error) {
+	return p.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
+
+func (p *PoolClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) (address []byte, err error) {
+	err = p.try(ctx, opUploadBlob, p.inTurn(), nil, func(n *Node) (int, error) {
+		address, err = n.client.UploadBlobContext(ctx, data, pin, encrypt)
+		return 0, err
+	})
+	return address, err
+}
+
+// UploadBlobStream moves to the next node only if the reader can be rewound, otherwise
+// the upload is tried on one node.
+func (p *PoolClient) UploadBlobStream(ctx context.Context, rd io.Reader, pin, encrypt bool) (address []byte, err error) {
+	nodes := p.inTurn()
+	seeker, canSeek := rd.(io.Seeker)
+	var start int64
+	if canSeek {
+		start, err = seeker.Seek(0, io.SeekCurrent)
+		canSeek = err == nil
+	}
+	if !canSeek {
+		nodes = nodes[:1]
+	}
+	first := true
+	err = p.try(ctx, opUploadBlob, nodes, nil, func(n *Node) (int, error) {
+		if !first {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return 0, err
+			}
+		}
+		first = false
+		address, err = n.client.UploadBlobStream(ctx, rd, pin, encrypt)
+		return 0, err
+	})
+	return address, err
+}
+
+func (p *PoolClient) DownloadBlob(addr []byte) ([]byte, int, error) {
+	return p.DownloadBlobContext(context.Background(), addr)
+}
+
+func (p *PoolClient) DownloadBlobContext(ctx context.Context, addr []byte) (data []byte, respCode int, err error) {
+	err = p.try(ctx, opDownloadBlob, p.inTurn(), p.byAddress(addr)[0], func(n *Node) (int, error) {
+		data, respCode, err = n.client.DownloadBlobContext(ctx, addr)
+		return respCode, err
+	})
+	return data, respCode, err
+}
+
+// DownloadBlobStream moves to the next node only if the stream can not be opened,
+// errors while reading it are returned to the caller.
+func (p *PoolClient) DownloadBlobStream(ctx context.Context, addr []byte) (rc io.ReadCloser, respCode int, err error) {
+	err = p.try(ctx, opDownloadBlob, p.inTurn(), p.byAddress(addr)[0], func(n *Node) (int, error) {
+		rc, respCode, err = n.client.DownloadBlobStream(ctx, addr)
+		return respCode, err
+	})
+	return rc, respCode, err
+}
+
+func (p *PoolClient) DeleteChunk(addr []byte) error {
+	return p.DeleteChunkContext(context.Background(), addr)
+}
+
+func (p *PoolClient) DeleteChunkContext(ctx context.Context, addr []byte) error {
+	return p.broadcast(ctx, opDeleteChunk, func(n *Node) error {
+		return n.client.DeleteChunkContext(ctx, addr)
+	})
+}
+
+func (p *PoolClient) DeleteBlob(addr []byte) error {
+	return p.DeleteBlobContext(context.Background(), addr)
+}
+
+func (p *PoolClient) DeleteBlobContext(ctx context.Context, addr []byte) error {
+	return p.broadcast(ctx, opDeleteBlob, func(n *Node) error {
+		return n.client.DeleteBlobContext(ctx, addr)
+	})
+}
+
+// inTurn orders the nodes starting from the next one in turn, healthy nodes first.
+func (p *PoolClient) inTurn() []*Node {
+	start := int(atomic.AddUint64(&p.next, 1)-1) % len(p.nodes)
+	nodes := make([]*Node, 0, len(p.nodes))
+	nodes = append(nodes, p.nodes[start:]...)
+	nodes = append(nodes, p.nodes[:start]...)
+	return healthyFirst(nodes)
+}
+
+// byAddress orders the nodes by their score for the address, healthy nodes first.
+func (p *PoolClient) byAddress(address []byte) []*Node {
+	nodes := make([]*Node, len(p.nodes))
+	copy(nodes, p.nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].score(address) > nodes[j].score(address)
+	})
+	return healthyFirst(nodes)
+}
+
+// healthyFirst moves the nodes that are down to the end, they are tried only when all
+// the others failed, since the last health check may be out of date.
+func healthyFirst(nodes []*Node) []*Node {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].IsHealthy() && !nodes[j].IsHealthy()
+	})
+	return nodes
+}
+
+// try calls fn on the nodes in order until one of them succeeds or gives an answer
+// that the other nodes would give too. Data is missing from the network only when
+// routed, the node the address routes to, does not find it.
+func (p *PoolClient) try(ctx context.Context, op operation, nodes []*Node, routed *Node, fn func(n *Node) (int, error)) error {
+	var err error
+	for i, n := range nodes {
+		var respCode int
+		respCode, err = fn(n)
+		if err == nil || isAnswer(op, respCode, err, n == routed) {
+			n.countRequest(false)
+			return err
+		}
+		if isAnswer(op, respCode, err, true) {
+			// the node answered, the data may still be on the others
+			n.countRequest(false)
+			continue
+		}
+		n.countRequest(true)
+		if ctx.Err() != nil {
+			return err
+		}
+		p.markFailed(n, err)
+		if i < len(nodes)-1 {
+			fields := logrus.Fields{
+				"operation": string(op),
+				"node":      n.name,
+				"next":      nodes[i+1].name,
+				"error":     err.Error(),
+			}
+			p.logger.WithFields(fields).Log(logrus.WarnLevel, "blockstore call failed over: ")
+		}
+	}
+	return err
+}
+
+// broadcast calls fn on all the healthy nodes, or on all nodes if none is healthy,
+// since deletes unpin and the data may have been pinned through any of them. It
+// succeeds if one of the nodes did.
+func (p *PoolClient) broadcast(ctx context.Context, op operation, fn func(n *Node) error) error {
+	nodes := make([]*Node, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		if n.IsHealthy() {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		nodes = p.nodes
+	}
+	var firstErr error
+	succeeded := false
+	for _, n := range nodes {
+		err := fn(n)
+		if err == nil {
+			n.countRequest(false)
+			succeeded = true
+			continue
+		}
+		if isAnswer(op, 0, err, true) {
+			n.countRequest(false)
+		} else {
+			n.countRequest(true)
+			if ctx.Err() != nil {
+				return err
+			}
+			p.markFailed(n, err)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if succeeded {
+		return nil
+	}
+	return firstErr
+}
+
+// markFailed takes a node which could not be reached out of the rotation until the
+// next health check.
+func (p *PoolClient) markFailed(n *Node, err error) {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return
+	}
+	if n.setHealthy(false, err.Error()) {
+		p.logNodeState(n, false, err.Error())
+	}
+}
+
+// isAnswer tells if the node answered in a way that does not depend on the node: an
+// upload refused because of its postage batch or, if the node is the one the address
+// routes to, data that is not in the network.
+func isAnswer(op operation, respCode int, err error, routed bool) bool {
+	if errors.Is(err, postage.ErrBatchExhausted) ||
+		errors.Is(err, postage.ErrBatchNotUsable) ||
+		errors.Is(err, postage.ErrInvalidBatchID) {
+		return true
+	}
+	if !routed {
+		return false
+	}
+	if respCode == http.StatusNotFound {
+		return true
+	}
+	switch op {
+	case opDownloadChunk:
+		return errors.Is(err, blockstore.ErrDataNotFound)
+	case opDeleteChunk:
+		return errors.Is(err, blockstore.ErrChunkNotFound)
+	case opDeleteBlob:
+		return errors.Is(err, blockstore.ErrBlobNotFound)
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+// testNode is one node of a network whose data is in a shared mock, like bee nodes of
+// the same swarm
+type testNode struct {
+	*mock.MockBeeClient
+	mu      sync.Mutex
+	down    bool
+	missing bool // does not find the data, like a node the data has not reached yet
+	calls   int
+}
+
+func (t *testNode) setMissing(missing bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.missing = missing
+}
+
+func (t *testNode) isMissing() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.missing
+}
+
+func (t *testNode) setDown(down bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.down = down
+}
+
+func (t *testNode) called() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls
+}
+
+func (t *testNode) call() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.down {
+		return &url.Error{Op: "Post", URL: "http://localhost:1633", Err: errors.New("connection refused")}
+	}
+	t.calls++
+	return nil
+}
+
+func (t *testNode) CheckConnection() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.down
+}
+
+func (t *testNode) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	if err := t.call(); err != nil {
+		return nil, err
+	}
+	return t.MockBeeClient.UploadChunkContext(ctx, ch, pin)
+}
+
+func (t *testNode) DownloadChunk(ctx context.Context, address []byte) ([]byte, error) {
+	if err := t.call(); err != nil {
+		return nil, err
+	}
+	if t.isMissing() {
+		return nil, blockstore.ErrDataNotFound
+	}
+	return t.MockBeeClient.DownloadChunk(ctx, address)
+}
+
+func (t *testNode) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	if err := t.call(); err != nil {
+		return nil, err
+	}
+	return t.MockBeeClient.UploadBlobContext(ctx, data, pin, encrypt)
+}
+
+func (t *testNode) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
+	if err := t.call(); err != nil {
+		return nil, 0, err
+	}
+	if t.isMissing() {
+		return nil, http.StatusNotFound, blockstore.ErrBlobNotFound
+	}
+	return t.MockBeeClient.DownloadBlobContext(ctx, address)
+}
+
+func newTestPool(t *testing.T, count int) (*PoolClient, []*testNode) {
+	t.Helper()
+	network := mock.NewMockBeeClient()
+	var nodes []*Node
+	var testNodes []*testNode
+	for i := 0; i < count; i++ {
+		tn := &testNode{MockBeeClient: network}
+		testNodes = append(testNodes, tn)
+		nodes = append(nodes, NewNode(string(rune('a'+i)), tn))
+	}
+	p, err := NewPoolClient(nodes, 0, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p, testNodes
+}
+
+func TestPool(t *testing.T) {
+	t.Run("no-nodes", func(t *testing.T) {
+		_, err := NewPoolClient(nil, 0, logging.New(ioutil.Discard, 0))
+		if !errors.Is(err, ErrNoNodes) {
+			t.Fatalf("expected ErrNoNodes, got %v", err)
+		}
+	})
+
+	t.Run("spreads-blobs", func(t *testing.T) {
+		p, nodes := newTestPool(t, 3)
+		for i := 0; i < 6; i++ {
+			_, err := p.UploadBlob([]byte{byte(i)}, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i, n := range nodes {
+			if n.called() != 2 {
+				t.Fatalf("node %d got %d uploads, expected 2", i, n.called())
+			}
+		}
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		p, nodes := newTestPool(t, 3)
+		// goes down after the health check, the first upload finds out
+		nodes[0].setDown(true)
+		for i := 0; i < 6; i++ {
+			data := []byte{byte(i)}
+			addr, err := p.UploadBlob(data, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := p.DownloadBlob(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0] != data[0] {
+				t.Fatalf("downloaded wrong data")
+			}
+		}
+		if nodes[0].called() != 0 {
+			t.Fatalf("node that is down was used")
+		}
+		if p.nodes[0].IsHealthy() {
+			t.Fatalf("node that is down is still healthy")
+		}
+		if p.HealthyNodes() != 2 {
+			t.Fatalf("expected 2 healthy nodes, got %d", p.HealthyNodes())
+		}
+	})
+
+	t.Run("sticky-chunks", func(t *testing.T) {
+		p, nodes := newTestPool(t, 3)
+		ch, err := content.NewChunk([]byte("feed update"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = p.UploadChunk(ch, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			_, err = p.DownloadChunk(context.Background(), ch.Address().Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		home := -1
+		for i, n := range nodes {
+			switch n.called() {
+			case 0:
+			case 6:
+				home = i
+			default:
+				t.Fatalf("node %d got %d of the chunk calls", i, n.called())
+			}
+		}
+		if home < 0 {
+			t.Fatalf("chunk calls did not stick to one node")
+		}
+
+		// a missing chunk is not looked up on the other nodes
+		_, err = p.DownloadChunk(context.Background(), make([]byte, 32))
+		if err == nil {
+			t.Fatalf("expected missing chunk")
+		}
+		total := 0
+		for _, n := range nodes {
+			total += n.called()
+		}
+		if total != 7 {
+			t.Fatalf("missing chunk was asked %d times", total-6)
+		}
+
+		// when the home node is down the chunk is read from another one
+		nodes[home].setDown(true)
+		_, err = p.DownloadChunk(context.Background(), ch.Address().Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("missing-on-other-nodes", func(t *testing.T) {
+		p, nodes := newTestPool(t, 3)
+		ch, err := content.NewChunk([]byte("feed update"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = p.UploadChunk(ch, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := p.UploadBlob([]byte("blob"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a node the chunk does not route to is asked only when the home node is down,
+		// its not found is not the answer of the network
+		order := p.byAddress(ch.Address().Bytes())
+		for i, n := range p.nodes {
+			switch n {
+			case order[0]:
+				nodes[i].setDown(true)
+			case order[1]:
+				nodes[i].setMissing(true)
+			}
+		}
+		_, err = p.DownloadChunk(context.Background(), ch.Address().Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// blobs are read in turn, only the node the blob routes to has it
+		for i := range nodes {
+			nodes[i].setDown(false)
+			nodes[i].setMissing(p.nodes[i] != p.byAddress(addr)[0])
+		}
+		for i := 0; i < 3; i++ {
+			_, _, err = p.DownloadBlob(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		network := mock.NewMockBeeClient()
+		up := &testNode{MockBeeClient: network}
+		down := &testNode{MockBeeClient: network, down: true}
+		p, err := NewPoolClient([]*Node{NewNode("up", up), NewNode("down", down)}, 0, logging.New(ioutil.Discard, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if !p.CheckConnection() {
+			t.Fatalf("pool with a healthy node is not connected")
+		}
+		h := p.Health()
+		if !h.Healthy || h.Status != "degraded" || len(h.Backends) != 2 {
+			t.Fatalf("unexpected health %+v", h)
+		}
+
+		down.setDown(false)
+		p.checkNodes()
+		if p.Health().Status != "ok" {
+			t.Fatalf("node not back after health check")
+		}
+
+		up.setDown(true)
+		down.setDown(true)
+		p.checkNodes()
+		if p.CheckConnection() || p.Health().Status != "down" {
+			t.Fatalf("pool without healthy nodes is connected")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postage
+
+import (
+	"context"
+	"io"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+)
+
+// BatchClient is a blockstore.Client decorator which sends every upload with the
+// batch returned by selector, unless the context of the upload already names a batch.
+type BatchClient struct {
+	blockstore.ClientV2
+	selector func() string
+}
+
+func NewBatchClient(client blockstore.Client, selector func() string) *BatchClient {
+	return &BatchClient{
+		ClientV2: blockstore.ToV2(client),
+		selector: selector,
+	}
+}
+
+func (b *BatchClient) withBatch(ctx context.Context) context.Context {
+	if _, ok := FromContext(ctx); ok {
+		return ctx
+	}
+	if batchID := b.selector(); batchID != "" {
+		return WithBatch(ctx, batchID)
+	}
+	return ctx
+}
+
+func (b *BatchClient) UploadChunk(ch swarm.Chunk, pin bool) ([]byte, error) {
+	return b.UploadChunkContext(context.Background(), ch, pin)
+}
+
+func (b *BatchClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	return b.ClientV2.UploadChunkContext(b.withBatch(ctx), ch, pin)
+}
+
+func (b *BatchClient) UploadBlob(data []byte, pin, encrypt bool) ([]byte, error) {
+	return b.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
+
+func (b *BatchClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	return b.ClientV2.UploadBlobContext(b.withBatch(ctx), data, pin, encrypt)
+}
+
+func (b *BatchClient) UploadBlobStream(ctx context.Context, r io.Reader, pin, encrypt bool) ([]byte, error) {
+	return b.ClientV2.UploadBlobStream(b.withBatch(ctx), r, pin, encrypt)
+}
+
+func (b *BatchClient) Health() blockstore.Health {
+	return blockstore.GetHealth(b.ClientV2)
+}
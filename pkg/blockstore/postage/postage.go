@@ -0,0 +1,281 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postage
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+var (
+	ErrInvalidBatchID = errors.New("invalid postage batch id")
+	ErrBatchExhausted = errors.New("postage batch exhausted")
+	ErrBatchNotUsable = errors.New("postage batch not usable")
+)
+
+type batchKey struct{}
+
+// WithBatch returns a context which makes uploads done with it use the given batch.
+func WithBatch(ctx context.Context, batchID string) context.Context {
+	return context.WithValue(ctx, batchKey{}, batchID)
+}
+
+// FromContext returns the batch set with WithBatch.
+func FromContext(ctx context.Context) (string, bool) {
+	batchID, ok := ctx.Value(batchKey{}).(string)
+	return batchID, ok && batchID != ""
+}
+
+// ValidateBatchID checks that the id is a hex encoded 32 byte batch id.
+func ValidateBatchID(batchID string) error {
+	b, err := hex.DecodeString(batchID)
+	if err != nil || len(b) != swarm.HashSize {
+		return fmt.Errorf("%w: %q", ErrInvalidBatchID, batchID)
+	}
+	return nil
+}
+
+type BatchUsage struct {
+	BatchID   string `json:"batchID"`
+	Chunks    uint64 `json:"chunks"`
+	Bytes     uint64 `json:"bytes"`
+	Capacity  uint64 `json:"capacity,omitempty"` // in chunks, 0 when the depth is not known
+	Exhausted bool   `json:"exhausted"`
+}
+
+// Manager keeps the default postage batch, the batches chosen for users and pods and
+// how much of every batch was used by this server. The batch assignments are saved in
+// stateFile, the usage counters live only in memory.
+type Manager struct {
+	mu           sync.RWMutex
+	stateFile    string
+	defaultBatch string
+	userBatches  map[string]string
+	podBatches   map[string]string
+	usage        map[string]*BatchUsage
+}
+
+type managerState struct {
+	UserBatches map[string]string `json:"userBatches"`
+	PodBatches  map[string]string `json:"podBatches"`
+}
+
+// NewManager creates a Manager with the given default batch, which may be empty. If
+// stateFile is not empty the batch assignments are loaded from and saved to it.
+func NewManager(defaultBatch, stateFile string) (*Manager, error) {
+	if defaultBatch != "" {
+		if err := ValidateBatchID(defaultBatch); err != nil {
+			return nil, err
+		}
+	}
+	m := &Manager{
+		stateFile:    stateFile,
+		defaultBatch: defaultBatch,
+		userBatches:  make(map[string]string),
+		podBatches:   make(map[string]string),
+		usage:        make(map[string]*BatchUsage),
+	}
+	if stateFile == "" {
+		return m, nil
+	}
+	data, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	var state managerState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserBatches != nil {
+		m.userBatches = state.UserBatches
+	}
+	if state.PodBatches != nil {
+		m.podBatches = state.PodBatches
+	}
+	return m, nil
+}
+
+// DefaultBatch returns the batch used when no other batch was chosen.
+func (m *Manager) DefaultBatch() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.defaultBatch
+}
+
+// SetUserBatch makes the uploads of the user go to the batch, an empty batchID
+// removes the assignment.
+func (m *Manager) SetUserBatch(userName, batchID string) error {
+	return m.assign(m.userBatches, userName, batchID)
+}
+
+// SetPodBatch makes the uploads done in a pod of the user go to the batch, an empty
+// batchID removes the assignment.
+func (m *Manager) SetPodBatch(userName, podName, batchID string) error {
+	return m.assign(m.podBatches, podKey(userName, podName), batchID)
+}
+
+// Resolve returns the batch to use for an upload, the pod batch wins over the user
+// batch which wins over the default batch.
+func (m *Manager) Resolve(userName, podName string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if podName != "" {
+		if batchID, ok := m.podBatches[podKey(userName, podName)]; ok {
+			return batchID
+		}
+	}
+	if batchID, ok := m.userBatches[userName]; ok {
+		return batchID
+	}
+	return m.defaultBatch
+}
+
+// SetDepth records the depth of a batch, so that it is known to be exhausted before
+// the node rejects an upload.
+func (m *Manager) SetDepth(batchID string, depth uint8) error {
+	if err := ValidateBatchID(batchID); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usageOf(batchID).Capacity = uint64(1) << depth
+	return nil
+}
+
+// CheckUsable returns an error if the batch is known to be exhausted.
+func (m *Manager) CheckUsable(batchID string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.usage[batchID]
+	if !ok {
+		return nil
+	}
+	if u.Exhausted || (u.Capacity > 0 && u.Chunks >= u.Capacity) {
+		return fmt.Errorf("%w: %s", ErrBatchExhausted, batchID)
+	}
+	return nil
+}
+
+// RecordUpload adds an upload of the given number of chunks and bytes to the batch.
+func (m *Manager) RecordUpload(batchID string, chunks, bytes uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.usageOf(batchID)
+	u.Chunks += chunks
+	u.Bytes += bytes
+}
+
+// MarkExhausted records that the node refused the batch.
+func (m *Manager) MarkExhausted(batchID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usageOf(batchID).Exhausted = true
+}
+
+// Usage returns how much of the batch was used by this server.
+func (m *Manager) Usage(batchID string) BatchUsage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if u, ok := m.usage[batchID]; ok {
+		return *u
+	}
+	return BatchUsage{BatchID: batchID}
+}
+
+func (m *Manager) usageOf(batchID string) *BatchUsage {
+	u, ok := m.usage[batchID]
+	if !ok {
+		u = &BatchUsage{BatchID: batchID}
+		m.usage[batchID] = u
+	}
+	return u
+}
+
+func (m *Manager) assign(batches map[string]string, key, batchID string) error {
+	if batchID != "" {
+		if err := ValidateBatchID(batchID); err != nil {
+			return err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if batchID == "" {
+		delete(batches, key)
+	} else {
+		batches[key] = batchID
+	}
+	return m.save()
+}
+
+func (m *Manager) save() error {
+	if m.stateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(&managerState{
+		UserBatches: m.userBatches,
+		PodBatches:  m.podBatches,
+	})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(m.stateFile), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := m.stateFile + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.stateFile)
+}
+
+func podKey(userName, podName string) string {
+	return userName + "/" + podName
+}
+
+// ChunkCount estimates the number of chunks a blob of the given size is stored in,
+// including the intermediate chunks of the chunk tree.
+func ChunkCount(size int64, encrypt bool) uint64 {
+	branches := int64(swarm.Branches)
+	if encrypt {
+		branches /= 2
+	}
+	level := (size + swarm.ChunkSize - 1) / swarm.ChunkSize
+	if level == 0 {
+		level = 1
+	}
+	total := level
+	for level > 1 {
+		level = (level + branches - 1) / branches
+		total += level
+	}
+	return uint64(total)
+}
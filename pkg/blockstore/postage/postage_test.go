@@ -0,0 +1,116 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postage_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+)
+
+func TestManager(t *testing.T) {
+	defaultBatch := strings.Repeat("a", 64)
+	userBatch := strings.Repeat("b", 64)
+	podBatch := strings.Repeat("c", 64)
+
+	t.Run("invalid-batch", func(t *testing.T) {
+		_, err := postage.NewManager("abcd", "")
+		if !errors.Is(err, postage.ErrInvalidBatchID) {
+			t.Fatalf("expected invalid batch, got %v", err)
+		}
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		m, err := postage.NewManager(defaultBatch, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Resolve("user1", "pod1") != defaultBatch {
+			t.Fatalf("expected default batch")
+		}
+		err = m.SetUserBatch("user1", userBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.SetPodBatch("user1", "pod1", podBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Resolve("user1", "pod1") != podBatch {
+			t.Fatalf("expected pod batch")
+		}
+		if m.Resolve("user1", "pod2") != userBatch {
+			t.Fatalf("expected user batch")
+		}
+		if m.Resolve("user2", "pod1") != defaultBatch {
+			t.Fatalf("expected default batch")
+		}
+		err = m.SetPodBatch("user1", "pod1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Resolve("user1", "pod1") != userBatch {
+			t.Fatalf("expected user batch after removing the pod batch")
+		}
+	})
+
+	t.Run("persist", func(t *testing.T) {
+		stateFile := filepath.Join(t.TempDir(), "postage.json")
+		m, err := postage.NewManager(defaultBatch, stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.SetUserBatch("user1", userBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.SetPodBatch("user1", "pod1", podBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err = postage.NewManager(defaultBatch, stateFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Resolve("user1", "pod1") != podBatch || m.Resolve("user1", "pod2") != userBatch {
+			t.Fatalf("batch assignments not loaded")
+		}
+	})
+
+	t.Run("chunk-count", func(t *testing.T) {
+		for _, tc := range []struct {
+			size    int64
+			encrypt bool
+			chunks  uint64
+		}{
+			{0, false, 1},
+			{4096, false, 1},
+			{4097, false, 3},
+			{128 * 4096, false, 129},
+			{128*4096 + 1, false, 132},
+			{64*4096 + 1, true, 68},
+		} {
+			if got := postage.ChunkCount(tc.size, tc.encrypt); got != tc.chunks {
+				t.Fatalf("size %d encrypt %v: expected %d chunks, got %d", tc.size, tc.encrypt, tc.chunks, got)
+			}
+		}
+	})
+}
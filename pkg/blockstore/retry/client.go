@@ -0,0 +1,276 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCircuitOpen = errors.New("blockstore unavailable: circuit breaker is open")
+)
+
+// RetryClient is a blockstore.Client decorator which retries failed calls according to
+// a per operation Policy and stops calling the backend through a circuit breaker once
+// it keeps failing.
+type RetryClient struct {
+	client   blockstore.ClientV2
+	policies map[Operation]Policy
+	breaker  *Breaker
+	logger   logging.Logger
+}
+
+func NewRetryClient(client blockstore.Client, policies map[Operation]Policy, breaker *Breaker, logger logging.Logger) *RetryClient {
+	return &RetryClient{
+		client:   blockstore.ToV2(client),
+		policies: policies,
+		breaker:  breaker,
+		logger:   logger,
+	}
+}
+
+func (r *RetryClient) CheckConnection() bool {
+	return r.client.CheckConnection()
+}
+
+func (r *RetryClient) Health() blockstore.Health {
+	stats := r.breaker.Stats()
+	health := blockstore.Health{
+		Name:    "retry",
+		Healthy: r.breaker.State() != BreakerOpen,
+		Status:  "circuit " + stats.State,
+		Details: map[string]interface{}{
+			"breaker": stats,
+		},
+		Backends: []blockstore.Health{blockstore.GetHealth(r.client)},
+	}
+	return health
+}
+
+func (r *RetryClient) UploadChunk(ch swarm.Chunk, pin bool) ([]byte, error) {
+	return r.UploadChunkContext(context.Background(), ch, pin)
+}
+
+func (r *RetryClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	err = r.do(ctx, OpUploadChunk, r.policy(OpUploadChunk).Idempotent, func(ctx context.Context) (int, error) {
+		address, err = r.client.UploadChunkContext(ctx, ch, pin)
+		return 0, err
+	})
+	return address, err
+}
+
+func (r *RetryClient) DownloadChunk(ctx context.Context, addr []byte) (data []byte, err error) {
+	err = r.do(ctx, OpDownloadChunk, r.policy(OpDownloadChunk).Idempotent, func(ctx context.Context) (int, error) {
+		data, err = r.client.DownloadChunk(ctx, addr)
+		return 0, err
+	})
+	return data, err
+}
+
+func (r *RetryClient) UploadBlob(data []byte, pin, encrypt bool) ([]byte, error) {
+	return r.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
+
+// UploadBlobContext treats an encrypted upload as not idempotent, every attempt
+// would store the data under a new random key.
+func (r *RetryClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) (address []byte, err error) {
+	idempotent := r.policy(OpUploadBlob).Idempotent && !encrypt
+	err = r.do(ctx, OpUploadBlob, idempotent, func(ctx context.Context) (int, error) {
+		address, err = r.client.UploadBlobContext(ctx, data, pin, encrypt)
+		return 0, err
+	})
+	return address, err
+}
+
+// UploadBlobStream can retry only if the reader can be rewound, otherwise the data of
+// the failed attempt is gone and the call is made once.
+func (r *RetryClient) UploadBlobStream(ctx context.Context, rd io.Reader, pin, encrypt bool) (address []byte, err error) {
+	seeker, canSeek := rd.(io.Seeker)
+	var start int64
+	if canSeek {
+		start, err = seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			canSeek = false
+		}
+	}
+	idempotent := r.policy(OpUploadBlob).Idempotent && !encrypt
+	first := true
+	err = r.do(ctx, OpUploadBlob, idempotent, func(ctx context.Context) (int, error) {
+		if !first {
+			if !canSeek {
+				return 0, errNoRewind
+			}
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return 0, errNoRewind
+			}
+		}
+		first = false
+		address, err = r.client.UploadBlobStream(ctx, rd, pin, encrypt)
+		return 0, err
+	})
+	return address, err
+}
+
+func (r *RetryClient) DownloadBlob(addr []byte) ([]byte, int, error) {
+	return r.DownloadBlobContext(context.Background(), addr)
+}
+
+func (r *RetryClient) DownloadBlobContext(ctx context.Context, addr []byte) (data []byte, respCode int, err error) {
+	err = r.do(ctx, OpDownloadBlob, r.policy(OpDownloadBlob).Idempotent, func(ctx context.Context) (int, error) {
+		data, respCode, err = r.client.DownloadBlobContext(ctx, addr)
+		return respCode, err
+	})
+	return data, respCode, err
+}
+
+// DownloadBlobStream retries opening the stream, errors while reading it are returned
+// to the caller as they are.
+func (r *RetryClient) DownloadBlobStream(ctx context.Context, addr []byte) (rc io.ReadCloser, respCode int, err error) {
+	err = r.do(ctx, OpDownloadBlob, r.policy(OpDownloadBlob).Idempotent, func(ctx context.Context) (int, error) {
+		rc, respCode, err = r.client.DownloadBlobStream(ctx, addr)
+		return respCode, err
+	})
+	return rc, respCode, err
+}
+
+func (r *RetryClient) DeleteChunk(addr []byte) error {
+	return r.DeleteChunkContext(context.Background(), addr)
+}
+
+func (r *RetryClient) DeleteChunkContext(ctx context.Context, addr []byte) error {
+	return r.do(ctx, OpDeleteChunk, r.policy(OpDeleteChunk).Idempotent, func(ctx context.Context) (int, error) {
+		return 0, r.client.DeleteChunkContext(ctx, addr)
+	})
+}
+
+func (r *RetryClient) DeleteBlob(addr []byte) error {
+	return r.DeleteBlobContext(context.Background(), addr)
+}
+
+func (r *RetryClient) DeleteBlobContext(ctx context.Context, addr []byte) error {
+	return r.do(ctx, OpDeleteBlob, r.policy(OpDeleteBlob).Idempotent, func(ctx context.Context) (int, error) {
+		return 0, r.client.DeleteBlobContext(ctx, addr)
+	})
+}
+
+var errNoRewind = errors.New("upload stream can not be rewound for a retry")
+
+func (r *RetryClient) policy(op Operation) Policy {
+	if p, ok := r.policies[op]; ok {
+		return p
+	}
+	return Policy{MaxAttempts: 1}
+}
+
+// do runs fn until it succeeds, fails permanently or the attempts of the policy run out.
+func (r *RetryClient) do(ctx context.Context, op Operation, idempotent bool, fn func(ctx context.Context) (int, error)) error {
+	p := r.policy(op)
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if !r.breaker.Allow() {
+			if lastErr != nil {
+				return lastErr
+			}
+			return ErrCircuitOpen
+		}
+		respCode, err := fn(ctx)
+		if err == nil || isNotFound(op, respCode, err) || isPostageError(err) {
+			// the backend answered, even if the data was not there or the upload
+			// was refused
+			r.breaker.Success()
+			return err
+		}
+		if ctx.Err() != nil || errors.Is(err, errNoRewind) {
+			// says nothing about the backend
+			r.breaker.Abort()
+			return err
+		}
+		r.breaker.Failure(err)
+		lastErr = err
+
+		if attempt >= p.MaxAttempts || !canRetry(err, idempotent) {
+			return err
+		}
+		wait := p.backoff(attempt)
+		fields := logrus.Fields{
+			"operation": string(op),
+			"attempt":   attempt,
+			"backoff":   wait.String(),
+			"error":     err.Error(),
+		}
+		r.logger.WithFields(fields).Log(logrus.WarnLevel, "retrying blockstore call: ")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// isNotFound tells if the backend answered that the data does not exist. Such answers
+// are not retried and do not count against the backend, feed lookups probe for
+// missing chunks all the time.
+func isNotFound(op Operation, respCode int, err error) bool {
+	if respCode == http.StatusNotFound {
+		return true
+	}
+	switch op {
+	case OpDownloadChunk:
+		return errors.Is(err, blockstore.ErrDataNotFound)
+	case OpDeleteChunk:
+		return errors.Is(err, blockstore.ErrChunkNotFound)
+	case OpDeleteBlob:
+		return errors.Is(err, blockstore.ErrBlobNotFound)
+	}
+	return false
+}
+
+// isPostageError tells if an upload was refused because of its postage batch, which
+// does not get better by trying again.
+func isPostageError(err error) bool {
+	return errors.Is(err, postage.ErrBatchExhausted) ||
+		errors.Is(err, postage.ErrBatchNotUsable) ||
+		errors.Is(err, postage.ErrInvalidBatchID)
+}
+
+// canRetry tells if a failed call can be repeated. A call which is not idempotent may
+// have been applied even if it failed, whether the transport or the backend reported
+// the error, so it is retried only when the connection was never made.
+func canRetry(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if idempotent {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
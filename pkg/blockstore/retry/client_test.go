@@ -0,0 +1,257 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+// flakyClient fails the first failures calls of every operation with err
+type flakyClient struct {
+	*mock.MockBeeClient
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyClient) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return f.MockBeeClient.UploadBlobContext(ctx, data, pin, encrypt)
+}
+
+func (f *flakyClient) DeleteBlobContext(ctx context.Context, address []byte) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	return f.MockBeeClient.DeleteBlobContext(ctx, address)
+}
+
+func (f *flakyClient) DownloadChunk(ctx context.Context, address []byte) ([]byte, error) {
+	f.calls++
+	return f.MockBeeClient.DownloadChunk(ctx, address)
+}
+
+func fastPolicies(attempts int) map[Operation]Policy {
+	policies := DefaultPolicies(attempts)
+	for op, p := range policies {
+		p.InitialBackoff = time.Millisecond
+		p.MaxBackoff = time.Millisecond
+		policies[op] = p
+	}
+	return policies
+}
+
+func transportError() error {
+	return &url.Error{Op: "Post", URL: "http://localhost:1633/bytes", Err: errors.New("connection reset by peer")}
+}
+
+func TestRetryClient(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	t.Run("retries-until-success", func(t *testing.T) {
+		flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 2, err: errors.New("error uploading blob")}
+		client := NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+		addr, err := client.UploadBlob([]byte("data"), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flaky.calls != 3 {
+			t.Fatalf("expected 3 calls, got %d", flaky.calls)
+		}
+		data, _, err := client.DownloadBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "data" {
+			t.Fatalf("invalid data %s", string(data))
+		}
+	})
+
+	t.Run("gives-up-after-max-attempts", func(t *testing.T) {
+		flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 5, err: errors.New("error uploading blob")}
+		client := NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+		_, err := client.UploadBlob([]byte("data"), true, false)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if flaky.calls != 3 {
+			t.Fatalf("expected 3 calls, got %d", flaky.calls)
+		}
+	})
+
+	t.Run("encrypted-upload-not-retried", func(t *testing.T) {
+		for _, failure := range []error{transportError(), errors.New("error uploading blob")} {
+			flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 1, err: failure}
+			client := NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+			_, err := client.UploadBlob([]byte("data"), true, true)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if flaky.calls != 1 {
+				t.Fatalf("expected 1 call for %v, got %d", failure, flaky.calls)
+			}
+		}
+
+		flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 1, err: transportError()}
+		client := NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+
+		// an unencrypted upload is content addressed, so it is safe to repeat
+		flaky.calls = 0
+		_, err := client.UploadBlob([]byte("data"), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flaky.calls != 2 {
+			t.Fatalf("expected 2 calls, got %d", flaky.calls)
+		}
+	})
+
+	t.Run("delete-retried-only-if-not-sent", func(t *testing.T) {
+		flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 1, err: transportError()}
+		client := NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+		err := client.DeleteBlob(make([]byte, 32))
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if flaky.calls != 1 {
+			t.Fatalf("expected 1 call, got %d", flaky.calls)
+		}
+
+		// the node could have unpinned the blob before failing
+		flaky = &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 1, err: errors.New("error deleting blob")}
+		client = NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+		err = client.DeleteBlob(make([]byte, 32))
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if flaky.calls != 1 {
+			t.Fatalf("expected 1 call, got %d", flaky.calls)
+		}
+
+		dialErr := &url.Error{Op: "Delete", URL: "http://localhost:1633", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+		flaky = &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 1, err: dialErr}
+		client = NewRetryClient(flaky, fastPolicies(3), NewBreaker(10, time.Minute), logger)
+		addr, err := flaky.MockBeeClient.UploadBlob([]byte("data"), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.DeleteBlob(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flaky.calls != 2 {
+			t.Fatalf("expected 2 calls, got %d", flaky.calls)
+		}
+	})
+
+	t.Run("chunk-not-found-is-not-retried", func(t *testing.T) {
+		flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient()}
+		breaker := NewBreaker(1, time.Minute)
+		client := NewRetryClient(flaky, fastPolicies(3), breaker, logger)
+		_, err := client.DownloadChunk(context.Background(), swarm.ZeroAddress.Bytes())
+		if !errors.Is(err, blockstore.ErrDataNotFound) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+		if flaky.calls != 1 {
+			t.Fatalf("expected 1 call, got %d", flaky.calls)
+		}
+		if breaker.State() != BreakerClosed {
+			t.Fatalf("not found opened the breaker")
+		}
+	})
+
+	t.Run("cancelled-context-stops-retries", func(t *testing.T) {
+		flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 5, err: errors.New("error uploading blob")}
+		policies := DefaultPolicies(5)
+		client := NewRetryClient(flaky, policies, NewBreaker(10, time.Minute), logger)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := client.UploadBlobContext(ctx, []byte("data"), true, false)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if flaky.calls >= 5 {
+			t.Fatalf("retries continued after the context was done")
+		}
+	})
+}
+
+func TestBreaker(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	now := time.Now()
+	breaker := NewBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	flaky := &flakyClient{MockBeeClient: mock.NewMockBeeClient(), failures: 2, err: errors.New("error uploading blob")}
+	client := NewRetryClient(flaky, fastPolicies(1), breaker, logger)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.UploadBlob([]byte("data"), true, false)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+	}
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("breaker should be open, it is %s", breaker.State())
+	}
+	if client.Health().Healthy {
+		t.Fatalf("health should report the open breaker")
+	}
+
+	// calls fail fast while open
+	_, err := client.UploadBlob([]byte("data"), true, false)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if flaky.calls != 2 {
+		t.Fatalf("open breaker let a call through")
+	}
+
+	// after the timeout a probe goes through and closes the breaker
+	now = now.Add(2 * time.Minute)
+	_, err = client.UploadBlob([]byte("data"), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("breaker should be closed, it is %s", breaker.State())
+	}
+	if !client.Health().Healthy {
+		t.Fatalf("health should be ok")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+type Operation string
+
+const (
+	OpUploadChunk   Operation = "uploadChunk"
+	OpDownloadChunk Operation = "downloadChunk"
+	OpUploadBlob    Operation = "uploadBlob"
+	OpDownloadBlob  Operation = "downloadBlob"
+	OpDeleteChunk   Operation = "deleteChunk"
+	OpDeleteBlob    Operation = "deleteBlob"
+)
+
+// Policy says how often and how fast an operation is retried. Operations which are not
+// Idempotent are retried only when the request surely did not reach the node, since
+// repeating them could for example unpin a chunk twice.
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64 // fraction of the backoff which is randomised, 0 to 1
+	Idempotent     bool
+}
+
+// DefaultPolicies returns the policies used when none are given for an operation.
+// Chunk uploads are idempotent since chunks are content addressed, unencrypted blob
+// uploads are handled as idempotent by the client itself. Reads are retried more
+// eagerly than writes, deletes change pin counters and are not idempotent.
+func DefaultPolicies(maxAttempts int) map[Operation]Policy {
+	read := Policy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		Idempotent:     true,
+	}
+	write := read
+	write.InitialBackoff = 250 * time.Millisecond
+	write.MaxBackoff = 5 * time.Second
+	del := write
+	del.Idempotent = false
+
+	return map[Operation]Policy{
+		OpUploadChunk:   write,
+		OpDownloadChunk: read,
+		OpUploadBlob:    write,
+		OpDownloadBlob:  read,
+		OpDeleteChunk:   del,
+		OpDeleteBlob:    del,
+	}
+}
+
+// backoff returns the time to wait before the given retry, starting at 1.
+func (p Policy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * p.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// ToV2 returns the ClientV2 behind the given client. Clients which do not implement
+// ClientV2 are wrapped so that the context is checked before and after every call.
+// The wrapped calls themselves can not be interrupted and streams are buffered.
+func ToV2(c Client) ClientV2 {
+	if c == nil {
+		return nil
+	}
+	if v2, ok := c.(ClientV2); ok {
+		return v2
+	}
+	return &legacyClient{Client: c}
+}
+
+type legacyClient struct {
+	Client
+}
+
+func (l *legacyClient) UploadChunkContext(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return l.UploadChunk(ch, pin)
+}
+
+func (l *legacyClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return l.UploadBlob(data, pin, encrypt)
+}
+
+func (l *legacyClient) UploadBlobStream(ctx context.Context, r io.Reader, pin, encrypt bool) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return l.UploadBlobContext(ctx, data, pin, encrypt)
+}
+
+func (l *legacyClient) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, http.StatusRequestTimeout, err
+	}
+	data, respCode, err := l.DownloadBlob(address)
+	if err != nil {
+		return nil, respCode, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, http.StatusRequestTimeout, err
+	}
+	return data, respCode, nil
+}
+
+func (l *legacyClient) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	data, respCode, err := l.DownloadBlobContext(ctx, address)
+	if err != nil {
+		return nil, respCode, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), respCode, nil
+}
+
+func (l *legacyClient) DeleteChunkContext(ctx context.Context, address []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.DeleteChunk(address)
+}
+
+func (l *legacyClient) DeleteBlobContext(ctx context.Context, address []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.DeleteBlob(address)
+}
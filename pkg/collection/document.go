@@ -19,6 +19,7 @@ package collection
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -1274,9 +1275,11 @@ func (d *Document) DocBatchWrite(docBatch *DocBatch, podFile string) error {
 	return nil
 }
 
-func (d *Document) DocFileIndex(dbName, podFile string) error {
+// DocFileIndex loads every json line of the pod file in to the document db. Indexing
+// stops with an error when ctx is cancelled.
+func (d *Document) DocFileIndex(ctx context.Context, dbName, podFile string) error {
 	d.logger.Info("Indexing file to db: ", podFile, dbName)
-	reader, _, _, err := d.file.OpenFileForIndex(podFile)
+	reader, _, _, err := d.file.OpenFileForIndex(ctx, podFile)
 	if err != nil {
 		d.logger.Errorf("Indexing file: ", err.Error())
 		return err
@@ -1328,7 +1331,7 @@ func (d *Document) DocFileIndex(dbName, podFile string) error {
 }
 
 func (d *Document) getLineFromFile(podFile string, seekOffset uint64) ([]byte, error) {
-	reader, _, _, err := d.file.OpenFileForIndex(podFile)
+	reader, _, _, err := d.file.OpenFileForIndex(context.Background(), podFile)
 	if err != nil {
 		d.logger.Errorf("getting  line: ", err.Error())
 		return nil, err
@@ -129,7 +129,7 @@ func CreateIndex(collectionName, indexName string, indexType IndexType, fd *feed
 
 func OpenIndex(collectionName, indexName string, fd *feed.API, ai *account.Info, user utils.Address, client blockstore.Client, logger logging.Logger) (*Index, error) {
 	actualIndexName := collectionName + indexName
-	manifest := getRootManifestOfIndex(actualIndexName, fd, user, client) // this will load the entire Manifest for immutable indexes
+	manifest := getRootManifestOfIndex(context.Background(), actualIndexName, fd, user, client) // this will load the entire Manifest for immutable indexes
 	if manifest == nil {
 		return nil, ErrIndexNotPresent
 	}
@@ -154,7 +154,7 @@ func (idx *Index) DeleteIndex() error {
 	if idx.isReadOnlyFeed() {
 		return ErrReadOnlyIndex
 	}
-	manifest := getRootManifestOfIndex(idx.name, idx.feed, idx.user, idx.client)
+	manifest := getRootManifestOfIndex(context.Background(), idx.name, idx.feed, idx.user, idx.client)
 	if manifest == nil {
 		return ErrIndexNotPresent
 	}
@@ -218,7 +218,7 @@ func (idx *Index) loadIndexAndCount(ctx context.Context, cancel context.CancelFu
 
 			var newManifest *Manifest
 			if idx.mutable {
-				man, err := idx.loadManifest(manifest.Name + entry.Name)
+				man, err := idx.loadManifestContext(ctx, manifest.Name+entry.Name)
 				if err != nil {
 					fmt.Println("Manifest load error: ", manifest.Name+entry.Name)
 					//select {
@@ -256,6 +256,11 @@ func (idx *Index) loadIndexAndCount(ctx context.Context, cancel context.CancelFu
 
 // Manifest related functions
 func (idx *Index) loadManifest(manifestPath string) (*Manifest, error) {
+	return idx.loadManifestContext(context.Background(), manifestPath)
+}
+
+// loadManifestContext loads the Manifest, the download is abandoned when ctx is done.
+func (idx *Index) loadManifestContext(ctx context.Context, manifestPath string) (*Manifest, error) {
 	// get feed data and unmarshall the Manifest
 	idx.logger.Info("loading Manifest: ", manifestPath)
 	topic := utils.HashString(manifestPath)
@@ -264,7 +269,7 @@ func (idx *Index) loadManifest(manifestPath string) (*Manifest, error) {
 		return nil, ErrNoManifestFound
 	}
 
-	data, respCode, err := idx.client.DownloadBlob(refData)
+	data, respCode, err := blockstore.ToV2(idx.client).DownloadBlobContext(ctx, refData)
 	if err != nil {
 		return nil, ErrNoManifestFound
 	}
@@ -350,14 +355,14 @@ func longestCommonPrefix(str1, str2 string) (string, string, string) {
 	return str1[:matchLen], str1[matchLen:], str2[matchLen:]
 }
 
-func getRootManifestOfIndex(actualIndexName string, fd *feed.API, user utils.Address, client blockstore.Client) *Manifest {
+func getRootManifestOfIndex(ctx context.Context, actualIndexName string, fd *feed.API, user utils.Address, client blockstore.Client) *Manifest {
 	var manifest Manifest
 	topic := utils.HashString(actualIndexName)
 	_, addr, err := fd.GetFeedData(topic, user)
 	if err != nil {
 		return nil
 	}
-	data, _, err := client.DownloadBlob(addr)
+	data, _, err := blockstore.ToV2(client).DownloadBlobContext(ctx, addr)
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// MarkLiveReferences marks the manifests and the values of all the key value tables
+// as live.
+func (kv *KeyValue) MarkLiveReferences(mark func(reference []byte)) error {
+	kvTables, err := kv.LoadKVTables()
+	if err != nil {
+		return err
+	}
+	for name := range kvTables {
+		err = markIndex(defaultCollectionName+name, kv.fd, kv.user, kv.client, mark)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkLiveReferences marks the manifests of all the indexes of the document dbs and
+// the documents they point to as live.
+func (d *Document) MarkLiveReferences(mark func(reference []byte)) error {
+	schemas, err := d.LoadDocumentDBSchemas()
+	if err != nil {
+		return err
+	}
+	for dbName, schema := range schemas {
+		var indexes []SIndex
+		indexes = append(indexes, schema.SimpleIndexes...)
+		indexes = append(indexes, schema.MapIndexes...)
+		indexes = append(indexes, schema.ListIndexes...)
+		for _, index := range indexes {
+			err = markIndex(dbName+index.FieldName, d.fd, d.user, d.client, mark)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func markIndex(actualIndexName string, fd *feed.API, user utils.Address, client blockstore.Client, mark func(reference []byte)) error {
+	topic := utils.HashString(actualIndexName)
+	_, ref, err := fd.GetFeedData(topic, user)
+	if err != nil {
+		return err
+	}
+	if len(ref) == 0 {
+		return nil // deleted index
+	}
+	manifest, err := downloadManifest(ref, client)
+	if err != nil {
+		return err
+	}
+	mark(ref)
+	return markManifest(manifest, manifest.Mutable, fd, user, client, mark)
+}
+
+func markManifest(manifest *Manifest, mutable bool, fd *feed.API, user utils.Address, client blockstore.Client, mark func(reference []byte)) error {
+	for _, entry := range manifest.Entries {
+		if entry.EType != IntermediateEntry {
+			// documents are referenced by their blob, key value pairs hold the value
+			// itself which is marked harmlessly
+			for _, ref := range entry.Ref {
+				mark(ref)
+			}
+			continue
+		}
+
+		if !mutable {
+			if entry.Manifest != nil {
+				err := markManifest(entry.Manifest, mutable, fd, user, client, mark)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		topic := utils.HashString(manifest.Name + entry.Name)
+		_, ref, err := fd.GetFeedData(topic, user)
+		if err != nil {
+			return err
+		}
+		child, err := downloadManifest(ref, client)
+		if err != nil {
+			return err
+		}
+		mark(ref)
+		err = markManifest(child, mutable, fd, user, client, mark)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadManifest(ref []byte, client blockstore.Client) (*Manifest, error) {
+	data, respCode, err := client.DownloadBlob(ref)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, ErrNoManifestFound
+	}
+	var manifest Manifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, ErrManifestUnmarshall
+	}
+	return &manifest, nil
+}
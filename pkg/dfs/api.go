@@ -18,7 +18,7 @@ package dfs
 
 import (
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
-	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
 )
@@ -26,19 +26,22 @@ import (
 type DfsAPI struct {
 	dataDir string
 	client  blockstore.Client
+	batches *postage.Manager
 	users   *user.Users
 	logger  logging.Logger
 }
 
-func NewDfsAPI(dataDir, host, port, cookieDomain string, logger logging.Logger) (*DfsAPI, error) {
-	c := bee.NewBeeClient(host, port, logger)
+// NewDfsAPI creates the dfs API on top of the blockstore client. batches picks the
+// postage batches of the uploads and can be nil when the store does not need them.
+func NewDfsAPI(dataDir string, c blockstore.Client, batches *postage.Manager, cookieDomain string, logger logging.Logger) (*DfsAPI, error) {
 	if !c.CheckConnection() {
 		return nil, ErrBeeClient
 	}
-	users := user.NewUsers(dataDir, c, cookieDomain, logger)
+	users := user.NewUsers(dataDir, c, batches, cookieDomain, logger)
 	return &DfsAPI{
 		dataDir: dataDir,
 		client:  c,
+		batches: batches,
 		users:   users,
 		logger:  logger,
 	}, nil
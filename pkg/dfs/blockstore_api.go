@@ -0,0 +1,97 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dfs
+
+import (
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+// BlockStoreHealth reports the health of the blockstore client and of every client it
+// wraps.
+func (d *DfsAPI) BlockStoreHealth() blockstore.Health {
+	return blockstore.GetHealth(d.client)
+}
+
+// GetPostageBatch returns the postage batch used for the uploads of the user, which
+// depends on the pod that is open.
+func (d *DfsAPI) GetPostageBatch(sessionId string) (string, error) {
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+	if d.batches == nil {
+		return "", ErrNoPostage
+	}
+	return d.batches.Resolve(ui.GetUserName(), ui.GetPodName()), nil
+}
+
+// SetUserPostageBatch makes all the uploads of the user use the batch, unless the pod
+// has a batch of its own. An empty batchID goes back to the default batch.
+func (d *DfsAPI) SetUserPostageBatch(sessionId, batchID string) error {
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+	if d.batches == nil {
+		return ErrNoPostage
+	}
+	return d.batches.SetUserBatch(ui.GetUserName(), batchID)
+}
+
+// SetPodPostageBatch makes the uploads done in the pod use the batch. An empty
+// batchID goes back to the batch of the user. The pod has to be one of the pods of
+// the user.
+func (d *DfsAPI) SetPodPostageBatch(sessionId, podName, batchID string) error {
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+	if d.batches == nil {
+		return ErrNoPostage
+	}
+	pods, sharedPods, err := ui.GetPod().ListPods()
+	if err != nil {
+		return err
+	}
+	if !containsPod(pods, podName) && !containsPod(sharedPods, podName) {
+		return pod.ErrInvalidPodName
+	}
+	return d.batches.SetPodBatch(ui.GetUserName(), podName, batchID)
+}
+
+// PostageBatchUsage reports how much of the batch this server used.
+func (d *DfsAPI) PostageBatchUsage(batchID string) (*postage.BatchUsage, error) {
+	if d.batches == nil {
+		return nil, ErrNoPostage
+	}
+	if err := postage.ValidateBatchID(batchID); err != nil {
+		return nil, err
+	}
+	usage := d.batches.Usage(batchID)
+	return &usage, nil
+}
+
+func containsPod(pods []string, podName string) bool {
+	for _, name := range pods {
+		if name == podName {
+			return true
+		}
+	}
+	return false
+}
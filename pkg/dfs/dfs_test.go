@@ -0,0 +1,215 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dfs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/beetest"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+)
+
+// TestEndToEnd runs the dfs against a fake bee node over HTTP
+func TestEndToEnd(t *testing.T) {
+	srv, err := beetest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	logger := logging.New(ioutil.Discard, 0)
+	host, port := srv.HostPort()
+	api, err := dfs.NewDfsAPI(t.TempDir(), bee.NewBeeClient(host, port, nil, logger), nil, "localhost", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionId := "session1"
+	_, _, err = api.CreateUser("user1", "password", "", httptest.NewRecorder(), sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.CreatePod("pod1", "password", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.Mkdir("/dir1", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := make([]byte, 3000)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.UploadFile(context.Background(), "file1", sessionId, int64(len(content)), bytes.NewReader(content), "/dir1", "1000", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// log in again, so that everything is read back from the node
+	err = api.LogoutUser(sessionId, httptest.NewRecorder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.LoginUser("user1", "password", httptest.NewRecorder(), sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.OpenPod("pod1", "password", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := api.ListDir("/dir1", &dir.ListOptions{}, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Name != "file1" {
+		t.Fatalf("unexpected entries %v", page.Entries)
+	}
+	reader, _, _, err := api.DownloadFile(context.Background(), "/dir1/file1", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	got, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("downloaded file differs")
+	}
+}
+
+func TestPostageBatch(t *testing.T) {
+	srv, err := beetest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	logger := logging.New(ioutil.Discard, 0)
+	host, port := srv.HostPort()
+	defaultBatch := strings.Repeat("aa", 32)
+	batches, err := postage.NewManager(defaultBatch, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, err := dfs.NewDfsAPI(t.TempDir(), bee.NewBeeClient(host, port, batches, logger), batches, "localhost", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionId := "session1"
+	_, _, err = api.CreateUser("user1", "password", "", httptest.NewRecorder(), sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.CreatePod("pod1", "password", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("pod-batch", func(t *testing.T) {
+		podBatch := strings.Repeat("bb", 32)
+		err := api.SetPodPostageBatch(sessionId, "pod1", podBatch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := api.GetPostageBatch(sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != podBatch {
+			t.Fatalf("expected batch %s, got %s", podBatch, got)
+		}
+	})
+
+	t.Run("unknown-pod", func(t *testing.T) {
+		err := api.SetPodPostageBatch(sessionId, "pod2", strings.Repeat("cc", 32))
+		if err != pod.ErrInvalidPodName {
+			t.Fatalf("expected %v, got %v", pod.ErrInvalidPodName, err)
+		}
+	})
+}
+
+func TestCopy(t *testing.T) {
+	srv, err := beetest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	logger := logging.New(ioutil.Discard, 0)
+	host, port := srv.HostPort()
+	api, err := dfs.NewDfsAPI(t.TempDir(), bee.NewBeeClient(host, port, nil, logger), nil, "localhost", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionId := "session1"
+	_, _, err = api.CreateUser("user1", "password", "", httptest.NewRecorder(), sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pod3 is closed, pod2 stays open when pod1 is created
+	for _, podName := range []string{"pod3", "pod2", "pod1"} {
+		_, err = api.CreatePod(podName, "password", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if podName == "pod3" {
+			err = api.ClosePod(sessionId)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	content := make([]byte, 3000)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.UploadFile(context.Background(), "file1", sessionId, int64(len(content)), bytes.NewReader(content), "/", "1000", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("open-pod", func(t *testing.T) {
+		err := api.Copy("/file1", "pod2", "/", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("closed-pod", func(t *testing.T) {
+		err := api.Copy("/file1", "pod3", "/", sessionId)
+		if err != dfs.ErrPodNotOpen {
+			t.Fatalf("expected %v, got %v", dfs.ErrPodNotOpen, err)
+		}
+	})
+}
@@ -16,7 +16,11 @@ limitations under the License.
 
 package dfs
 
-import "github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+import (
+	"context"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+)
 
 func (d *DfsAPI) DocCreate(sessionId, name string, indexes map[string]collection.IndexType, mutable bool) error {
 	// get the logged in user information
@@ -258,7 +262,7 @@ func (d *DfsAPI) DocBatchWrite(sessionId string, docBatch *collection.DocBatch)
 	return podInfo.GetDocStore().DocBatchWrite(docBatch, "")
 }
 
-func (d *DfsAPI) DocIndexJson(sessionId, name, podFile string) error {
+func (d *DfsAPI) DocIndexJson(ctx context.Context, sessionId, name, podFile string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -279,5 +283,5 @@ func (d *DfsAPI) DocIndexJson(sessionId, name, podFile string) error {
 		return err
 	}
 
-	return podInfo.GetDocStore().DocFileIndex(name, fileWithPodName)
+	return podInfo.GetDocStore().DocFileIndex(ctx, name, fileWithPodName)
 }
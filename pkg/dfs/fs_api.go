@@ -17,6 +17,7 @@ limitations under the License.
 package dfs
 
 import (
+	"context"
 	"io"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
@@ -73,7 +74,7 @@ func (d *DfsAPI) IsDirPresent(directoryName, sessionId string) (bool, error) {
 	return true, nil
 }
 
-func (d *DfsAPI) RmDir(directoryName, sessionId string) error {
+func (d *DfsAPI) RmDir(directoryName, sessionId string, recursive bool, progress func(*dir.RemoveProgress)) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -85,14 +86,16 @@ func (d *DfsAPI) RmDir(directoryName, sessionId string) error {
 		return ErrPodNotOpen
 	}
 
-	err := ui.GetPod().RemoveDir(ui.GetPodName(), directoryName)
+	err := ui.GetPod().RemoveDir(ui.GetPodName(), directoryName, recursive, progress)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) ListDir(currentDir, sessionId string) ([]dir.DirOrFileEntry, error) {
+// ListDir lists a page of the entries of a directory of the open pod, selected and
+// ordered by opts.
+func (d *DfsAPI) ListDir(currentDir string, opts *dir.ListOptions, sessionId string) (*dir.ListPage, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -104,11 +107,43 @@ func (d *DfsAPI) ListDir(currentDir, sessionId string) ([]dir.DirOrFileEntry, er
 		return nil, ErrPodNotOpen
 	}
 
-	entries, err := ui.GetPod().ListEntiesInDir(ui.GetPodName(), currentDir)
+	page, err := ui.GetPod().ListDirPage(ui.GetPodName(), currentDir, opts)
 	if err != nil {
 		return nil, err
 	}
-	return entries, nil
+	return page, nil
+}
+
+// Find searches the files and directories of the open pod which meet all the
+// conditions of opts.
+func (d *DfsAPI) Find(opts *pod.FindOptions, sessionId string) ([]*pod.FindResult, bool, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, false, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, false, ErrPodNotOpen
+	}
+	return ui.GetPod().Find(ui.GetPodName(), opts)
+}
+
+// DirectoryDiskUsage adds up the storage taken by the files below a directory of the
+// open pod.
+func (d *DfsAPI) DirectoryDiskUsage(directoryName, sessionId string) (*pod.DiskUsage, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+	return ui.GetPod().DiskUsage(ui.GetPodName(), directoryName)
 }
 
 func (d *DfsAPI) DirectoryStat(directoryName, sessionId string, printNames bool) (*dir.DirStats, error) {
@@ -209,6 +244,44 @@ func (d *DfsAPI) DeleteFile(podFile, sessionId string) error {
 	return nil
 }
 
+func (d *DfsAPI) Move(source, destination, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return ErrPodNotOpen
+	}
+
+	return ui.GetPod().Move(ui.GetPodName(), source, destination)
+}
+
+// Copy copies source of the open pod to destination in the pod dstPodName, or in the
+// open pod itself if dstPodName is empty. The pod dstPodName has to be open too.
+func (d *DfsAPI) Copy(source, dstPodName, destination, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return ErrPodNotOpen
+	}
+
+	if dstPodName != "" && dstPodName != ui.GetPodName() {
+		if _, err := ui.GetPod().GetPodInfoFromPodMap(dstPodName); err != nil {
+			return ErrPodNotOpen
+		}
+	}
+
+	return ui.GetPod().Copy(ui.GetPodName(), source, dstPodName, destination)
+}
+
 func (d *DfsAPI) FileStat(fileName, sessionId string) (*file.FileStats, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
@@ -228,7 +301,31 @@ func (d *DfsAPI) FileStat(fileName, sessionId string) (*file.FileStats, error) {
 	return ds, nil
 }
 
-func (d *DfsAPI) UploadFile(fileName, sessionId string, fileSize int64, fd io.Reader, podDir, blockSize, compression string) (string, error) {
+// VerifyFile checks the blocks of a file of the open pod against their checksums, or
+// of all its files when podFile is empty.
+func (d *DfsAPI) VerifyFile(ctx context.Context, podFile, sessionId string) ([]*file.VerifyResult, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+
+	if podFile == "" {
+		return ui.GetPod().VerifyPod(ctx, ui.GetPodName())
+	}
+	result, err := ui.GetPod().VerifyFile(ctx, ui.GetPodName(), podFile)
+	if err != nil {
+		return nil, err
+	}
+	return []*file.VerifyResult{result}, nil
+}
+
+func (d *DfsAPI) UploadFile(ctx context.Context, fileName, sessionId string, fileSize int64, fd io.Reader, podDir, blockSize, compression, chunking string) (string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -240,14 +337,14 @@ func (d *DfsAPI) UploadFile(fileName, sessionId string, fileSize int64, fd io.Re
 		return "", ErrPodNotOpen
 	}
 
-	ref, err := ui.GetPod().UploadFile(ui.GetPodName(), fileName, fileSize, fd, podDir, blockSize, compression)
+	ref, err := ui.GetPod().UploadFile(ctx, ui.GetPodName(), fileName, fileSize, fd, podDir, blockSize, compression, chunking)
 	if err != nil {
 		return "", err
 	}
 	return ref, nil
 }
 
-func (d *DfsAPI) DownloadFile(podFile, sessionId string) (io.ReadCloser, string, string, error) {
+func (d *DfsAPI) DownloadFile(ctx context.Context, podFile, sessionId string) (io.ReadCloser, string, string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -259,13 +356,103 @@ func (d *DfsAPI) DownloadFile(podFile, sessionId string) (io.ReadCloser, string,
 		return nil, "", "", ErrPodNotOpen
 	}
 
-	reader, ref, size, err := ui.GetPod().DownloadFile(ui.GetPodName(), podFile)
+	reader, ref, size, err := ui.GetPod().DownloadFile(ctx, ui.GetPodName(), podFile)
 	if err != nil {
 		return nil, "", "", err
 	}
 	return reader, ref, size, nil
 }
 
+// DownloadFileVersion returns a reader for a version of a file of the open pod.
+func (d *DfsAPI) DownloadFileVersion(ctx context.Context, podFile string, version uint32, sessionId string) (io.ReadCloser, string, string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, "", "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, "", "", ErrPodNotOpen
+	}
+	return ui.GetPod().DownloadFileVersion(ctx, ui.GetPodName(), podFile, version)
+}
+
+// ListFileVersions lists the versions of a file of the open pod, oldest first.
+func (d *DfsAPI) ListFileVersions(podFile, sessionId string) ([]*file.VersionInfo, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+	return ui.GetPod().ListFileVersions(ui.GetPodName(), podFile)
+}
+
+// RestoreFileVersion makes a version of a file of the open pod its current one.
+func (d *DfsAPI) RestoreFileVersion(podFile string, version uint32, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return ErrPodNotOpen
+	}
+	return ui.GetPod().RestoreFileVersion(ui.GetPodName(), podFile, version)
+}
+
+// PruneFileVersions drops all but the newest keep versions of a file of the open pod.
+func (d *DfsAPI) PruneFileVersions(podFile string, keep int, sessionId string) (int, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return 0, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return 0, ErrPodNotOpen
+	}
+	return ui.GetPod().PruneFileVersions(ui.GetPodName(), podFile, keep)
+}
+
+// AppendFile writes the data from fd at the end of a file of the open pod.
+func (d *DfsAPI) AppendFile(ctx context.Context, podFile string, fd io.Reader, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return ErrPodNotOpen
+	}
+	return ui.GetPod().AppendFile(ctx, ui.GetPodName(), podFile, fd)
+}
+
+// WriteFileAt writes the data from fd over a file of the open pod starting at offset.
+func (d *DfsAPI) WriteFileAt(ctx context.Context, podFile string, offset int64, fd io.Reader, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return ErrPodNotOpen
+	}
+	return ui.GetPod().WriteFileAt(ctx, ui.GetPodName(), podFile, offset, fd)
+}
+
 func (d *DfsAPI) ShareFile(podFile, destinationUser, sessionId string) (string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
@@ -314,3 +501,108 @@ func (d *DfsAPI) ReceiveInfo(sessionId string, sharingRef utils.SharingReference
 
 	return d.users.ReceiveFileInfo(ui.GetPodName(), sharingRef, ui, ui.GetPod())
 }
+
+// NewUploadSession starts the upload of a file in blocks to podDir of the open pod.
+// The session is kept till the upload is finished, also across restarts.
+func (d *DfsAPI) NewUploadSession(fileName, sessionId string, fileSize int64, podDir, blockSize, compression string) (*file.UploadSession, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+
+	session, err := ui.GetPod().NewUploadSession(ui.GetPodName(), fileName, fileSize, podDir, blockSize, compression)
+	if err != nil {
+		return nil, err
+	}
+	err = d.users.GetUploadSessions().Save(ui.GetUserName(), session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetUploadSession returns the upload session with the given id, with the blocks
+// which arrived so far.
+func (d *DfsAPI) GetUploadSession(uploadId, sessionId string) (*file.UploadSession, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+	return d.users.GetUploadSessions().Load(ui.GetUserName(), uploadId)
+}
+
+// UploadSessionBlock uploads the block with the given index of an upload session. The
+// pod of the session has to be open.
+func (d *DfsAPI) UploadSessionBlock(ctx context.Context, uploadId, sessionId string, index int, data []byte, checksum string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	uploads := d.users.GetUploadSessions()
+	session, err := uploads.Load(ui.GetUserName(), uploadId)
+	if err != nil {
+		return err
+	}
+	if session.PodName != ui.GetPodName() {
+		return ErrUploadPodNotOpen
+	}
+
+	block, err := ui.GetPod().UploadSessionBlock(ctx, ui.GetPodName(), session, index, data, checksum)
+	if err != nil {
+		return err
+	}
+	_, err = uploads.Update(ui.GetUserName(), uploadId, func(session *file.UploadSession) error {
+		session.Blocks[index] = block
+		return nil
+	})
+	return err
+}
+
+// FinishUploadSession creates the file of an upload session once all its blocks
+// arrived and removes the session.
+func (d *DfsAPI) FinishUploadSession(ctx context.Context, uploadId, sessionId string) (string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	uploads := d.users.GetUploadSessions()
+	session, err := uploads.Load(ui.GetUserName(), uploadId)
+	if err != nil {
+		return "", err
+	}
+	if session.PodName != ui.GetPodName() {
+		return "", ErrUploadPodNotOpen
+	}
+
+	ref, err := ui.GetPod().FinishUploadSession(ctx, ui.GetPodName(), session)
+	if err != nil {
+		return "", err
+	}
+	err = uploads.Delete(ui.GetUserName(), uploadId)
+	if err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// DeleteUploadSession gives up an upload session, the blocks it uploaded are left to
+// the garbage collection.
+func (d *DfsAPI) DeleteUploadSession(uploadId, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+	return d.users.GetUploadSessions().Delete(ui.GetUserName(), uploadId)
+}
@@ -151,6 +151,21 @@ func (d *DfsAPI) SyncPod(sessionId string) error {
 	return nil
 }
 
+// SetPodVersioning turns the versioning of the files of the open pod on or off.
+func (d *DfsAPI) SetPodVersioning(enable bool, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return ErrPodNotOpen
+	}
+	return ui.GetPod().SetVersioning(ui.GetPodName(), enable)
+}
+
 func (d *DfsAPI) ListPods(sessionId string) ([]string, []string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
@@ -200,3 +215,107 @@ func (d *DfsAPI) PodReceive(sessionId string, ref utils.Reference) (*pod.Info, e
 
 	return ui.GetPod().ReceivePod(ref)
 }
+
+// CreateSnapshot freezes the open pod under the snapshot name.
+func (d *DfsAPI) CreateSnapshot(name, sessionId string) (*pod.Snapshot, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+	return ui.GetPod().CreateSnapshot(ui.GetPodName(), name)
+}
+
+func (d *DfsAPI) ListSnapshots(sessionId string) ([]*pod.Snapshot, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	return ui.GetPod().ListSnapshots()
+}
+
+func (d *DfsAPI) DeleteSnapshot(name, sessionId string) error {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	return ui.GetPod().DeleteSnapshot(name)
+}
+
+// OpenSnapshot opens the pod of a snapshot read only, in place of the open pod.
+func (d *DfsAPI) OpenSnapshot(name, sessionId string) (*pod.Info, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// close the already open pod
+	if ui.GetPodName() != "" {
+		err := ui.GetPod().ClosePod(ui.GetPodName())
+		if err != nil {
+			return nil, err
+		}
+		ui.RemovePodName()
+	}
+
+	// open the snapshot
+	po, err := ui.GetPod().OpenSnapshot(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add podName in the login user session
+	ui.SetPodName(po.GetCurrentPodNameOnly())
+	return po, nil
+}
+
+// RestoreSnapshot creates the pod podName from a snapshot and opens it.
+func (d *DfsAPI) RestoreSnapshot(name, podName, passPhrase, sessionId string) (*pod.Info, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// create the pod from the snapshot
+	po, err := ui.GetPod().RestoreSnapshot(name, podName, passPhrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add podName in the login user session
+	ui.SetPodName(po.GetCurrentPodNameOnly())
+	return po, nil
+}
+
+// PodTree lists the files and directories below a directory of a pod or a snapshot.
+func (d *DfsAPI) PodTree(source, dirPath, sessionId string) ([]*pod.TreeEntry, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	return ui.GetPod().Tree(source, dirPath)
+}
+
+// PodDiff compares the trees below the same directory of two pods or snapshots.
+func (d *DfsAPI) PodDiff(from, to, dirPath, sessionId string) ([]*pod.DiffEntry, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	return ui.GetPod().Diff(from, to, dirPath)
+}
@@ -19,6 +19,8 @@ package dfs
 import (
 	"net/http"
 
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
 )
 
@@ -72,6 +74,31 @@ func (d *DfsAPI) DeleteUser(passPhrase, sessionId string, response http.Response
 	return d.users.DeleteUser(ui.GetUserName(), d.dataDir, passPhrase, sessionId, response, ui)
 }
 
+func (d *DfsAPI) CollectGarbage(passPhrase, sessionId string, dryRun bool) (*gc.Report, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	return d.users.CollectGarbage(ui, passPhrase, dryRun)
+}
+
+// DiskUsage adds up the storage taken by all the pods of the logged in user. The
+// password is needed to open the pods which are not open.
+func (d *DfsAPI) DiskUsage(passPhrase, sessionId string) (*pod.UserDiskUsage, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	if passPhrase == "" || !ui.GetAccount().Authorise(passPhrase) {
+		return nil, user.ErrInvalidPassword
+	}
+	return ui.GetPod().UserDiskUsage(passPhrase)
+}
+
 func (d *DfsAPI) IsUserNameAvailable(userName string) bool {
 	return d.users.IsUsernameAvailable(userName, d.dataDir)
 }
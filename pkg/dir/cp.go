@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	"bytes"
+	gopath "path"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// CopyTree copies the directory at srcPath, whose inode is dirInode, and everything
+// below it to dstPath in dst, the directories of this or of another pod. The files are
+// copied with File.Copy, sharing their inodes and blocks, and the source directories
+// whose file metas were written again for it are updated.
+func (d *Directory) CopyTree(srcPath string, dirInode *DirInode, dst *Directory, dstPath string) error {
+	hashes, err := d.CopyEntries(srcPath, dirInode, dst, dstPath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	meta := *dirInode.Meta
+	meta.Path = gopath.Dir(dstPath)
+	meta.Name = gopath.Base(dstPath)
+	meta.CreationTime = now
+	meta.AccessTime = now
+	meta.ModificationTime = now
+	return dst.createDirInodeFeed(dstPath, &DirInode{
+		Meta:   &meta,
+		Hashes: hashes,
+	})
+}
+
+// CopyEntries copies the files and directories in the directory at srcPath, whose
+// inode is dirInode, in to the directory at dstPath in dst, which has to exist, and
+// returns the entries of the copies. Adding them to the inode at dstPath is left to
+// the caller.
+func (d *Directory) CopyEntries(srcPath string, dirInode *DirInode, dst *Directory, dstPath string) ([][]byte, error) {
+	var hashes [][]byte
+	srcChanged := false
+	for i, ref := range dirInode.Hashes {
+		_, data, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
+		if err != nil {
+			srcRef, dstRef, err := d.file.Copy(ref, dst.file, dstPath, "")
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(srcRef, ref) {
+				dirInode.Hashes[i] = srcRef
+				srcChanged = true
+			}
+			hashes = append(hashes, dstRef)
+			continue
+		}
+
+		childInode, err := d.DecodeDirInode(data)
+		if err != nil {
+			return nil, err
+		}
+		childDstPath := dstPath + utils.PathSeperator + childInode.Meta.Name
+		err = d.CopyTree(srcPath+utils.PathSeperator+childInode.Meta.Name, childInode, dst, childDstPath)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, utils.HashString(childDstPath))
+	}
+
+	if srcChanged {
+		_, err := d.UpdateDirectory(dirInode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
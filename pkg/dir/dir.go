@@ -27,10 +27,15 @@ import (
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const (
 	DirectoryNameLength = 25
+
+	// listingCacheSize is the number of sorted directory listings kept for their next
+	// pages.
+	listingCacheSize = 16
 )
 
 type Directory struct {
@@ -42,23 +47,29 @@ type Directory struct {
 	dirMap  map[string]*DirInode // path to dirInode cache
 	dirMu   *sync.RWMutex
 	logger  logging.Logger
+
+	listings *lru.Cache // sorted entries of directories, by inode reference and order
 }
 
 type DirInode struct {
 	Meta   *m.DirectoryMetaData
 	Hashes [][]byte
+	index  []byte    // reference of the page index, if the entries are paged
+	pages  []dirPage // pages the entries were read from or written to
 }
 
 func NewDirectory(podName string, client blockstore.Client, fd *feed.API, acc *account.Info, file *f.File, logger logging.Logger) *Directory {
+	listings, _ := lru.New(listingCacheSize)
 	return &Directory{
-		podName: podName,
-		client:  client,
-		fd:      fd,
-		acc:     acc,
-		file:    file,
-		dirMap:  make(map[string]*DirInode),
-		dirMu:   &sync.RWMutex{},
-		logger:  logger,
+		podName:  podName,
+		client:   client,
+		fd:       fd,
+		acc:      acc,
+		file:     file,
+		dirMap:   make(map[string]*DirInode),
+		dirMu:    &sync.RWMutex{},
+		logger:   logger,
+		listings: listings,
 	}
 }
 
@@ -106,6 +117,31 @@ func (d *Directory) GetDirFromDirectoryMap(path string) *DirInode {
 	return nil
 }
 
+// DirectoriesBelow returns the cached inodes of all the directories below dirPath,
+// by their paths.
+func (d *Directory) DirectoriesBelow(dirPath string) map[string]*DirInode {
+	d.dirMu.Lock()
+	defer d.dirMu.Unlock()
+	dirs := make(map[string]*DirInode)
+	for k, dirInode := range d.dirMap {
+		if strings.HasPrefix(k, dirPath+utils.PathSeperator) {
+			dirs[k] = dirInode
+		}
+	}
+	return dirs
+}
+
+// RemoveDirectoriesBelow drops the cached inodes of all the directories below dirPath.
+func (d *Directory) RemoveDirectoriesBelow(dirPath string) {
+	d.dirMu.Lock()
+	defer d.dirMu.Unlock()
+	for k := range d.dirMap {
+		if strings.HasPrefix(k, dirPath+utils.PathSeperator) {
+			delete(d.dirMap, k)
+		}
+	}
+}
+
 func (d *Directory) GetPrefixPodFromPathMap(prefix string) *DirInode {
 	d.dirMu.Lock()
 	defer d.dirMu.Unlock()
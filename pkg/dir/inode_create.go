@@ -17,7 +17,6 @@ limitations under the License.
 package dir
 
 import (
-	"encoding/json"
 	"time"
 
 	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
@@ -39,7 +38,7 @@ func (d *Directory) CreateDirINode(podName, dirName string, parent *DirInode) (*
 	dirInode := &DirInode{
 		Meta: &meta,
 	}
-	data, err := json.Marshal(dirInode)
+	data, err := d.encodeDirInode(dirInode)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -88,7 +87,7 @@ func (d *Directory) CreatePodINode(podName string) (*DirInode, []byte, error) {
 	dirInode := &DirInode{
 		Meta: &meta,
 	}
-	data, err := json.Marshal(dirInode)
+	data, err := d.encodeDirInode(dirInode)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -115,3 +114,18 @@ func (d *Directory) DeleteDirectoryInode(dirPath string) error {
 	topic := utils.HashString(dirPath)
 	return d.fd.DeleteFeed(topic, d.acc.GetAddress())
 }
+
+// createDirInodeFeed creates the feed of the directory at dirPath with dirInode, which
+// already holds the meta and the entries of the directory.
+func (d *Directory) createDirInodeFeed(dirPath string, dirInode *DirInode) error {
+	data, err := d.encodeDirInode(dirInode)
+	if err != nil {
+		return err
+	}
+	_, err = d.fd.CreateFeed(utils.HashString(dirPath), d.acc.GetAddress(), data)
+	if err != nil {
+		return err
+	}
+	d.AddToDirectoryMap(dirPath, dirInode)
+	return nil
+}
@@ -17,8 +17,6 @@ limitations under the License.
 package dir
 
 import (
-	"encoding/json"
-
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
@@ -31,10 +29,9 @@ func (d *Directory) GetDirNode(name string, fd *feed.API, accountInfo *account.I
 		return nil, nil, err
 	}
 
-	var dirInode DirInode
-	err = json.Unmarshal(data, &dirInode)
+	dirInode, err := d.DecodeDirInode(data)
 	if err != nil {
 		return nil, nil, err
 	}
-	return addr, &dirInode, nil
+	return addr, dirInode, nil
 }
@@ -17,7 +17,6 @@ limitations under the License.
 package dir
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
@@ -30,7 +29,7 @@ func (d *Directory) UpdateDirectory(dirInode *DirInode) ([]byte, error) {
 	meta.ModificationTime = time.Now().Unix()
 	dirInode.Meta = meta
 
-	data, err := json.Marshal(dirInode)
+	data, err := d.encodeDirInode(dirInode)
 	if err != nil {
 		return nil, err
 	}
@@ -17,8 +17,14 @@ limitations under the License.
 package dir
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	gopath "path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -38,59 +44,96 @@ type DirOrFileEntry struct {
 	CreationTime     string `json:"creation_time"`
 	ModificationTime string `json:"modification_time"`
 	AccessTime       string `json:"access_time"`
+
+	// only in detailed listings of files
+	Reference   string `json:"reference,omitempty"`
+	Compression string `json:"compression,omitempty"`
+	Chunking    string `json:"chunking,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
 }
 
 func (d *Directory) ListDir(podName, path string, printNames bool) []DirOrFileEntry {
-	_, dirInode, err := d.GetDirNode(path, d.getFeed(), d.getAccount())
+	entries, err := d.listEntries(path, false)
 	if err != nil {
 		return nil
 	}
+	return entries
+}
+
+// listEntries returns the entries of the directory at path in the order they were
+// added, with the details of the files if detail is set. Entries which can not be
+// read are left out.
+func (d *Directory) listEntries(path string, detail bool) ([]DirOrFileEntry, error) {
+	_, dirInode, err := d.GetDirNode(path, d.getFeed(), d.getAccount())
+	if err != nil {
+		return nil, err
+	}
+	return d.readEntries(dirInode, detail), nil
+}
 
+func (d *Directory) readEntries(dirInode *DirInode, detail bool) []DirOrFileEntry {
 	var listEntries []DirOrFileEntry
 	for _, ref := range dirInode.Hashes {
-		// check if this is a directory
-		_, data, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
+		entry, err := d.readEntry(ref, detail)
 		if err != nil {
-			// if it is not a dir, then treat this reference as a file
-			data, _, err := d.getClient().DownloadBlob(ref)
-			if err != nil {
-				continue
-			}
-			var meta *m.FileMetaData
-			err = json.Unmarshal(data, &meta)
-			if err != nil {
-				continue
-			}
-
-			entry := DirOrFileEntry{
-				Name:             meta.Name,
-				ContentType:      meta.ContentType,
-				Size:             strconv.FormatUint(meta.FileSize, 10),
-				BlockSize:        strconv.FormatInt(int64(uint64(meta.BlockSize)), 10),
-				CreationTime:     strconv.FormatInt(meta.CreationTime, 10),
-				AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
-				ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
-			}
-			listEntries = append(listEntries, entry)
 			continue
 		}
+		listEntries = append(listEntries, *entry)
+	}
+	return listEntries
+}
 
-		var dirInode *DirInode
-		err = json.Unmarshal(data, &dirInode)
+// readEntry reads the entry of a directory at ref, the feed of a sub directory or the
+// meta of a file.
+func (d *Directory) readEntry(ref []byte, detail bool) (*DirOrFileEntry, error) {
+	// check if this is a directory
+	_, data, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
+	if err != nil {
+		// if it is not a dir, then treat this reference as a file
+		data, _, err := d.getClient().DownloadBlob(ref)
 		if err != nil {
-			continue
+			return nil, err
 		}
-		entry := DirOrFileEntry{
-			Name:             dirInode.Meta.Name,
-			ContentType:      MineTypeDirectory, // per RFC2425
-			CreationTime:     strconv.FormatInt(dirInode.Meta.CreationTime, 10),
-			AccessTime:       strconv.FormatInt(dirInode.Meta.AccessTime, 10),
-			ModificationTime: strconv.FormatInt(dirInode.Meta.ModificationTime, 10),
+		var meta *m.FileMetaData
+		err = json.Unmarshal(data, &meta)
+		if err != nil {
+			return nil, err
 		}
-		listEntries = append(listEntries, entry)
+		return fileEntry(meta, ref, detail), nil
 	}
-	return listEntries
 
+	dirMeta, err := decodeDirMeta(data)
+	if err != nil {
+		return nil, err
+	}
+	return &DirOrFileEntry{
+		Name:             dirMeta.Name,
+		ContentType:      MineTypeDirectory, // per RFC2425
+		CreationTime:     strconv.FormatInt(dirMeta.CreationTime, 10),
+		AccessTime:       strconv.FormatInt(dirMeta.AccessTime, 10),
+		ModificationTime: strconv.FormatInt(dirMeta.ModificationTime, 10),
+	}, nil
+}
+
+func fileEntry(meta *m.FileMetaData, ref []byte, detail bool) *DirOrFileEntry {
+	entry := &DirOrFileEntry{
+		Name:             meta.Name,
+		ContentType:      meta.ContentType,
+		Size:             strconv.FormatUint(meta.FileSize, 10),
+		BlockSize:        strconv.FormatInt(int64(uint64(meta.BlockSize)), 10),
+		CreationTime:     strconv.FormatInt(meta.CreationTime, 10),
+		AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
+		ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
+	}
+	if detail {
+		entry.Reference = utils.NewReference(ref).String()
+		entry.Compression = meta.Compression
+		entry.Chunking = meta.Chunking
+		if len(meta.Checksum) > 0 {
+			entry.Checksum = hex.EncodeToString(meta.Checksum)
+		}
+	}
+	return entry
 }
 
 func (d *Directory) ListDirOnlyNames(podName, path string, printNames bool) ([]string, []string) {
@@ -138,3 +181,246 @@ func (d *Directory) ListDirOnlyNames(podName, path string, printNames bool) ([]s
 	}
 	return fileListing, dirListing
 }
+
+const (
+	ListSortName  = "name"
+	ListSortSize  = "size"
+	ListSortMTime = "mtime"
+
+	ListTypeFile = "file"
+	ListTypeDir  = "dir"
+)
+
+var (
+	ErrInvalidListOption = errors.New("invalid list option")
+	ErrInvalidCursor     = errors.New("invalid list cursor")
+)
+
+// ListOptions selects, orders and pages the entries of a directory listing.
+type ListOptions struct {
+	Prefix  string // only names starting with it
+	Type    string // ListTypeFile or ListTypeDir, empty for both
+	SortBy  string // ListSortName, the default, ListSortSize or ListSortMTime
+	Reverse bool
+	Limit   int    // entries in a page, 0 for all of them
+	Cursor  string // NextCursor of the previous page
+	Detail  bool   // include the details of the files
+}
+
+// ListPage is a page of a directory listing. NextCursor is empty on the last page.
+type ListPage struct {
+	Entries    []DirOrFileEntry
+	NextCursor string
+}
+
+// listCursor is the position after the last entry of a page. It holds the sort key of
+// the entry rather than its index, so the next page starts at the right place also
+// when entries were added or removed in between.
+type listCursor struct {
+	Key  int64  `json:"k,omitempty"`
+	Name string `json:"n"`
+}
+
+// ListDirPage lists a page of the entries of the directory at path. Directories have
+// no size and sort as empty files. Entries with the same sort key are ordered by name.
+func (d *Directory) ListDirPage(path string, opts *ListOptions) (*ListPage, error) {
+	if opts.Limit < 0 {
+		return nil, ErrInvalidListOption
+	}
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = ListSortName
+	}
+	var key func(entry *DirOrFileEntry) int64
+	switch sortBy {
+	case ListSortName:
+		key = func(*DirOrFileEntry) int64 { return 0 }
+	case ListSortSize:
+		key = func(entry *DirOrFileEntry) int64 {
+			size, _ := strconv.ParseInt(entry.Size, 10, 64)
+			return size
+		}
+	case ListSortMTime:
+		key = func(entry *DirOrFileEntry) int64 {
+			mtime, _ := strconv.ParseInt(entry.ModificationTime, 10, 64)
+			return mtime
+		}
+	default:
+		return nil, ErrInvalidListOption
+	}
+	if opts.Type != "" && opts.Type != ListTypeFile && opts.Type != ListTypeDir {
+		return nil, ErrInvalidListOption
+	}
+	var cursor *listCursor
+	if opts.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(opts.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		cursor = &listCursor{}
+		err = json.Unmarshal(data, cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	// before tells if an entry with the key and name comes before the other in the
+	// order of the listing
+	before := func(key1 int64, name1 string, key2 int64, name2 string) bool {
+		if key1 != key2 {
+			return (key1 < key2) != opts.Reverse
+		}
+		if opts.Reverse {
+			return name1 > name2
+		}
+		return name1 < name2
+	}
+
+	inodeReference, dirInode, err := d.GetDirNode(path, d.getFeed(), d.getAccount())
+	if err != nil {
+		return nil, err
+	}
+	if sortBy == ListSortName && opts.Prefix == "" && opts.Type == "" {
+		return d.listPageByName(path, dirInode, opts, cursor, before)
+	}
+
+	all := d.sortedEntries(inodeReference, dirInode, sortBy, opts, func(entry1, entry2 *DirOrFileEntry) bool {
+		return before(key(entry1), entry1.Name, key(entry2), entry2.Name)
+	})
+	entries := make([]DirOrFileEntry, 0, len(all))
+	for _, entry := range all {
+		isDir := entry.ContentType == MineTypeDirectory
+		if !strings.HasPrefix(entry.Name, opts.Prefix) ||
+			(opts.Type == ListTypeFile && isDir) || (opts.Type == ListTypeDir && !isDir) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(entries), func(i int) bool {
+			return before(cursor.Key, cursor.Name, key(&entries[i]), entries[i].Name)
+		})
+	}
+	end := len(entries)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+	}
+
+	page := &ListPage{Entries: entries[start:end]}
+	if end < len(entries) {
+		last := &entries[end-1]
+		page.NextCursor, err = encodeCursor(key(last), last.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// sortedEntries reads all the entries of dirInode and sorts them with less. The
+// sorted entries are cached by the reference of the inode, so that the next pages of
+// the listing do not read the directory again.
+func (d *Directory) sortedEntries(inodeReference []byte, dirInode *DirInode, sortBy string, opts *ListOptions, less func(entry1, entry2 *DirOrFileEntry) bool) []DirOrFileEntry {
+	// the inodes of snapshots have no reference
+	cacheKey := fmt.Sprintf("%x/%s/%t/%t", inodeReference, sortBy, opts.Reverse, opts.Detail)
+	if inodeReference != nil {
+		if cached, ok := d.listings.Get(cacheKey); ok {
+			return cached.([]DirOrFileEntry)
+		}
+	}
+	entries := d.readEntries(dirInode, opts.Detail)
+	sort.Slice(entries, func(i, j int) bool {
+		return less(&entries[i], &entries[j])
+	})
+	if inodeReference != nil {
+		d.listings.Add(cacheKey, entries)
+	}
+	return entries
+}
+
+// listPageByName lists a page of the entries of dirInode, the directory at path, in
+// the order of their names. The pod holds the metas of its files and the paths of its
+// directories already, so the names are known without reading the entries, and only
+// the sub directories in the page and the entries the pod does not hold are read.
+func (d *Directory) listPageByName(path string, dirInode *DirInode, opts *ListOptions, cursor *listCursor,
+	before func(key1 int64, name1 string, key2 int64, name2 string) bool) (*ListPage, error) {
+	metas := make(map[string]*m.FileMetaData)
+	for _, filePath := range d.file.ListFiles(path + utils.PathSeperator) {
+		if gopath.Dir(filePath) != path {
+			continue
+		}
+		meta := d.file.GetFromFileMap(filePath)
+		if meta != nil && meta.MetaReference != nil {
+			metas[string(meta.MetaReference)] = meta
+		}
+	}
+	dirNames := make(map[string]string)
+	for dirPath := range d.DirectoriesBelow(path) {
+		if gopath.Dir(dirPath) == path {
+			dirNames[string(utils.HashString(dirPath))] = gopath.Base(dirPath)
+		}
+	}
+
+	type namedEntry struct {
+		name  string
+		ref   []byte
+		entry *DirOrFileEntry // nil until the entry is read
+	}
+	named := make([]namedEntry, 0, len(dirInode.Hashes))
+	for _, ref := range dirInode.Hashes {
+		if meta, ok := metas[string(ref)]; ok {
+			named = append(named, namedEntry{name: meta.Name, ref: ref, entry: fileEntry(meta, ref, opts.Detail)})
+			continue
+		}
+		if name, ok := dirNames[string(ref)]; ok {
+			named = append(named, namedEntry{name: name, ref: ref})
+			continue
+		}
+		entry, err := d.readEntry(ref, opts.Detail)
+		if err != nil {
+			continue
+		}
+		named = append(named, namedEntry{name: entry.Name, ref: ref, entry: entry})
+	}
+	sort.Slice(named, func(i, j int) bool {
+		return before(0, named[i].name, 0, named[j].name)
+	})
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(named), func(i int) bool {
+			return before(0, cursor.Name, 0, named[i].name)
+		})
+	}
+	page := &ListPage{}
+	i := start
+	for ; i < len(named) && (opts.Limit == 0 || len(page.Entries) < opts.Limit); i++ {
+		entry := named[i].entry
+		if entry == nil {
+			var err error
+			entry, err = d.readEntry(named[i].ref, opts.Detail)
+			if err != nil {
+				continue
+			}
+		}
+		page.Entries = append(page.Entries, *entry)
+	}
+	if i < len(named) && len(page.Entries) > 0 {
+		var err error
+		page.NextCursor, err = encodeCursor(0, page.Entries[len(page.Entries)-1].Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func encodeCursor(key int64, name string) (string, error) {
+	data, err := json.Marshal(&listCursor{Key: key, Name: name})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
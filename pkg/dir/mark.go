@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+)
+
+// MarkLiveReferences walks the directory tree below dirInode and marks the blobs of
+// every file in it as live. The directory inodes themselves live in feeds, only the
+// pages of large directories are blobs.
+func (d *Directory) MarkLiveReferences(dirInode *DirInode, fd *feed.API, accountInfo *account.Info, mark func(reference []byte)) error {
+	for _, ref := range dirInode.blobs() {
+		mark(ref)
+	}
+	for _, ref := range dirInode.Hashes {
+		_, data, err := fd.GetFeedData(ref, accountInfo.GetAddress())
+		if err != nil {
+			// not a directory, so it has to be a file. If it is not a file either the
+			// tree can not be walked completely and nothing should be collected.
+			err = d.file.MarkLiveReferences(ref, mark)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		childInode, err := d.DecodeDirInode(data)
+		if err != nil {
+			return err
+		}
+		err = d.MarkLiveReferences(childInode, fd, accountInfo, mark)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
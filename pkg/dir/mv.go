@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	gopath "path"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// MoveTree creates the directory at srcPath, whose inode is dirInode, and everything
+// below it again under dstPath. Directory inodes are found by the hash of their path
+// and file metas carry their path, so both are written again, while the inodes and
+// blocks of the files are reused. It returns the tree of the old directories, which
+// RemoveDirInodes deletes once the new directory is linked in place of the old one.
+func (d *Directory) MoveTree(srcPath, dstPath string, dirInode *DirInode) (*Tree, error) {
+	tree := &Tree{Path: srcPath, blobs: dirInode.blobs()}
+	var hashes [][]byte
+	for _, ref := range dirInode.Hashes {
+		_, data, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
+		if err != nil {
+			newRef, err := d.file.Move(ref, dstPath, "")
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, newRef)
+			continue
+		}
+
+		childInode, err := d.DecodeDirInode(data)
+		if err != nil {
+			return nil, err
+		}
+		childDstPath := dstPath + utils.PathSeperator + childInode.Meta.Name
+		child, err := d.MoveTree(srcPath+utils.PathSeperator+childInode.Meta.Name, childDstPath, childInode)
+		if err != nil {
+			return nil, err
+		}
+		tree.Dirs = append(tree.Dirs, child)
+		hashes = append(hashes, utils.HashString(childDstPath))
+	}
+
+	meta := *dirInode.Meta
+	meta.Path = gopath.Dir(dstPath)
+	meta.Name = gopath.Base(dstPath)
+	newInode := &DirInode{
+		Meta:   &meta,
+		Hashes: hashes,
+	}
+	err := d.createDirInodeFeed(dstPath, newInode)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// RemoveDirInodes deletes the inodes of the directories in tree, with their pages, and
+// drops them from the directory map. The files in the directories are left alone.
+func (d *Directory) RemoveDirInodes(tree *Tree) error {
+	for _, child := range tree.Dirs {
+		err := d.RemoveDirInodes(child)
+		if err != nil {
+			return err
+		}
+	}
+	err := d.DeleteDirectoryInode(tree.Path)
+	if err != nil {
+		return err
+	}
+	err = d.deleteBlobs(tree.blobs)
+	if err != nil {
+		return err
+	}
+	d.RemoveFromDirectoryMap(tree.Path)
+	return nil
+}
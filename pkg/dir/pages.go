@@ -0,0 +1,206 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"net/http"
+
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// dirPageTarget is the average number of entries in a page. A page ends after an
+	// entry whose hash is a multiple of it, so the page boundaries depend only on the
+	// entries around them and adding or removing an entry changes a single page.
+	dirPageTarget = 256
+	// dirPageMax bounds the size of a page when none of its entries ends it.
+	dirPageMax = 1024
+)
+
+var (
+	ErrDirPageNotFound = errors.New("directory page not found")
+)
+
+// dirInodeData is the form of a DirInode which is stored in the feed of the directory.
+// The entries of a directory which fits in a feed chunk are kept inline in Hashes, as
+// in version 1 inodes. The entries of larger directories are split in pages stored as
+// blobs and Index is the reference of the blob which lists those pages.
+type dirInodeData struct {
+	Meta   *m.DirectoryMetaData
+	Hashes [][]byte `json:",omitempty"`
+	Index  []byte   `json:",omitempty"`
+}
+
+// dirPage is a page of entries an inode was read from or written to.
+type dirPage struct {
+	key       [sha256.Size]byte // hash of the encoded page
+	reference []byte
+}
+
+// encodeDirInode returns the feed data of dirInode, uploading the pages of its entries
+// if they do not fit in a feed chunk. Pages which were read or written before with the
+// same entries are not uploaded again.
+func (d *Directory) encodeDirInode(dirInode *DirInode) ([]byte, error) {
+	data, err := json.Marshal(&dirInodeData{Meta: dirInode.Meta, Hashes: dirInode.Hashes})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= utils.MaxChunkLength {
+		dirInode.index = nil
+		dirInode.pages = nil
+		return data, nil
+	}
+
+	known := make(map[[sha256.Size]byte][]byte, len(dirInode.pages))
+	for _, page := range dirInode.pages {
+		known[page.key] = page.reference
+	}
+	var pages []dirPage
+	var references [][]byte
+	for _, entries := range splitPages(dirInode.Hashes) {
+		pageData, err := json.Marshal(entries)
+		if err != nil {
+			return nil, err
+		}
+		key := sha256.Sum256(pageData)
+		ref, ok := known[key]
+		if !ok {
+			ref, err = d.getClient().UploadBlob(pageData, true, true)
+			if err != nil {
+				return nil, err
+			}
+			known[key] = ref
+		}
+		pages = append(pages, dirPage{key: key, reference: ref})
+		references = append(references, ref)
+	}
+
+	indexData, err := json.Marshal(references)
+	if err != nil {
+		return nil, err
+	}
+	index, err := d.getClient().UploadBlob(indexData, true, true)
+	if err != nil {
+		return nil, err
+	}
+	dirInode.index = index
+	dirInode.pages = pages
+	dirInode.Meta.Version = m.DirMetaVersion
+	return json.Marshal(&dirInodeData{Meta: dirInode.Meta, Index: index})
+}
+
+// DecodeDirInode reads a DirInode from the data of a directory feed, downloading its
+// pages if the entries are not inline.
+func (d *Directory) DecodeDirInode(data []byte) (*DirInode, error) {
+	var stored dirInodeData
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, err
+	}
+	dirInode := &DirInode{
+		Meta:   stored.Meta,
+		Hashes: stored.Hashes,
+	}
+	if stored.Index == nil {
+		return dirInode, nil
+	}
+
+	indexData, err := d.downloadPage(stored.Index)
+	if err != nil {
+		return nil, err
+	}
+	var references [][]byte
+	err = json.Unmarshal(indexData, &references)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range references {
+		pageData, err := d.downloadPage(ref)
+		if err != nil {
+			return nil, err
+		}
+		var entries [][]byte
+		err = json.Unmarshal(pageData, &entries)
+		if err != nil {
+			return nil, err
+		}
+		dirInode.Hashes = append(dirInode.Hashes, entries...)
+		dirInode.pages = append(dirInode.pages, dirPage{key: sha256.Sum256(pageData), reference: ref})
+	}
+	dirInode.index = stored.Index
+	return dirInode, nil
+}
+
+// decodeDirMeta reads only the meta of a directory from the data of its feed, without
+// downloading its pages.
+func decodeDirMeta(data []byte) (*m.DirectoryMetaData, error) {
+	var stored dirInodeData
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, err
+	}
+	return stored.Meta, nil
+}
+
+func (d *Directory) downloadPage(reference []byte) ([]byte, error) {
+	data, respCode, err := d.getClient().DownloadBlob(reference)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, ErrDirPageNotFound
+	}
+	return data, nil
+}
+
+// blobs returns the references of the index and the pages of the inode.
+func (i *DirInode) blobs() [][]byte {
+	if i.index == nil {
+		return nil
+	}
+	blobs := [][]byte{i.index}
+	for _, page := range i.pages {
+		blobs = append(blobs, page.reference)
+	}
+	return blobs
+}
+
+func splitPages(hashes [][]byte) [][][]byte {
+	var pages [][][]byte
+	start := 0
+	for i, hash := range hashes {
+		if i+1-start == dirPageMax || isPageBoundary(hash) {
+			pages = append(pages, hashes[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(hashes) {
+		pages = append(pages, hashes[start:])
+	}
+	return pages
+}
+
+func isPageBoundary(hash []byte) bool {
+	h := fnv.New32a()
+	_, _ = h.Write(hash)
+	return h.Sum32()%dirPageTarget == 0
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+type countingClient struct {
+	*mock.MockBeeClient
+	uploads int
+}
+
+func (c *countingClient) UploadBlob(data []byte, pin, encrypt bool) ([]byte, error) {
+	c.uploads++
+	return c.MockBeeClient.UploadBlob(data, pin, encrypt)
+}
+
+func TestDirectory_Pages(t *testing.T) {
+	client := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai := acc.GetUserAccountInfo()
+	fd := feed.New(ai, client, logger)
+	file := f.NewFile("pod1", client, fd, ai, logger)
+	directory := dir.NewDirectory("pod1", client, fd, ai, file, logger)
+
+	podInode, _, err := directory.CreatePodINode("pod1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("large-directory", func(t *testing.T) {
+		podInode.Hashes = randomHashes(t, 20000)
+		_, err = directory.UpdateDirectory(podInode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if podInode.Meta.Version != m.DirMetaVersion {
+			t.Fatalf("invalid version %d", podInode.Meta.Version)
+		}
+
+		_, dirInode, err := directory.GetDirNode("/pod1", fd, ai)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, dirInode.Hashes, podInode.Hashes)
+	})
+
+	t.Run("incremental-update", func(t *testing.T) {
+		_, dirInode, err := directory.GetDirNode("/pod1", fd, ai)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// appending changes the last page and the index
+		client.uploads = 0
+		dirInode.Hashes = append(dirInode.Hashes, randomHashes(t, 1)...)
+		_, err = directory.UpdateDirectory(dirInode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.uploads > 2 {
+			t.Fatalf("appending an entry uploaded %d blobs", client.uploads)
+		}
+
+		// removing an entry changes its page, at most merged with the next one
+		client.uploads = 0
+		dirInode.Hashes = append(dirInode.Hashes[:10000], dirInode.Hashes[10001:]...)
+		_, err = directory.UpdateDirectory(dirInode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.uploads > 3 {
+			t.Fatalf("removing an entry uploaded %d blobs", client.uploads)
+		}
+
+		_, gotInode, err := directory.GetDirNode("/pod1", fd, ai)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, gotInode.Hashes, dirInode.Hashes)
+	})
+
+	t.Run("shrink-to-inline", func(t *testing.T) {
+		podInode.Hashes = randomHashes(t, 2)
+		topic, err := directory.UpdateDirectory(podInode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := fd.GetFeedData(topic, ai.GetAddress())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("Index")) {
+			t.Fatalf("small directory is paged")
+		}
+
+		_, dirInode, err := directory.GetDirNode("/pod1", fd, ai)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, dirInode.Hashes, podInode.Hashes)
+	})
+
+	t.Run("read-version-1", func(t *testing.T) {
+		hashes := randomHashes(t, 5)
+		data, err := json.Marshal(map[string]interface{}{
+			"Meta": &m.DirectoryMetaData{
+				Version: 1,
+				Path:    utils.PathSeperator,
+				Name:    "pod2",
+			},
+			"Hashes": hashes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.CreateFeed(utils.HashString("/pod2"), ai.GetAddress(), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, dirInode, err := directory.GetDirNode("/pod2", fd, ai)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dirInode.Meta.Name != "pod2" || dirInode.Meta.Version != 1 {
+			t.Fatalf("invalid meta")
+		}
+		assertHashes(t, dirInode.Hashes, hashes)
+	})
+}
+
+func randomHashes(t *testing.T, count int) [][]byte {
+	t.Helper()
+	hashes := make([][]byte, count)
+	for i := range hashes {
+		hashes[i] = make([]byte, 32)
+		_, err := rand.Read(hashes[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return hashes
+}
+
+func assertHashes(t *testing.T, got, want [][]byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("entry %d differs", i)
+		}
+	}
+}
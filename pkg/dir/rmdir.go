@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	"errors"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// Tree is a directory with everything below it, as loaded from Swarm.
+type Tree struct {
+	Path  string
+	Files [][]byte // meta references of the files
+	Dirs  []*Tree
+	blobs [][]byte // pages of the directory inode
+}
+
+// RemoveProgress is reported after every file and directory removed from a tree.
+type RemoveProgress struct {
+	Path         string `json:"path"`
+	FilesRemoved int    `json:"files_removed"`
+	DirsRemoved  int    `json:"dirs_removed"`
+	TotalFiles   int    `json:"total_files"`
+	TotalDirs    int    `json:"total_dirs"`
+}
+
+// LoadTree loads the tree of the directory at dirPath whose inode is dirInode.
+func (d *Directory) LoadTree(dirPath string, dirInode *DirInode) (*Tree, error) {
+	tree := &Tree{Path: dirPath, blobs: dirInode.blobs()}
+	for _, ref := range dirInode.Hashes {
+		_, data, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
+		if err != nil {
+			tree.Files = append(tree.Files, ref)
+			continue
+		}
+
+		childInode, err := d.DecodeDirInode(data)
+		if err != nil {
+			return nil, err
+		}
+		childPath := childInode.Meta.Path + utils.PathSeperator + childInode.Meta.Name
+		child, err := d.LoadTree(childPath, childInode)
+		if err != nil {
+			return nil, err
+		}
+		tree.Dirs = append(tree.Dirs, child)
+	}
+	return tree, nil
+}
+
+// Count returns the number of files and directories in the tree, itself included.
+func (t *Tree) Count() (files, dirs int) {
+	files = len(t.Files)
+	dirs = 1
+	for _, child := range t.Dirs {
+		childFiles, childDirs := child.Count()
+		files += childFiles
+		dirs += childDirs
+	}
+	return files, dirs
+}
+
+// RemoveTree deletes the tree depth first: the files of a directory, then its sub
+// directories and then its own inode, so that nothing is deleted before the entries
+// below it. progress, if not nil, is called after every removal.
+func (d *Directory) RemoveTree(tree *Tree, progress func(*RemoveProgress)) error {
+	state := &RemoveProgress{}
+	state.TotalFiles, state.TotalDirs = tree.Count()
+	return d.removeTree(tree, state, progress)
+}
+
+func (d *Directory) removeTree(tree *Tree, state *RemoveProgress, progress func(*RemoveProgress)) error {
+	for _, ref := range tree.Files {
+		path, err := d.file.DeleteFile(tree.Path, ref)
+		if err != nil {
+			return err
+		}
+		state.FilesRemoved++
+		state.Path = path
+		if progress != nil {
+			progress(state)
+		}
+	}
+
+	for _, child := range tree.Dirs {
+		err := d.removeTree(child, state, progress)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := d.DeleteDirectoryInode(tree.Path)
+	if err != nil {
+		return err
+	}
+	err = d.deleteBlobs(tree.blobs)
+	if err != nil {
+		return err
+	}
+	d.RemoveFromDirectoryMap(tree.Path)
+	state.DirsRemoved++
+	state.Path = tree.Path
+	if progress != nil {
+		progress(state)
+	}
+	return nil
+}
+
+// deleteBlobs deletes the blobs at references, skipping those which are already gone,
+// unless the blobs of the pod are kept for its snapshots.
+func (d *Directory) deleteBlobs(references [][]byte) error {
+	if d.file.IsKeepingBlobs() {
+		return nil
+	}
+	for _, ref := range references {
+		err := d.getClient().DeleteBlob(ref)
+		if err != nil && !errors.Is(err, blockstore.ErrBlobNotFound) {
+			return err
+		}
+	}
+	return nil
+}
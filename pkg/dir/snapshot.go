@@ -0,0 +1,56 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	"encoding/hex"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// CollectInodes adds the feed data of the directory at path and of every directory
+// below it to inodes, by the hex of the topic of their feed. The data holds the
+// references of the file metas and of the pages of entries, which are never changed
+// once uploaded, so together they are a frozen copy of the tree.
+func (d *Directory) CollectInodes(path string, inodes map[string][]byte) error {
+	topic := utils.HashString(path)
+	_, data, err := d.getFeed().GetFeedData(topic, d.getAccount().GetAddress())
+	if err != nil {
+		return err
+	}
+	return d.collectInodes(topic, data, inodes)
+}
+
+func (d *Directory) collectInodes(topic, data []byte, inodes map[string][]byte) error {
+	inodes[hex.EncodeToString(topic)] = data
+	dirInode, err := d.DecodeDirInode(data)
+	if err != nil {
+		return err
+	}
+	for _, ref := range dirInode.Hashes {
+		_, childData, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
+		if err != nil {
+			// not a directory, the reference of the file meta is in data already
+			continue
+		}
+		err = d.collectInodes(ref, childData, inodes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package dir
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
@@ -39,8 +38,7 @@ func (d *Directory) LoadDirMeta(podName string, curDirInode *DirInode, fd *feed.
 			return err
 		}
 
-		var dirInode *DirInode
-		err = json.Unmarshal(data, &dirInode)
+		dirInode, err := d.DecodeDirInode(data)
 		if err != nil {
 			return err
 		}
@@ -18,6 +18,7 @@ package feed
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -46,12 +47,16 @@ var (
 	ErrInvalidPayloadSize = fmt.Errorf("payload is greater than %d", utils.MaxChunkLength)
 
 	ErrReadOnlyFeed = fmt.Errorf("read only feed")
+
+	// ErrNoFeedUpdates is returned when a feed has no update yet
+	ErrNoFeedUpdates = NewError(ErrNotFound, "no feed updates found")
 )
 
 type API struct {
 	handler     *Handler
 	accountInfo *account.Info
 	logger      logging.Logger
+	pinned      map[string][]byte // data of the feeds by the hex of their topic, if pinned
 }
 
 type Request struct {
@@ -73,6 +78,14 @@ func New(accountInfo *account.Info, client blockstore.Client, logger logging.Log
 	}
 }
 
+// NewPinned returns a read only API which finds the data of the feeds in pinned, by
+// the hex of their topic, instead of in Swarm. The feeds of every user look the same.
+func NewPinned(pinned map[string][]byte, client blockstore.Client, logger logging.Logger) *API {
+	a := New(&account.Info{}, client, logger)
+	a.pinned = pinned
+	return a
+}
+
 // create feed
 func (a *API) CreateFeed(topic []byte, user utils.Address, data []byte) ([]byte, error) {
 	var req Request
@@ -132,7 +145,7 @@ func (a *API) CreateFeed(topic []byte, user utils.Address, data []byte) ([]byte,
 	}
 
 	// send the updated soc chunk to bee
-	address, err := a.handler.update(&req)
+	address, err := a.handler.update(context.Background(), &req)
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +156,13 @@ func (a *API) GetFeedData(topic []byte, user utils.Address) ([]byte, []byte, err
 	if len(topic) != TopicLength {
 		return nil, nil, ErrInvalidTopicSize
 	}
+	if a.pinned != nil {
+		data, ok := a.pinned[hex.EncodeToString(topic)]
+		if !ok {
+			return nil, nil, ErrNoFeedUpdates
+		}
+		return nil, data, nil
+	}
 
 	ctx := context.Background()
 	f := new(Feed)
@@ -237,7 +257,7 @@ func (a *API) UpdateFeed(topic []byte, user utils.Address, data []byte) ([]byte,
 	//	}
 	//}
 
-	address, err := a.handler.update(req)
+	address, err := a.handler.update(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -254,7 +274,7 @@ func (a *API) DeleteFeed(topic []byte, user utils.Address) error {
 		return err
 	}
 	if delRef != nil {
-		err = a.handler.deleteChunk(delRef)
+		err = a.handler.deleteChunk(context.Background(), delRef)
 		if err != nil {
 			return err
 		}
@@ -19,6 +19,7 @@ package feed
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -101,7 +102,7 @@ func TestFeed(t *testing.T) {
 
 		// check if the data and address is present and is same as stored
 		_, _, err := fd.GetFeedData(topic, user1)
-		if err != nil && err.Error() != "no feed updates found" {
+		if !errors.Is(err, ErrNoFeedUpdates) {
 			t.Fatal(err)
 		}
 	})
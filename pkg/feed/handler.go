@@ -40,7 +40,7 @@ import (
 
 type Handler struct {
 	accountInfo *account.Info
-	client      blockstore.Client
+	client      blockstore.ClientV2
 	hasherPool  *bmtlegacy.TreePool
 	HashSize    int
 	cache       map[uint64]*CacheEntry
@@ -62,7 +62,7 @@ func init() {
 func NewHandler(accountInfo *account.Info, client blockstore.Client, hasherPool *bmtlegacy.TreePool) *Handler {
 	fh := &Handler{
 		accountInfo: accountInfo,
-		client:      client,
+		client:      blockstore.ToV2(client),
 		hasherPool:  hasherPool,
 		cache:       make(map[uint64]*CacheEntry),
 	}
@@ -76,22 +76,22 @@ func NewHandler(accountInfo *account.Info, client blockstore.Client, hasherPool
 	return fh
 }
 
-func (h *Handler) update(req *Request) ([]byte, error) {
+func (h *Handler) update(ctx context.Context, req *Request) ([]byte, error) {
 	if req.idAddr.Equal(swarm.ZeroAddress) || req.binaryData == nil {
 		return nil, fmt.Errorf("invlaid address or chunk data")
 	}
 	ch := swarm.NewChunk(req.idAddr, req.binaryData)
 
 	// send the chunk
-	addr, err := h.client.UploadChunk(ch, true)
+	addr, err := h.client.UploadChunkContext(ctx, ch, true)
 	if err != nil {
 		return nil, err
 	}
 	return addr, nil
 }
 
-func (h *Handler) deleteChunk(ref []byte) error {
-	return h.client.DeleteChunk(ref)
+func (h *Handler) deleteChunk(ctx context.Context, ref []byte) error {
+	return h.client.DeleteChunkContext(ctx, ref)
 }
 
 // GetContent retrieves the data payload of the last synced update of the feed
@@ -174,7 +174,7 @@ func (h *Handler) Lookup(ctx context.Context, query *Query) (*CacheEntry, error)
 	}
 	request, _ := requestPtr.(*Request)
 	if request == nil {
-		return nil, NewError(ErrNotFound, "no feed updates found")
+		return nil, ErrNoFeedUpdates
 	}
 	return h.updateCache(request)
 }
@@ -0,0 +1,172 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"io"
+	"math/bits"
+)
+
+const (
+	// ChunkingCDC splits a file in to blocks at content defined boundaries, so an
+	// insert or a delete in the file only changes the blocks around it.
+	ChunkingCDC = "cdc"
+
+	// the minimum and maximum block sizes are derived from the average block size
+	cdcMinDivisor    = 4
+	cdcMaxMultiplier = 4
+)
+
+// gearTable has a pseudo random value for every byte. It is generated from a fixed
+// seed, the block boundaries of a file must not change between versions of dfs.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	seed := uint64(0x6466732d63646321)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// CDCBlockSizes returns the minimum and maximum block sizes of content defined
+// chunking with the given average block size.
+func CDCBlockSizes(avgSize uint32) (uint32, uint32) {
+	minSize := avgSize / cdcMinDivisor
+	if minSize == 0 {
+		minSize = 1
+	}
+	return minSize, avgSize * cdcMaxMultiplier
+}
+
+// Chunker splits a stream in to content defined blocks with FastCDC. A block ends
+// where the gear hash of the bytes before matches a mask, the mask is harder to match
+// before the average size and easier after it, which keeps the block sizes close to
+// the average. Blocks are never shorter than min, except the last, or longer than max.
+type Chunker struct {
+	reader  io.Reader
+	buf     []byte
+	start   int
+	end     int
+	eof     bool
+	minSize int
+	avgSize int
+	maxSize int
+	maskS   uint64
+	maskL   uint64
+}
+
+func NewChunker(reader io.Reader, minSize, avgSize, maxSize uint32) *Chunker {
+	avgBits := bits.Len32(avgSize) - 1
+	return &Chunker{
+		reader:  reader,
+		buf:     make([]byte, maxSize),
+		minSize: int(minSize),
+		avgSize: int(avgSize),
+		maxSize: int(maxSize),
+		maskS:   topBitsMask(avgBits + 1),
+		maskL:   topBitsMask(avgBits - 1),
+	}
+}
+
+// topBitsMask returns a mask of the n highest bits. The gear hash is shifted left by
+// every byte, so its high bits depend on the most bytes before.
+func topBitsMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	if n >= 64 {
+		return ^uint64(0)
+	}
+	return ((uint64(1) << uint(n)) - 1) << uint(64-n)
+}
+
+// Next returns the next block of the stream, or io.EOF once all of it is returned. The
+// block is a new slice which the caller can keep.
+func (c *Chunker) Next() ([]byte, error) {
+	err := c.fill()
+	if err != nil {
+		return nil, err
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+	n := c.cut(c.buf[c.start:c.end])
+	block := make([]byte, n)
+	copy(block, c.buf[c.start:c.start+n])
+	c.start += n
+	return block, nil
+}
+
+// fill reads till a whole max size block is buffered or the stream ends.
+func (c *Chunker) fill() error {
+	if c.eof || c.end-c.start >= c.maxSize {
+		return nil
+	}
+	if c.start > 0 {
+		copy(c.buf, c.buf[c.start:c.end])
+		c.end -= c.start
+		c.start = 0
+	}
+	for c.end < c.maxSize {
+		n, err := c.reader.Read(c.buf[c.end:])
+		c.end += n
+		if err == io.EOF {
+			c.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cut returns the length of the block at the start of data.
+func (c *Chunker) cut(data []byte) int {
+	n := len(data)
+	if n <= c.minSize {
+		return n
+	}
+	if n > c.maxSize {
+		n = c.maxSize
+	}
+	normal := c.avgSize
+	if normal > n {
+		normal = n
+	}
+
+	var hash uint64
+	i := c.minSize
+	for ; i < normal; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
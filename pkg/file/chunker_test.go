@@ -0,0 +1,83 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+)
+
+func TestChunker(t *testing.T) {
+	avgSize := uint32(1024)
+	minSize, maxSize := file.CDCBlockSizes(avgSize)
+	data := make([]byte, 200*1024)
+	rand.Read(data)
+
+	chunks := func(t *testing.T, data []byte) [][]byte {
+		chunker := file.NewChunker(bytes.NewReader(data), minSize, avgSize, maxSize)
+		var chunks [][]byte
+		for {
+			chunk, err := chunker.Next()
+			if errors.Is(err, io.EOF) {
+				return chunks
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	t.Run("chunk-sizes", func(t *testing.T) {
+		got := chunks(t, data)
+		if !bytes.Equal(bytes.Join(got, nil), data) {
+			t.Fatalf("chunks do not make up the data")
+		}
+		for i, chunk := range got {
+			if uint32(len(chunk)) > maxSize || (i < len(got)-1 && uint32(len(chunk)) < minSize) {
+				t.Fatalf("chunk %d has invalid size %d", i, len(chunk))
+			}
+		}
+		avg := len(data) / len(got)
+		if avg < int(avgSize)/2 || avg > int(avgSize)*2 {
+			t.Fatalf("average chunk size %d too far from %d", avg, avgSize)
+		}
+	})
+
+	t.Run("insert-changes-few-chunks", func(t *testing.T) {
+		inserted := append(append(append([]byte{}, data[:1000]...), 'x'), data[1000:]...)
+		before := make(map[string]bool)
+		for _, chunk := range chunks(t, data) {
+			before[string(chunk)] = true
+		}
+		got := chunks(t, inserted)
+		changed := 0
+		for _, chunk := range got {
+			if !before[string(chunk)] {
+				changed++
+			}
+		}
+		if changed > 3 {
+			t.Fatalf("inserting a byte changed %d of %d chunks", changed, len(got))
+		}
+	})
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	// CompressionAuto compresses the blocks of a file with zstd, except when the
+	// content of the file is compressed already or a block does not get smaller.
+	CompressionAuto = "auto"
+
+	// compressionNone is recorded in the blocks which auto stored as they are
+	compressionNone = "none"
+	autoCodec       = "zstd"
+)
+
+var (
+	ErrInvalidCompression = errors.New("invalid compression")
+
+	// content types which do not get smaller when compressed again
+	compressedContentTypes = []string{
+		"image/gif",
+		"image/jpeg",
+		"image/png",
+		"image/webp",
+		"video/",
+		"audio/mpeg",
+		"application/ogg",
+		"application/x-gzip",
+		"application/zip",
+		"application/x-rar-compressed",
+		"font/woff",
+		"font/woff2",
+	}
+
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderOnce sync.Once
+	zstdEncoders    = make(map[zstd.EncoderLevel]*zstd.Encoder)
+	zstdEncodersMu  sync.Mutex
+)
+
+// ParseCompression splits a compression in to its codec and level, "zstd:19" is zstd
+// at level 19. The level is 0 when it is not given, which is the default level of the
+// codec. The levels are 1 to 9 for gzip and lz4 and 1 to 22 for zstd, snappy and auto
+// have none.
+func ParseCompression(compression string) (string, int, error) {
+	codec, levelStr := compression, ""
+	if i := strings.IndexByte(compression, ':'); i >= 0 {
+		codec, levelStr = compression[:i], compression[i+1:]
+	}
+	level := 0
+	if levelStr != "" {
+		var err error
+		level, err = strconv.Atoi(levelStr)
+		if err != nil || level <= 0 {
+			return "", 0, ErrInvalidCompression
+		}
+	}
+
+	maxLevel := 0
+	switch codec {
+	case "gzip", "lz4":
+		maxLevel = 9
+	case "zstd":
+		maxLevel = 22
+	case "snappy", CompressionAuto:
+	default:
+		return "", 0, ErrInvalidCompression
+	}
+	if level > maxLevel {
+		return "", 0, ErrInvalidCompression
+	}
+	return codec, level, nil
+}
+
+// compressBlock compresses a block of a file. With CompressionAuto the returned block
+// compression is the one picked for the block, it is empty otherwise since the
+// compression of the file is used for all the blocks.
+func compressBlock(data []byte, compression string, blockSize uint32, contentType string) ([]byte, string, error) {
+	if compression != CompressionAuto {
+		compressed, err := Compress(data, compression, blockSize)
+		return compressed, "", err
+	}
+	if isCompressedContent(contentType) {
+		return data, compressionNone, nil
+	}
+	compressed, err := Compress(data, autoCodec, blockSize)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(compressed) >= len(data) {
+		return data, compressionNone, nil
+	}
+	return compressed, autoCodec, nil
+}
+
+// blockCompression returns the compression of a block. The blocks of files compressed
+// with CompressionAuto record their own, for the others it is the one of the file.
+func blockCompression(block *FileBlock, compression string) string {
+	if block.Compression != "" {
+		return block.Compression
+	}
+	return compression
+}
+
+func isCompressedContent(contentType string) bool {
+	for _, t := range compressedContentTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func gzipCompress(data []byte, level int, blockSize uint32) ([]byte, error) {
+	var b bytes.Buffer
+	if level == 0 {
+		level = pgzip.DefaultCompression
+	}
+	w, err := pgzip.NewWriterLevel(&b, level)
+	if err != nil {
+		return nil, err
+	}
+	block := int(blockSize / 10)
+	err = w.SetConcurrency(block, 10)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func zstdCompress(data []byte, level int) ([]byte, error) {
+	encoderLevel := zstd.SpeedDefault
+	if level != 0 {
+		encoderLevel = zstd.EncoderLevelFromZstd(level)
+	}
+
+	// an encoder can compress many blocks at the same time, so one is kept per level
+	zstdEncodersMu.Lock()
+	encoder, ok := zstdEncoders[encoderLevel]
+	if !ok {
+		var err error
+		encoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(encoderLevel))
+		if err != nil {
+			zstdEncodersMu.Unlock()
+			return nil, err
+		}
+		zstdEncoders[encoderLevel] = encoder
+	}
+	zstdEncodersMu.Unlock()
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func zstdDecompress(data []byte) ([]byte, error) {
+	var err error
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, err = zstd.NewReader(nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if zstdDecoder == nil {
+		return nil, ErrInvalidCompression
+	}
+	return zstdDecoder.DecodeAll(data, nil)
+}
+
+func lz4Compress(data []byte, level int) ([]byte, error) {
+	var b bytes.Buffer
+	w := lz4.NewWriter(&b)
+	if level != 0 {
+		err := w.Apply(lz4.CompressionLevelOption(lz4.CompressionLevel(1 << (8 + level))))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func lz4Decompress(data []byte) ([]byte, error) {
+	return ioutil.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+)
+
+func TestCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("fairOS-dfs compression "), 1000)
+	blockSize := uint32(163850) // pgzip needs blocks larger than 16384 bytes for each of its 10 workers
+
+	t.Run("parse", func(t *testing.T) {
+		valid := []string{"gzip", "gzip:9", "snappy", "zstd", "zstd:22", "lz4", "lz4:1", file.CompressionAuto}
+		for _, c := range valid {
+			if _, _, err := file.ParseCompression(c); err != nil {
+				t.Fatalf("%s: %v", c, err)
+			}
+		}
+		invalid := []string{"brotli", "gzip:10", "zstd:23", "lz4:0", "snappy:1", "auto:3", "zstd:fast", "none"}
+		for _, c := range invalid {
+			if _, _, err := file.ParseCompression(c); err != file.ErrInvalidCompression {
+				t.Fatalf("%s: expected invalid compression, got %v", c, err)
+			}
+		}
+	})
+
+	t.Run("round-trip", func(t *testing.T) {
+		for _, c := range []string{"gzip", "gzip:1", "snappy", "zstd", "zstd:1", "zstd:19", "lz4", "lz4:9"} {
+			compressed, err := file.Compress(data, c, blockSize)
+			if err != nil {
+				t.Fatalf("%s: %v", c, err)
+			}
+			if len(compressed) >= len(data) {
+				t.Fatalf("%s: data not compressed", c)
+			}
+			decompressed, err := file.Decompress(compressed, c, blockSize)
+			if err != nil {
+				t.Fatalf("%s: %v", c, err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Fatalf("%s: decompressed data differs", c)
+			}
+		}
+	})
+
+	t.Run("no-compression", func(t *testing.T) {
+		for _, c := range []string{"", "none"} {
+			compressed, err := file.Compress(data, c, blockSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(compressed, data) {
+				t.Fatalf("%q: data changed", c)
+			}
+		}
+	})
+
+	t.Run("random-data", func(t *testing.T) {
+		random := make([]byte, 4096)
+		rand.Read(random)
+		compressed, err := file.Compress(random, "zstd", uint32(len(random)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decompressed, err := file.Decompress(compressed, "zstd", uint32(len(random)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decompressed, random) {
+			t.Fatalf("decompressed data differs")
+		}
+	})
+}
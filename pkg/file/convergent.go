@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+)
+
+const (
+	blockKeyContext = "fairOS-dfs block key"
+)
+
+// uploadBlock uploads the data of a block. Blocks of files chunked by content are
+// encrypted here with a key derived from the pod key and their data, instead of by
+// bee with a random key, so the same block is the same blob in every upload to the
+// pod and Swarm stores it once. The key is returned to be kept in the inode, it is
+// nil for blocks encrypted by bee. Blocks are streamed to the blockstore from a
+// seekable reader, so that they can be retried and are not kept by its caches.
+func (f *File) uploadBlock(ctx context.Context, data []byte, convergent bool) ([]byte, []byte, error) {
+	if !convergent || f.acc == nil || f.acc.GetPrivateKey() == nil {
+		addr, err := f.getClient().UploadBlobStream(ctx, bytes.NewReader(data), true, true)
+		return addr, nil, err
+	}
+	key := f.blockKey(data)
+	sealed, err := cryptBlock(key, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	addr, err := f.getClient().UploadBlobStream(ctx, bytes.NewReader(sealed), true, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return addr, key, nil
+}
+
+// blockKey derives the key of a block from the pod key and the block data. Only the
+// same data in the same pod gets the same key, so other pods can not tell which
+// blocks they share with it.
+func (f *File) blockKey(data []byte) []byte {
+	mac := hmac.New(sha256.New, f.acc.GetPrivateKey().D.Bytes())
+	mac.Write([]byte(blockKeyContext))
+	sum := sha256.Sum256(data)
+	mac.Write(sum[:])
+	return mac.Sum(nil)
+}
+
+// downloadBlock streams the stored data of a block from the blockstore in to a buffer
+// of the size kept in the inode, decrypting it on the way if it was encrypted with a
+// key of its own. A blob of another size is not the block and gives ErrInvalidBlock.
+func downloadBlock(ctx context.Context, client blockstore.ClientV2, block *FileBlock) ([]byte, error) {
+	rc, _, err := client.DownloadBlobStream(ctx, block.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if len(block.Key) > 0 {
+		c, err := aes.NewCipher(block.Key)
+		if err != nil {
+			return nil, err
+		}
+		r = cipher.StreamReader{S: cipher.NewCTR(c, make([]byte, aes.BlockSize)), R: rc}
+	}
+	if block.CompressedSize == 0 {
+		return ioutil.ReadAll(r)
+	}
+	data := make([]byte, block.CompressedSize)
+	_, err = io.ReadFull(r, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrInvalidBlock
+	}
+	if err != nil {
+		return nil, err
+	}
+	var extra [1]byte
+	_, err = io.ReadFull(r, extra[:])
+	if err == nil {
+		return nil, ErrInvalidBlock
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// cryptBlock encrypts or decrypts data with AES-256 in counter mode. Every key is
+// only used for one plain text, so the counter always starts at zero.
+func cryptBlock(key, data []byte) ([]byte, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(c, make([]byte, aes.BlockSize)).XORKeyStream(out, data)
+	return out, nil
+}
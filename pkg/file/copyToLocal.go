@@ -18,6 +18,7 @@ package file
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -54,7 +55,7 @@ func (f *File) CopyToFile(podFile string, localDir string) error {
 
 	totalBytes := uint32(0)
 	for _, fb := range fileInode.FileBlocks {
-		stdoutBytes, _, err := f.getClient().DownloadBlob(fb.Address)
+		stdoutBytes, err := downloadBlock(context.Background(), f.getClient(), fb)
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// Copy copies the file at metaReference to dst, the files of this or of another pod,
+// at dstPath with dstName, or the current name if dstName is empty. The copy gets a
+// meta of its own pointing at the same inode, so no block is uploaded again. Both
+// metas are marked as sharing the inode, which makes a delete leave the inode and the
+// blocks to the garbage collector instead of removing them under the other file. The
+// meta of the source is written again for that unless it is marked already or the
+// pod is read only, so srcReference is where the source meta is found afterwards.
+func (f *File) Copy(metaReference []byte, dst *File, dstPath, dstName string) (srcReference, dstReference []byte, err error) {
+	metaBytes, respCode, err := f.getClient().DownloadBlob(metaReference)
+	if err != nil {
+		return nil, nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("file meta %x not found", metaReference)
+	}
+	var meta m.FileMetaData
+	err = json.Unmarshal(metaBytes, &meta)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srcReference = metaReference
+	if !meta.SharedInode && !f.acc.IsReadOnlyPod() {
+		meta.SharedInode = true
+		meta.MetaReference = nil
+		srcMetaBytes, err := json.Marshal(meta)
+		if err != nil {
+			return nil, nil, err
+		}
+		srcReference, err = f.getClient().UploadBlob(srcMetaBytes, true, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		srcMeta := meta
+		srcMeta.MetaReference = srcReference
+		f.AddToFileMap(meta.Path+utils.PathSeperator+meta.Name, &srcMeta)
+	}
+
+	now := time.Now().Unix()
+	meta.Path = dstPath
+	if dstName != "" {
+		meta.Name = dstName
+	}
+	meta.SharedInode = true
+	meta.CreationTime = now
+	meta.AccessTime = now
+	meta.ModificationTime = now
+	meta.MetaReference = nil
+	dstMetaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	dstReference, err = dst.getClient().UploadBlob(dstMetaBytes, true, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta.MetaReference = dstReference
+	dst.AddToFileMap(dstPath+utils.PathSeperator+meta.Name, &meta)
+	return srcReference, dstReference, nil
+}
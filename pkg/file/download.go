@@ -17,10 +17,11 @@ limitations under the License.
 package file
 
 import (
+	"context"
 	"io"
 )
 
-func (f *File) Download(podFile string) (io.ReadCloser, string, string, error) {
+func (f *File) Download(ctx context.Context, podFile string) (io.ReadCloser, string, string, error) {
 	//TODO: need to change the access time for podFile
-	return f.OpenFileForReading(podFile)
+	return f.OpenFileForReading(ctx, podFile)
 }
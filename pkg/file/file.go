@@ -28,12 +28,15 @@ import (
 
 type File struct {
 	podName string
-	client  blockstore.Client
+	client  blockstore.ClientV2
 	fd      *feed.API
 	acc     *account.Info
 	fileMap map[string]*m.FileMetaData
 	fileMu  *sync.RWMutex
 	logger  logging.Logger
+
+	keepBlobs     bool // deletes leave the blobs to the garbage collector
+	trackVersions bool // deletes and moves update the histories of the files
 }
 
 type FileINode struct {
@@ -45,12 +48,15 @@ type FileBlock struct {
 	Size           uint32
 	CompressedSize uint32
 	Address        []byte
+	Compression    string `json:",omitempty"` // set when the file is compressed with CompressionAuto
+	Checksum       []byte `json:",omitempty"` // sha256 of the block data before compression
+	Key            []byte `json:",omitempty"` // set when the block is encrypted by fairOS-dfs instead of by bee
 }
 
 func NewFile(podName string, client blockstore.Client, fd *feed.API, acc *account.Info, logger logging.Logger) *File {
 	return &File{
 		podName: podName,
-		client:  client,
+		client:  blockstore.ToV2(client),
 		fd:      fd,
 		acc:     acc,
 		fileMap: make(map[string]*m.FileMetaData),
@@ -59,7 +65,7 @@ func NewFile(podName string, client blockstore.Client, fd *feed.API, acc *accoun
 	}
 }
 
-func (f *File) getClient() blockstore.Client {
+func (f *File) getClient() blockstore.ClientV2 {
 	return f.client
 }
 
@@ -84,6 +90,35 @@ func (f *File) GetFromFileMap(filePath string) *m.FileMetaData {
 	return nil
 }
 
+// KeepBlobs makes the deletes of files leave their blobs to the garbage collector,
+// which knows if a snapshot of the pod still uses them.
+func (f *File) KeepBlobs(keep bool) {
+	f.fileMu.Lock()
+	defer f.fileMu.Unlock()
+	f.keepBlobs = keep
+}
+
+func (f *File) IsKeepingBlobs() bool {
+	f.fileMu.Lock()
+	defer f.fileMu.Unlock()
+	return f.keepBlobs
+}
+
+// TrackVersions makes the deletes and moves of files update their histories. Only the
+// files of a pod which ever had versioning can have one, so the others skip the lookup
+// of the feed of the history.
+func (f *File) TrackVersions(track bool) {
+	f.fileMu.Lock()
+	defer f.fileMu.Unlock()
+	f.trackVersions = track
+}
+
+func (f *File) IsTrackingVersions() bool {
+	f.fileMu.Lock()
+	defer f.fileMu.Unlock()
+	return f.trackVersions
+}
+
 func (f *File) IsFileAlreadyPResent(fileWithPath string) bool {
 	f.fileMu.Lock()
 	defer f.fileMu.Unlock()
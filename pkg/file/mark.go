@@ -0,0 +1,74 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// MarkLiveReferences marks the meta blob of a file, its inode and all its blocks as
+// live, along with the older versions of the file. Any error means the file could not
+// be fully walked.
+func (f *File) MarkLiveReferences(metaReference []byte, mark func(reference []byte)) error {
+	meta, err := f.markFile(metaReference, mark)
+	if err != nil {
+		return err
+	}
+	return f.markVersions(meta.Path+utils.PathSeperator+meta.Name, mark)
+}
+
+// markFile marks the meta blob at metaReference, the inode and the blocks of the file
+// as live.
+func (f *File) markFile(metaReference []byte, mark func(reference []byte)) (*m.FileMetaData, error) {
+	metaBytes, respCode, err := f.getClient().DownloadBlob(metaReference)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("file meta %x not found", metaReference)
+	}
+	var meta m.FileMetaData
+	err = json.Unmarshal(metaBytes, &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInodeBytes, respCode, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("file inode %x not found", meta.InodeAddress)
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil {
+		return nil, err
+	}
+
+	mark(metaReference)
+	mark(meta.InodeAddress)
+	for _, b := range fileInode.FileBlocks {
+		mark(b.Address)
+	}
+	return &meta, nil
+}
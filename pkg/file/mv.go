@@ -0,0 +1,73 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// Move uploads the meta of the file at metaReference again with dstPath and dstName,
+// or its current name if dstName is empty, and moves the file to its new path in the
+// file map, together with its versions. The inode and the blocks of the file are
+// reused as they are. It returns the reference of the new meta. The old meta is not
+// deleted, since a file shared earlier still points at it, and is left to the garbage
+// collector.
+func (f *File) Move(metaReference []byte, dstPath, dstName string) ([]byte, error) {
+	metaBytes, respCode, err := f.getClient().DownloadBlob(metaReference)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("file meta %x not found", metaReference)
+	}
+	var meta m.FileMetaData
+	err = json.Unmarshal(metaBytes, &meta)
+	if err != nil {
+		return nil, err
+	}
+	srcPath := meta.Path + utils.PathSeperator + meta.Name
+
+	meta.Path = dstPath
+	if dstName != "" {
+		meta.Name = dstName
+	}
+	meta.MetaReference = nil
+	fileMetaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	newReference, err := f.getClient().UploadBlob(fileMetaBytes, true, true)
+	if err != nil {
+		return nil, err
+	}
+	meta.MetaReference = newReference
+
+	if f.IsTrackingVersions() {
+		err = f.moveVersions(srcPath, dstPath+utils.PathSeperator+meta.Name, metaReference, newReference)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.RemoveFromFileMap(srcPath)
+	f.AddToFileMap(dstPath+utils.PathSeperator+meta.Name, &meta)
+	return newReference, nil
+}
@@ -18,17 +18,20 @@ package file
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strconv"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
 	"github.com/golang/snappy"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/klauspost/pgzip"
@@ -40,55 +43,56 @@ const (
 
 var (
 	ErrInvalidOffset = errors.New("invalid offset")
+	ErrInvalidBlock  = errors.New("block size does not match the inode")
 )
 
 type Reader struct {
-	readOffset  int64
-	client      blockstore.Client
-	fileInode   FileINode
-	fileC       chan []byte
-	lastBlock   []byte
-	fileSize    uint64
-	blockSize   uint32
-	blockCursor uint32
-	totalSize   uint64
-	compression string
-	blockCache  *lru.Cache
+	ctx           context.Context
+	readOffset    int64
+	client        blockstore.ClientV2
+	fileInode     FileINode
+	blockOffsets  []int64 // offset in the file where every block starts
+	fileC         chan []byte
+	lastBlock     []byte
+	fileSize      uint64
+	fileBlockSize uint32 // block size the file was uploaded with
+	blockSize     uint32 // size of the current block
+	blockCursor   uint32
+	totalSize     uint64
+	compression   string
+	blockCache    *lru.Cache
 
 	rlBuffer      []byte
 	rlOffset      int
 	rlReadNewLine bool
 }
 
-func (f *File) OpenFileForReading(podFile string) (io.ReadCloser, string, string, error) {
+// OpenFileForReading returns a reader for the file, the blocks are downloaded with ctx
+// as they are read.
+func (f *File) OpenFileForReading(ctx context.Context, podFile string) (io.ReadCloser, string, string, error) {
 	meta := f.GetFromFileMap(podFile)
 	if meta == nil {
 		return nil, "", "", fmt.Errorf("file not found in dfs")
 	}
-
-	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	reader, ref, size, err := f.openMeta(ctx, meta, false)
 	if err != nil {
 		return nil, "", "", err
 	}
-	var fileInode FileINode
-	err = json.Unmarshal(fileInodeBytes, &fileInode)
-	if err != nil {
-		return nil, "", "", err
-	}
-
-	reader := NewReader(fileInode, f.getClient(), meta.FileSize, meta.BlockSize, meta.Compression, false)
-	ref := swarm.NewAddress(meta.InodeAddress).String()
-	size := strconv.FormatUint(meta.FileSize, 10)
 	return reader, ref, size, nil
 }
 
-func (f *File) OpenFileForIndex(podFile string) (*Reader, string, string, error) {
+func (f *File) OpenFileForIndex(ctx context.Context, podFile string) (*Reader, string, string, error) {
 	meta := f.GetFromFileMap(podFile)
 	if meta == nil {
 		return nil, "", "", fmt.Errorf("file not found in dfs")
 	}
+	return f.openMeta(ctx, meta, true)
+}
 
-	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+// openMeta returns a reader for the file with the given meta, along with the
+// reference of its inode and its size.
+func (f *File) openMeta(ctx context.Context, meta *m.FileMetaData, cache bool) (*Reader, string, string, error) {
+	fileInodeBytes, _, err := f.getClient().DownloadBlobContext(ctx, meta.InodeAddress)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -98,23 +102,39 @@ func (f *File) OpenFileForIndex(podFile string) (*Reader, string, string, error)
 		return nil, "", "", err
 	}
 
-	reader := NewReader(fileInode, f.getClient(), meta.FileSize, meta.BlockSize, meta.Compression, true)
+	reader := NewReaderWithContext(ctx, fileInode, f.getClient(), meta.FileSize, meta.BlockSize, meta.Compression, cache)
 	ref := swarm.NewAddress(meta.InodeAddress).String()
 	size := strconv.FormatUint(meta.FileSize, 10)
 	return reader, ref, size, nil
 }
 
 func NewReader(fileInode FileINode, client blockstore.Client, fileSize uint64, blockSize uint32, compression string, cache bool) *Reader {
+	return NewReaderWithContext(context.Background(), fileInode, client, fileSize, blockSize, compression, cache)
+}
+
+// NewReaderWithContext creates a Reader whose block downloads are bound to ctx, a
+// cancelled ctx fails the next Read or Seek which needs a new block.
+func NewReaderWithContext(ctx context.Context, fileInode FileINode, client blockstore.Client, fileSize uint64, blockSize uint32, compression string, cache bool) *Reader {
 	var blockCache *lru.Cache
 	if cache {
 		blockCache, _ = lru.New(blockCacheSize)
 	}
 
+	blockOffsets := make([]int64, len(fileInode.FileBlocks))
+	offset := int64(0)
+	for i, block := range fileInode.FileBlocks {
+		blockOffsets[i] = offset
+		offset += int64(block.Size)
+	}
+
 	r := &Reader{
+		ctx:           ctx,
 		fileInode:     fileInode,
-		client:        client,
+		blockOffsets:  blockOffsets,
+		client:        blockstore.ToV2(client),
 		fileC:         make(chan []byte),
 		fileSize:      fileSize,
+		fileBlockSize: blockSize,
 		blockSize:     blockSize,
 		compression:   compression,
 		blockCache:    blockCache,
@@ -127,125 +147,74 @@ func (r *Reader) Read(b []byte) (n int, err error) {
 	if r.totalSize >= r.fileSize {
 		return 0, io.EOF
 	}
-	bytesToRead := uint32(len(b))
-	bytesRead := 0
-	if r.lastBlock != nil {
-		remDataSize := r.blockSize - r.blockCursor
-		if bytesToRead <= remDataSize {
-			copy(b, r.lastBlock[r.blockCursor:r.blockCursor+bytesToRead])
-			r.blockCursor += bytesToRead
-			r.readOffset += int64(bytesToRead)
-			bytesRead = int(bytesToRead)
-			//bytesToRead = 0
-			if r.blockCursor == r.blockSize {
-				r.lastBlock = nil
-				r.blockCursor = 0
-			}
-			r.totalSize += uint64(bytesToRead)
-			return bytesRead, nil
-		} else {
-			copy(b, r.lastBlock[r.blockCursor:r.blockSize])
-			r.lastBlock = nil
-			r.blockCursor = 0
-			r.readOffset += int64(remDataSize)
-			bytesRead += int(remDataSize)
-			bytesToRead -= remDataSize
-			r.totalSize += uint64(remDataSize)
-
-			// this situation comes when the block ends
-			if r.totalSize >= r.fileSize {
-				return bytesRead, io.EOF
-			}
-
-			// read spans across block.. so flow down and read the next block
-		}
-	}
 
-	if r.lastBlock == nil {
-		noOfBlocks := int((bytesToRead / r.blockSize) + 1)
-		for i := 0; i < noOfBlocks; i++ {
-			if r.lastBlock == nil {
-				blockIndex := (r.readOffset / int64(r.blockSize))
-				if blockIndex > int64(len(r.fileInode.FileBlocks)) {
-					return bytesRead, io.EOF
-				}
-				if blockIndex >= int64(len(r.fileInode.FileBlocks)) {
-					return bytesRead, io.EOF
-				}
-				r.lastBlock, err = r.getBlock(r.fileInode.FileBlocks[blockIndex].Address, r.compression, r.blockSize)
-				if err != nil {
-					return bytesRead, err
-				}
-				r.blockSize = uint32(len(r.lastBlock))
+	for n < len(b) && r.totalSize < r.fileSize {
+		// load the block of the offset, after a seek the offset can be in the middle of it
+		if r.lastBlock == nil {
+			blockIndex := r.blockIndex(r.readOffset)
+			if blockIndex < 0 || blockIndex >= len(r.fileInode.FileBlocks) {
+				break
 			}
-
-			//if length of bytes to read is greater than block size
-			if bytesToRead > r.blockSize {
-				bytesToRead = r.blockSize
+			r.lastBlock, err = r.getBlock(r.fileInode.FileBlocks[blockIndex], r.compression, r.fileBlockSize)
+			if err != nil {
+				return n, err
 			}
-
-			if uint32(len(r.lastBlock)) < bytesToRead {
-				bytesToRead = uint32(len(r.lastBlock))
+			r.blockSize = uint32(len(r.lastBlock))
+			r.blockCursor = uint32(r.readOffset - r.blockOffsets[blockIndex])
+			if r.blockCursor >= r.blockSize {
+				return n, ErrInvalidBlock
 			}
+		}
 
-			cursor := uint32(bytesRead)
-			copy(b[cursor:cursor+bytesToRead], r.lastBlock[:bytesToRead])
-			r.totalSize += uint64(bytesToRead)
-			if bytesToRead == r.blockSize {
-				r.lastBlock = nil
-				r.blockCursor = 0
-			} else {
-				r.blockCursor += bytesToRead
-			}
-			r.readOffset += int64(bytesToRead)
-			bytesRead += int(bytesToRead)
-			bytesToRead -= bytesToRead
-
-			if bytesToRead <= 0 {
-				return bytesRead, nil
-			}
+		copied := copy(b[n:], r.lastBlock[r.blockCursor:])
+		n += copied
+		r.blockCursor += uint32(copied)
+		r.readOffset += int64(copied)
+		r.totalSize += uint64(copied)
+		if r.blockCursor == r.blockSize {
+			r.lastBlock = nil
+			r.blockCursor = 0
 		}
 	}
-	return 0, nil
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// blockIndex returns the index of the block with the byte at offset. Blocks can have
+// different sizes, so the block is found from the offsets where the blocks start.
+func (r *Reader) blockIndex(offset int64) int {
+	return sort.Search(len(r.blockOffsets), func(i int) bool {
+		return r.blockOffsets[i] > offset
+	}) - 1
 }
 
-func (r *Reader) Seek(seekOffset int64, whence int) (int64, error) {
-	// TODO: use whence
-	if seekOffset < 0 || seekOffset > int64(r.fileSize) {
+// Seek sets the offset of the next Read, interpreted according to whence. The block
+// of the new offset is downloaded by the next Read, so seeking itself is cheap and
+// seeking to the end of the file makes the next Read return io.EOF.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.readOffset
+	case io.SeekEnd:
+		offset += int64(r.fileSize)
+	default:
 		return 0, ErrInvalidOffset
 	}
-
-	// seek to start if offset is zero
-	if seekOffset == 0 {
-		blockData, err := r.getBlock(r.fileInode.FileBlocks[0].Address, r.compression, r.blockSize)
-		if err != nil {
-			return 0, err
-		}
-		r.lastBlock = blockData
-		r.blockCursor = 0
-		r.readOffset = 0
-		r.blockSize = uint32(len(r.lastBlock))
-		r.totalSize = 0
-		r.rlBuffer = nil
-		r.rlOffset = 0
-		return 0, nil
+	if offset < 0 || offset > int64(r.fileSize) {
+		return 0, ErrInvalidOffset
 	}
 
-	blockIndex := seekOffset / int64(r.blockSize)
-	blockOffset := seekOffset % int64(r.blockSize)
-
-	blockData, err := r.getBlock(r.fileInode.FileBlocks[blockIndex].Address, r.compression, r.blockSize)
-	if err != nil {
-		return 0, err
-	}
-	r.lastBlock = blockData
-	r.blockCursor = uint32(blockOffset)
-	r.readOffset = seekOffset
-	r.blockSize = uint32(len(r.lastBlock))
-	r.totalSize = uint64(seekOffset)
+	r.lastBlock = nil
+	r.blockCursor = 0
+	r.blockSize = r.fileBlockSize
+	r.readOffset = offset
+	r.totalSize = uint64(offset)
 	r.rlBuffer = nil
 	r.rlOffset = 0
-	return seekOffset, nil
+	return offset, nil
 }
 
 func (r *Reader) ReadLine() ([]byte, error) {
@@ -253,16 +222,8 @@ func (r *Reader) ReadLine() ([]byte, error) {
 		buf := make([]byte, r.blockSize)
 		n, err := r.Read(buf)
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				if buf[n-1] != '\n' {
-					return nil, err
-				} else {
-					goto SUCC
-				}
-			}
 			return nil, err
 		}
-	SUCC:
 		r.rlBuffer = buf[:n]
 		r.rlOffset = 0
 	}
@@ -294,16 +255,16 @@ READ:
 
 	// check if the newline is crossing the read buffer boundary
 	if !foundNewLine {
-		destBuf = append(destBuf, r.rlBuffer[readOffset:r.blockSize]...)
+		destBuf = append(destBuf, r.rlBuffer[readOffset:]...)
 		if r.totalSize == r.fileSize {
 			return destBuf, io.EOF
 		}
 		buf := make([]byte, r.blockSize)
-		_, err := r.Read(buf)
+		n, err := r.Read(buf)
 		if err != nil {
 			return nil, err
 		}
-		r.rlBuffer = buf
+		r.rlBuffer = buf[:n]
 		r.rlOffset = 0
 		goto READ
 	}
@@ -320,21 +281,26 @@ func (r *Reader) Close() error {
 	return nil
 }
 
-func (r *Reader) getBlock(ref []byte, compression string, blockSize uint32) ([]byte, error) {
-	refStr := utils.NewReference(ref).String()
+// getBlock downloads and decompresses a block, blocks with a checksum are verified
+// against it.
+func (r *Reader) getBlock(block *FileBlock, compression string, blockSize uint32) ([]byte, error) {
+	refStr := utils.NewReference(block.Address).String()
 	if r.blockCache != nil {
 		if data, found := r.blockCache.Get(refStr); found {
 			return data.([]byte), nil
 		}
 	}
-	stdoutBytes, _, err := r.client.DownloadBlob(ref)
+	stdoutBytes, err := downloadBlock(r.ctx, r.client, block)
 	if err != nil {
 		return nil, err
 	}
-	decompressedData, err := Decompress(stdoutBytes, compression, blockSize)
+	decompressedData, err := Decompress(stdoutBytes, blockCompression(block, compression), blockSize)
 	if err != nil {
 		return nil, err
 	}
+	if !checkBlock(block, decompressedData) {
+		return nil, ErrChecksumMismatch
+	}
 	if r.blockCache != nil {
 		r.blockCache.Add(refStr, decompressedData)
 	}
@@ -342,7 +308,11 @@ func (r *Reader) getBlock(ref []byte, compression string, blockSize uint32) ([]b
 }
 
 func Decompress(dataToDecompress []byte, compression string, blockSize uint32) ([]byte, error) {
-	switch compression {
+	codec, _, err := ParseCompression(compression)
+	if err != nil {
+		return dataToDecompress, nil
+	}
+	switch codec {
 	case "gzip":
 		br := bytes.NewReader(dataToDecompress)
 		block := int(blockSize / 10)
@@ -365,6 +335,10 @@ func Decompress(dataToDecompress []byte, compression string, blockSize uint32) (
 			return nil, err
 		}
 		return decoded, nil
+	case "zstd":
+		return zstdDecompress(dataToDecompress)
+	case "lz4":
+		return lz4Decompress(dataToDecompress)
 	}
 	return dataToDecompress, nil
 }
@@ -18,11 +18,18 @@ package file_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
@@ -101,6 +108,54 @@ func TestFileReader(t *testing.T) {
 		}
 	})
 
+	t.Run("read-zstd-file", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+		compression := "zstd"
+		fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false)
+		outputBytes := readFileContents(t, fileSize, reader)
+		if !checkFileContents(t, fileInode, outputBytes, mockClient, compression) {
+			t.Fatalf("file contents are not same")
+		}
+	})
+
+	t.Run("read-zstd-file-with-level", func(t *testing.T) {
+		fileSize := uint64(93)
+		blockSize := uint32(10)
+		compression := "zstd:19"
+		fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false)
+		outputBytes := readFileContents(t, fileSize, reader)
+		if !checkFileContents(t, fileInode, outputBytes, mockClient, compression) {
+			t.Fatalf("file contents are not same")
+		}
+	})
+
+	t.Run("read-lz4-file", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+		compression := "lz4"
+		fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false)
+		outputBytes := readFileContents(t, fileSize, reader)
+		if !checkFileContents(t, fileInode, outputBytes, mockClient, compression) {
+			t.Fatalf("file contents are not same")
+		}
+	})
+
+	t.Run("read-lz4-file-with-level", func(t *testing.T) {
+		fileSize := uint64(93)
+		blockSize := uint32(10)
+		compression := "lz4:9"
+		fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false)
+		outputBytes := readFileContents(t, fileSize, reader)
+		if !checkFileContents(t, fileInode, outputBytes, mockClient, compression) {
+			t.Fatalf("file contents are not same")
+		}
+	})
+
 	t.Run("read-lines", func(t *testing.T) {
 		fileSize := uint64(100)
 		blockSize := uint32(10)
@@ -243,6 +298,175 @@ func TestFileReader(t *testing.T) {
 			t.Fatalf("line contents are not same")
 		}
 	})
+
+	t.Run("read-with-cancelled-context", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+		fileInode := createFile(t, fileSize, blockSize, "", mockClient)
+		ctx, cancel := context.WithCancel(context.Background())
+		reader := file.NewReaderWithContext(ctx, fileInode, mockClient, fileSize, blockSize, "", false)
+		buf := make([]byte, blockSize)
+		_, err := reader.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cancel()
+		_, err = reader.Read(buf)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("read-streams-blocks", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+		fileInode := createFile(t, fileSize, blockSize, "", mockClient)
+		client := &streamOnlyClient{MockBeeClient: mockClient}
+		reader := file.NewReader(fileInode, client, fileSize, blockSize, "", false)
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != int(fileSize) {
+			t.Fatalf("read %d bytes", len(data))
+		}
+
+		// a blob which is not the size of the block is not the block
+		fileInode.FileBlocks[1].CompressedSize++
+		reader = file.NewReader(fileInode, client, fileSize, blockSize, "", false)
+		_, err = ioutil.ReadAll(reader)
+		if !errors.Is(err, file.ErrInvalidBlock) {
+			t.Fatalf("expected %v, got %v", file.ErrInvalidBlock, err)
+		}
+	})
+
+	t.Run("seek-whence", func(t *testing.T) {
+		fileSize := uint64(93)
+		blockSize := uint32(10)
+		compression := "snappy"
+		fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		content := readFileContents(t, fileSize, file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false))
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false)
+
+		offset, err := reader.Seek(-15, io.SeekEnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != 78 {
+			t.Fatalf("expected offset 78, got %d", offset)
+		}
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(reader, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, content[78:82]) {
+			t.Fatalf("contents after seek from end are not same")
+		}
+
+		offset, err = reader.Seek(-30, io.SeekCurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != 52 {
+			t.Fatalf("expected offset 52, got %d", offset)
+		}
+		rest, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rest, content[52:]) {
+			t.Fatalf("contents after seek from current are not same")
+		}
+
+		_, err = reader.Seek(int64(fileSize), io.SeekStart)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = reader.Read(buf)
+		if err != io.EOF {
+			t.Fatalf("expected EOF at the end of the file, got %v", err)
+		}
+
+		_, err = reader.Seek(1, io.SeekEnd)
+		if !errors.Is(err, file.ErrInvalidOffset) {
+			t.Fatalf("expected invalid offset, got %v", err)
+		}
+		_, err = reader.Seek(0, 3)
+		if !errors.Is(err, file.ErrInvalidOffset) {
+			t.Fatalf("expected invalid offset for whence, got %v", err)
+		}
+	})
+
+	t.Run("serve-ranges", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+		fileInode := createFile(t, fileSize, blockSize, "", mockClient)
+		content := readFileContents(t, fileSize, file.NewReader(fileInode, mockClient, fileSize, blockSize, "", false))
+
+		serve := func(rangeHeader string) *httptest.ResponseRecorder {
+			reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, "", false)
+			req := httptest.NewRequest(http.MethodGet, "/file/download", nil)
+			req.Header.Set("Range", rangeHeader)
+			w := httptest.NewRecorder()
+			http.ServeContent(w, req, "file", time.Time{}, reader)
+			return w
+		}
+
+		w := serve("bytes=25-44")
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		if w.Header().Get("Content-Range") != "bytes 25-44/100" {
+			t.Fatalf("invalid content range %q", w.Header().Get("Content-Range"))
+		}
+		if !bytes.Equal(w.Body.Bytes(), content[25:45]) {
+			t.Fatalf("range contents are not same")
+		}
+
+		w = serve("bytes=-7")
+		if w.Code != http.StatusPartialContent || !bytes.Equal(w.Body.Bytes(), content[93:]) {
+			t.Fatalf("suffix range contents are not same")
+		}
+
+		w = serve("bytes=0-4,50-59")
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		_, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mr := multipart.NewReader(w.Body, params["boundary"])
+		for _, want := range [][]byte{content[0:5], content[50:60]} {
+			part, err := mr.NextPart()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("multi range contents are not same")
+			}
+		}
+
+		w = serve("bytes=100-")
+		if w.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("expected 416, got %d", w.Code)
+		}
+	})
+}
+
+// streamOnlyClient only downloads blobs as streams
+type streamOnlyClient struct {
+	*mock.MockBeeClient
+}
+
+func (c *streamOnlyClient) DownloadBlobContext(ctx context.Context, address []byte) ([]byte, int, error) {
+	return nil, http.StatusInternalServerError, errors.New("blob not streamed")
 }
 
 func createFile(t *testing.T, fileSize uint64, blockSize uint32, compression string, mockClient *mock.MockBeeClient) file.FileINode {
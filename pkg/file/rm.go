@@ -0,0 +1,116 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// DeleteFile deletes the blocks, the inode and then the meta of the file whose meta
+// is at metaReference and drops the file from the file map. Blobs which are already
+// gone are skipped, so an interrupted delete can be run again. The inode and blocks
+// of a file sharing them with copies are left to the garbage collector, as are the
+// older versions of the file, blocks chunked by content, which other files can have
+// too, and every blob of a pod which keeps them. A meta written for another directory
+// than dirPath, like the meta of a file received from another user, was not made by
+// this pod, so the file is only dropped from the file map. It returns the path of the
+// deleted file.
+func (f *File) DeleteFile(dirPath string, metaReference []byte) (string, error) {
+	metaBytes, respCode, err := f.getClient().DownloadBlob(metaReference)
+	if err != nil {
+		return "", err
+	}
+	if respCode != http.StatusOK {
+		return "", fmt.Errorf("file meta %x not found", metaReference)
+	}
+	var meta m.FileMetaData
+	err = json.Unmarshal(metaBytes, &meta)
+	if err != nil {
+		return "", err
+	}
+	path := dirPath + utils.PathSeperator + meta.Name
+	if meta.Path != dirPath {
+		f.RemoveFromFileMap(path)
+		return path, nil
+	}
+
+	if !meta.SharedInode && !f.IsKeepingBlobs() {
+		err = f.deleteInode(meta.InodeAddress)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = f.deleteBlob(metaReference)
+	if err != nil {
+		return "", err
+	}
+	if f.IsTrackingVersions() {
+		err = f.DropVersions(path)
+		if err != nil {
+			return "", err
+		}
+	}
+	f.RemoveFromFileMap(path)
+	return path, nil
+}
+
+// deleteInode deletes the blocks and then the inode at inodeAddress. An inode which is
+// not found was deleted by an earlier, interrupted delete.
+func (f *File) deleteInode(inodeAddress []byte) error {
+	fileInodeBytes, respCode, err := f.getClient().DownloadBlob(inodeAddress)
+	if err != nil && respCode != http.StatusNotFound {
+		return err
+	}
+	if respCode != http.StatusOK {
+		return nil
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil {
+		return err
+	}
+	for _, b := range fileInode.FileBlocks {
+		// blocks encrypted with a key of their content can be in other files of the pod
+		if len(b.Key) > 0 {
+			continue
+		}
+		err = f.deleteBlob(b.Address)
+		if err != nil {
+			return err
+		}
+	}
+	return f.deleteBlob(inodeAddress)
+}
+
+func (f *File) deleteBlob(address []byte) error {
+	if f.IsKeepingBlobs() {
+		return nil
+	}
+	err := f.getClient().DeleteBlob(address)
+	if err != nil && !errors.Is(err, blockstore.ErrBlobNotFound) {
+		return err
+	}
+	return nil
+}
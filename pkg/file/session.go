@@ -0,0 +1,301 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	ErrInvalidFileSize       = errors.New("invalid file size")
+	ErrInvalidBlockSize      = errors.New("invalid block size")
+	ErrInvalidBlockIndex     = errors.New("invalid block index")
+	ErrInvalidBlockLength    = errors.New("invalid block length")
+	ErrChecksumMismatch      = errors.New("block checksum mismatch")
+	ErrMissingBlocks         = errors.New("blocks of the upload are missing")
+	ErrTooManyBlocks         = errors.New("too many blocks for an upload session")
+)
+
+const (
+	uploadSessionIdLength = 16
+
+	MinBlockSize = 100      // smallest block size files are uploaded with
+	MaxBlockSize = 64 << 20 // largest block size, every block is held in memory while it is uploaded
+
+	// MaxUploadSessionBlocks is the number of blocks a file uploaded in a session can have
+	// at most, the status of a session lists the ones which are missing.
+	MaxUploadSessionBlocks = 1 << 20
+
+	// UploadSessionTTL is how long a session is kept after its last block arrived.
+	// Expired sessions are removed and their blocks left to the garbage collector.
+	UploadSessionTTL = 7 * 24 * time.Hour
+)
+
+// CheckBlockSize tells if files can be uploaded with the given block size.
+func CheckBlockSize(blockSize uint64) error {
+	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
+		return ErrInvalidBlockSize
+	}
+	return nil
+}
+
+// UploadSession is the upload of a file in blocks. The blocks can be sent in any
+// order and over many requests, every block that arrived is kept in the session till
+// the file is finished with all of them.
+type UploadSession struct {
+	Id           string             `json:"id"`
+	PodName      string             `json:"podName"`
+	FilePath     string             `json:"filePath"` // full path of the file in the pod
+	FileSize     uint64             `json:"fileSize"`
+	BlockSize    uint32             `json:"blockSize"`
+	Compression  string             `json:"compression"`
+	CreationTime int64              `json:"creationTime"`
+	UpdateTime   int64              `json:"updateTime"` // when the last block arrived
+	Blocks       map[int]*FileBlock `json:"blocks"`     // blocks uploaded to the blockstore so far
+}
+
+func NewUploadSession(podName, filePath string, fileSize uint64, blockSize uint32, compression string) (*UploadSession, error) {
+	err := CheckBlockSize(uint64(blockSize))
+	if err != nil {
+		return nil, err
+	}
+	if (fileSize+uint64(blockSize)-1)/uint64(blockSize) > MaxUploadSessionBlocks {
+		return nil, ErrTooManyBlocks
+	}
+	id := make([]byte, uploadSessionIdLength)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	return &UploadSession{
+		Id:           hex.EncodeToString(id),
+		PodName:      podName,
+		FilePath:     filePath,
+		FileSize:     fileSize,
+		BlockSize:    blockSize,
+		Compression:  compression,
+		CreationTime: now,
+		UpdateTime:   now,
+		Blocks:       make(map[int]*FileBlock),
+	}, nil
+}
+
+// NoOfBlocks returns the number of blocks the file is split in to.
+func (s *UploadSession) NoOfBlocks() int {
+	n := s.FileSize / uint64(s.BlockSize)
+	if s.FileSize%uint64(s.BlockSize) != 0 {
+		n++
+	}
+	return int(n)
+}
+
+// blockLength returns the length of the block with the given index, only the last
+// block can be shorter than the block size.
+func (s *UploadSession) blockLength(index int) uint32 {
+	if index == s.NoOfBlocks()-1 && s.FileSize%uint64(s.BlockSize) != 0 {
+		return uint32(s.FileSize % uint64(s.BlockSize))
+	}
+	return s.BlockSize
+}
+
+// Expired tells if the session has not been used for longer than UploadSessionTTL.
+func (s *UploadSession) Expired() bool {
+	last := s.UpdateTime
+	if last == 0 {
+		last = s.CreationTime
+	}
+	return time.Since(time.Unix(last, 0)) > UploadSessionTTL
+}
+
+// MissingBlocks returns the indexes of the blocks which have not arrived yet.
+func (s *UploadSession) MissingBlocks() []int {
+	var missing []int
+	for i := 0; i < s.NoOfBlocks(); i++ {
+		if _, ok := s.Blocks[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// SessionStore keeps the upload sessions of every user on disk, in one file
+// "<dir>/<user name>/<session id>" per session, so that an upload can be continued
+// after the server is restarted.
+type SessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewSessionStore(dir string) *SessionStore {
+	return &SessionStore{
+		dir: dir,
+	}
+}
+
+func (s *SessionStore) path(userName, id string) string {
+	return filepath.Join(s.dir, userName, id)
+}
+
+// isSessionId tells if id can be the id of a session, anything else is never used as
+// a file name.
+func isSessionId(id string) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == uploadSessionIdLength
+}
+
+// Save stores the session for the user.
+func (s *SessionStore) Save(userName string, session *UploadSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(userName, session)
+}
+
+func (s *SessionStore) save(userName string, session *UploadSession) error {
+	err := os.MkdirAll(filepath.Join(s.dir, userName), 0700)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	// write aside and rename, so that a crash never leaves a half written session
+	tmp := s.path(userName, session.Id) + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(userName, session.Id))
+}
+
+// Load returns the session of the user with the given id.
+func (s *SessionStore) Load(userName, id string) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(userName, id)
+}
+
+func (s *SessionStore) load(userName, id string) (*UploadSession, error) {
+	if !isSessionId(id) {
+		return nil, ErrUploadSessionNotFound
+	}
+	data, err := ioutil.ReadFile(s.path(userName, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUploadSessionNotFound
+		}
+		return nil, err
+	}
+	var session UploadSession
+	err = json.Unmarshal(data, &session)
+	if err != nil {
+		return nil, err
+	}
+	if session.Expired() {
+		_ = os.Remove(s.path(userName, id))
+		return nil, ErrUploadSessionNotFound
+	}
+	if session.Blocks == nil {
+		session.Blocks = make(map[int]*FileBlock)
+	}
+	return &session, nil
+}
+
+// Update loads the session, changes it with update and stores it again. Sessions are
+// updated one at a time, so blocks which arrive together are not lost.
+func (s *SessionStore) Update(userName, id string, update func(session *UploadSession) error) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, err := s.load(userName, id)
+	if err != nil {
+		return nil, err
+	}
+	err = update(session)
+	if err != nil {
+		return nil, err
+	}
+	session.UpdateTime = time.Now().Unix()
+	err = s.save(userName, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Delete removes the session of the user with the given id.
+func (s *SessionStore) Delete(userName, id string) error {
+	if !isSessionId(id) {
+		return ErrUploadSessionNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.path(userName, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrUploadSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Sessions returns all the sessions of the user which have not expired, the expired
+// ones are removed.
+func (s *SessionStore) Sessions(userName string) ([]*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, userName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []*UploadSession
+	for _, f := range files {
+		if f.IsDir() || !isSessionId(f.Name()) {
+			continue
+		}
+		session, err := s.load(userName, f.Name())
+		if err != nil {
+			if err == ErrUploadSessionNotFound {
+				continue // expired
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// Drop removes all the sessions of the user.
+func (s *SessionStore) Drop(userName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.RemoveAll(filepath.Join(s.dir, userName))
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+)
+
+func TestSessionStore(t *testing.T) {
+	dir := t.TempDir()
+	store := file.NewSessionStore(dir)
+	session, err := file.NewUploadSession("pod1", "/pod1/file1", 250, 100, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("save-and-load", func(t *testing.T) {
+		err := store.Save("user1", session)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Load("user1", session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.FilePath != session.FilePath || got.FileSize != 250 || got.NoOfBlocks() != 3 {
+			t.Fatalf("loaded session differs")
+		}
+		_, err = store.Load("user2", session.Id)
+		if !errors.Is(err, file.ErrUploadSessionNotFound) {
+			t.Fatalf("expected session not found, got %v", err)
+		}
+		_, err = store.Load("user1", "../user2")
+		if !errors.Is(err, file.ErrUploadSessionNotFound) {
+			t.Fatalf("expected session not found, got %v", err)
+		}
+	})
+
+	t.Run("survives-restart", func(t *testing.T) {
+		_, err := store.Update("user1", session.Id, func(s *file.UploadSession) error {
+			s.Blocks[1] = &file.FileBlock{Checksum: []byte{0xab, 0xcd}}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a new store on the same directory sees the sessions of the old one
+		restarted := file.NewSessionStore(dir)
+		sessions, err := restarted.Sessions("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 1 || sessions[0].Id != session.Id {
+			t.Fatalf("expected the session, got %v", sessions)
+		}
+		missing := sessions[0].MissingBlocks()
+		if len(missing) != 2 || missing[0] != 0 || missing[1] != 2 {
+			t.Fatalf("unexpected missing blocks %v", missing)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		_, err := file.NewUploadSession("pod1", "/pod1/file2", 250, 10, "")
+		if !errors.Is(err, file.ErrInvalidBlockSize) {
+			t.Fatalf("expected invalid block size, got %v", err)
+		}
+		_, err = file.NewUploadSession("pod1", "/pod1/file2", 1<<40, file.MinBlockSize, "")
+		if !errors.Is(err, file.ErrTooManyBlocks) {
+			t.Fatalf("expected too many blocks, got %v", err)
+		}
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		old, err := file.NewUploadSession("pod1", "/pod1/file3", 250, 100, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		old.UpdateTime = time.Now().Add(-file.UploadSessionTTL - time.Hour).Unix()
+		err = store.Save("user1", old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions, err := store.Sessions("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 1 || sessions[0].Id != session.Id {
+			t.Fatalf("expired session listed")
+		}
+		_, err = store.Load("user1", old.Id)
+		if !errors.Is(err, file.ErrUploadSessionNotFound) {
+			t.Fatalf("expected session not found, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := store.Delete("user1", session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Delete("user1", session.Id)
+		if !errors.Is(err, file.ErrUploadSessionNotFound) {
+			t.Fatalf("expected session not found, got %v", err)
+		}
+		sessions, err := store.Sessions("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 0 {
+			t.Fatalf("session not deleted")
+		}
+	})
+}
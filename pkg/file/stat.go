@@ -31,10 +31,13 @@ type FileStats struct {
 	FileSize         string `json:"file_size"`
 	BlockSize        string `json:"block_size"`
 	Compression      string `json:"compression"`
+	Chunking         string `json:"chunking,omitempty"`
 	ContentType      string `json:"content_type"`
 	CreationTime     string `json:"creation_time"`
 	ModificationTime string `json:"modification_time"`
 	AccessTime       string `json:"access_time"`
+	DedupRatio       string `json:"dedup_ratio"`        // bytes of the file and its versions over the bytes stored for them
+	Checksum         string `json:"checksum,omitempty"` // hex encoded sha256 of the file contents
 	Blocks           []Blocks
 }
 
@@ -43,6 +46,7 @@ type Blocks struct {
 	Reference      string `json:"reference"`
 	Size           string `json:"size"`
 	CompressedSize string `json:"compressed_size"`
+	Compression    string `json:"compression,omitempty"`
 }
 
 func (f *File) FileStat(podName, fileName, account string) (*FileStats, error) {
@@ -61,6 +65,44 @@ func (f *File) FileStat(podName, fileName, account string) (*FileStats, error) {
 		return nil, err
 	}
 
+	// blocks with the same content share the reference, so they are stored once, also
+	// when an older version of the file has them
+	var totalSize, storedSize uint64
+	stored := make(map[string]bool)
+	count := func(blocks []*FileBlock) {
+		for _, b := range blocks {
+			totalSize += uint64(b.Size)
+			if ref := hex.EncodeToString(b.Address); !stored[ref] {
+				stored[ref] = true
+				storedSize += uint64(b.Size)
+			}
+		}
+	}
+	count(fileInode.FileBlocks)
+	versions, _, err := f.loadVersions(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if string(v.MetaReference) == string(meta.MetaReference) {
+			continue
+		}
+		versionMeta, err := f.downloadMeta(v.MetaReference)
+		if err != nil {
+			return nil, err
+		}
+		versionInodeBytes, _, err := f.getClient().DownloadBlob(versionMeta.InodeAddress)
+		if err != nil {
+			return nil, err
+		}
+		var versionInode FileINode
+		err = json.Unmarshal(versionInodeBytes, &versionInode)
+		if err != nil {
+			return nil, err
+		}
+		count(versionInode.FileBlocks)
+	}
+
 	var fileBlocks []Blocks
 	for _, b := range fileInode.FileBlocks {
 		fb := Blocks{
@@ -68,9 +110,14 @@ func (f *File) FileStat(podName, fileName, account string) (*FileStats, error) {
 			Reference:      hex.EncodeToString(b.Address),
 			Size:           strconv.Itoa(int(b.Size)),
 			CompressedSize: strconv.Itoa(int(b.CompressedSize)),
+			Compression:    b.Compression,
 		}
 		fileBlocks = append(fileBlocks, fb)
 	}
+	dedupRatio := 1.0
+	if storedSize > 0 {
+		dedupRatio = float64(totalSize) / float64(storedSize)
+	}
 	return &FileStats{
 		Account:          account,
 		PodName:          podName,
@@ -79,10 +126,13 @@ func (f *File) FileStat(podName, fileName, account string) (*FileStats, error) {
 		FileSize:         strconv.FormatUint(meta.FileSize, 10),
 		BlockSize:        strconv.Itoa(int(meta.BlockSize)),
 		Compression:      meta.Compression,
+		Chunking:         meta.Chunking,
 		ContentType:      meta.ContentType,
 		CreationTime:     strconv.FormatInt(meta.CreationTime, 10),
 		ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
 		AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
+		DedupRatio:       strconv.FormatFloat(dedupRatio, 'f', 2, 64),
+		Checksum:         hex.EncodeToString(meta.Checksum),
 		Blocks:           fileBlocks,
 	}, nil
 }
@@ -19,25 +19,36 @@ package file
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
 	"github.com/golang/snappy"
-	"github.com/klauspost/pgzip"
 )
 
 var (
 	NoOfParallelWorkers = runtime.NumCPU()
 )
 
-func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize uint32, filePath, compression string) ([]byte, error) {
+// UnknownFileSize is the size given to Upload for data whose length is not known
+// before all of it is read.
+const UnknownFileSize int64 = -1
+
+// Upload splits the data from fd in to blocks and uploads them in parallel, stopping
+// when ctx is cancelled or a block fails. With ChunkingCDC the blocks are content
+// defined and stored once across uploads of the pod. A fileSize of UnknownFileSize
+// reads the data till EOF.
+func (f *File) Upload(ctx context.Context, fd io.Reader, fileName string, fileSize int64, blockSize uint32, filePath, compression, chunking string) ([]byte, error) {
 	reader := bufio.NewReader(fd)
 	now := time.Now().Unix()
 	meta := m.FileMetaData{
@@ -54,30 +65,69 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 
 	fileINode := FileINode{}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var totalLength uint64
 	i := 0
-	errC := make(chan error)
-	doneC := make(chan bool)
+	errC := make(chan error, 1)
 	worker := make(chan bool, NoOfParallelWorkers)
 	var wg sync.WaitGroup
 	refMap := make(map[int]*FileBlock)
 	refMapMu := sync.RWMutex{}
 	var contentBytes []byte
-	for {
-		data := make([]byte, blockSize, blockSize+1024)
-		r, err := reader.Read(data)
+
+	var chunker *Chunker
+	if chunking == ChunkingCDC {
+		meta.Chunking = ChunkingCDC
+		meta.MinBlockSize, meta.MaxBlockSize = CDCBlockSizes(blockSize)
+		chunker = NewChunker(reader, meta.MinBlockSize, blockSize, meta.MaxBlockSize)
+	}
+	// the index of the first block with the given content, and of the blocks which
+	// repeat an earlier one
+	firstBlocks := make(map[[sha256.Size]byte]int)
+	duplicates := make(map[int]int)
+	fileHash := sha256.New()
+
+	// the first error cancels the rest of the upload
+	fail := func(err error) {
+		select {
+		case errC <- err:
+		default:
+		}
+		cancel()
+	}
+	for ctx.Err() == nil {
+		var data []byte
+		var r int
+		var err error
+		if chunker != nil {
+			data, err = chunker.Next()
+			r = len(data)
+		} else {
+			// a stream may return less than a block at a time, all blocks but the
+			// last are filled
+			data = make([]byte, blockSize, blockSize+1024)
+			r, err = io.ReadFull(reader, data)
+			if err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+		}
 		totalLength += uint64(r)
 		if err != nil {
 			if err == io.EOF {
-				if totalLength < uint64(fileSize) {
-					return nil, fmt.Errorf("invalid file length of file data received")
+				if fileSize != UnknownFileSize && totalLength < uint64(fileSize) {
+					fail(fmt.Errorf("invalid file length of file data received"))
 				}
 				break
 			} else {
-				return nil, err
+				fail(err)
+				break
 			}
 		}
 
+		fileHash.Write(data[:r])
+
 		// determine the content type from the first 512 bytes of the file
 		if len(contentBytes) < 512 {
 			contentBytes = append(contentBytes, data[:r]...)
@@ -88,9 +138,19 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 			}
 		}
 
+		if chunker != nil {
+			sum := sha256.Sum256(data)
+			if first, ok := firstBlocks[sum]; ok {
+				duplicates[i] = first
+				i++
+				continue
+			}
+			firstBlocks[sum] = i
+		}
+
 		wg.Add(1)
 		worker <- true
-		go func(counter, size int) {
+		go func(counter, size int, contentType string) {
 			blockName := fmt.Sprintf("block-%05d", counter)
 			defer func() {
 				<-worker
@@ -99,18 +159,23 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 			}()
 
 			f.logger.Info("Uploading ", blockName)
+			checksum := sha256.Sum256(data[:size])
+
 			// compress the data
 			uploadData := data[:size]
+			var dataCompression string
 			if compression != "" {
-				uploadData, err = Compress(data[:size], compression, blockSize)
+				var err error
+				uploadData, dataCompression, err = compressBlock(data[:size], compression, blockSize, contentType)
 				if err != nil {
-					errC <- err
+					fail(err)
+					return
 				}
 			}
 
-			addr, err := f.client.UploadBlob(uploadData, true, true)
+			addr, key, err := f.uploadBlock(ctx, uploadData, chunker != nil)
 			if err != nil {
-				errC <- err
+				fail(err)
 				return
 			}
 			fileBlock := &FileBlock{
@@ -118,40 +183,137 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 				Size:           uint32(size),
 				CompressedSize: uint32(len(uploadData)),
 				Address:        addr,
+				Compression:    dataCompression,
+				Checksum:       checksum[:],
+				Key:            key,
 			}
 
 			refMapMu.Lock()
 			defer refMapMu.Unlock()
 			refMap[counter] = fileBlock
-		}(i, r)
+		}(i, r, meta.ContentType)
 
 		i++
 	}
-
-	go func() {
-		wg.Wait()
-		close(doneC)
-	}()
+	wg.Wait()
 
 	select {
-	case <-doneC:
-		break
 	case err := <-errC:
-		close(errC)
 		return nil, err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	meta.Checksum = fileHash.Sum(nil)
+	if fileSize == UnknownFileSize {
+		meta.FileSize = totalLength
+	}
+
+	for i, first := range duplicates {
+		block := *refMap[first]
+		block.Name = fmt.Sprintf("block-%05d", i)
+		refMap[i] = &block
 	}
 
 	// copy the block references to the fileInode
 	for i := 0; i < len(refMap); i++ {
 		fileINode.FileBlocks = append(fileINode.FileBlocks, refMap[i])
 	}
+	return f.uploadInode(ctx, fileINode, &meta, filePath)
+}
+
+// UploadBlock compresses and uploads the block with the given index of an upload
+// session. The checksum is the hex encoded sha256 of the block data, a block which
+// does not match it or has the wrong length is rejected.
+func (f *File) UploadBlock(ctx context.Context, session *UploadSession, index int, data []byte, checksum string) (*FileBlock, error) {
+	if index < 0 || index >= session.NoOfBlocks() {
+		return nil, ErrInvalidBlockIndex
+	}
+	if uint32(len(data)) != session.blockLength(index) {
+		return nil, ErrInvalidBlockLength
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), checksum) {
+		return nil, ErrChecksumMismatch
+	}
+
+	// the content type is only known when the upload finishes, so auto decides on the
+	// size of the compressed block alone
+	uploadData := data
+	var dataCompression string
+	if session.Compression != "" {
+		var err error
+		uploadData, dataCompression, err = compressBlock(data, session.Compression, session.BlockSize, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	addr, err := f.client.UploadBlobContext(ctx, uploadData, true, true)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBlock{
+		Name:           fmt.Sprintf("block-%05d", index),
+		Size:           uint32(len(data)),
+		CompressedSize: uint32(len(uploadData)),
+		Address:        addr,
+		Compression:    dataCompression,
+		Checksum:       sum[:],
+	}, nil
+}
+
+// FinishUpload creates the file of an upload session once all its blocks arrived. The
+// blocks arrive in any order, so the file has no checksum of its own and is verified
+// by the checksums of its blocks.
+func (f *File) FinishUpload(ctx context.Context, session *UploadSession) ([]byte, error) {
+	if len(session.MissingBlocks()) > 0 {
+		return nil, ErrMissingBlocks
+	}
+	now := time.Now().Unix()
+	meta := m.FileMetaData{
+		Version:          m.FileMetaVersion,
+		Path:             filepath.Dir(session.FilePath),
+		Name:             filepath.Base(session.FilePath),
+		FileSize:         session.FileSize,
+		BlockSize:        session.BlockSize,
+		Compression:      session.Compression,
+		CreationTime:     now,
+		AccessTime:       now,
+		ModificationTime: now,
+	}
+
+	fileINode := FileINode{}
+	for i := 0; i < session.NoOfBlocks(); i++ {
+		fileINode.FileBlocks = append(fileINode.FileBlocks, session.Blocks[i])
+	}
+
+	// the blocks are not seen together, so the content type is found from the first one
+	if len(fileINode.FileBlocks) > 0 {
+		first := fileINode.FileBlocks[0]
+		data, err := downloadBlock(ctx, f.client, first)
+		if err != nil {
+			return nil, err
+		}
+		data, err = Decompress(data, blockCompression(first, session.Compression), first.Size)
+		if err != nil {
+			return nil, err
+		}
+		meta.ContentType = f.GetContentType(bufio.NewReader(bytes.NewReader(data)))
+	}
+	return f.uploadInode(ctx, fileINode, &meta, session.FilePath)
+}
 
+// uploadInode uploads the inode with the blocks of the file and the meta pointing to
+// it, and adds the file to the file map.
+func (f *File) uploadInode(ctx context.Context, fileINode FileINode, meta *m.FileMetaData, filePath string) ([]byte, error) {
 	fileInodeData, err := json.Marshal(fileINode)
 	if err != nil {
 		return nil, err
 	}
 
-	addr, err := f.client.UploadBlob(fileInodeData, true, true)
+	addr, err := f.client.UploadBlobContext(ctx, fileInodeData, true, true)
 	if err != nil {
 		return nil, err
 	}
@@ -161,12 +323,12 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 	if err != nil {
 		return nil, err
 	}
-	metaAddr, err := f.client.UploadBlob(fileMetaBytes, true, true)
+	metaAddr, err := f.client.UploadBlobContext(ctx, fileMetaBytes, true, true)
 	if err != nil {
 		return nil, err
 	}
 	meta.MetaReference = metaAddr // the self address is stored to share this file easily
-	f.AddToFileMap(filePath, &meta)
+	f.AddToFileMap(filePath, meta)
 	return metaAddr, nil
 }
 
@@ -179,26 +341,20 @@ func (f *File) GetContentType(bufferReader *bufio.Reader) string {
 }
 
 func Compress(dataToCompress []byte, compression string, blockSize uint32) ([]byte, error) {
-	switch compression {
+	codec, level, err := ParseCompression(compression)
+	if err != nil {
+		// files without compression are uploaded as they are
+		return dataToCompress, nil
+	}
+	switch codec {
 	case "gzip":
-		var b bytes.Buffer
-		w := pgzip.NewWriter(&b)
-		block := int(blockSize / 10)
-		err := w.SetConcurrency(block, 10)
-		if err != nil {
-			return nil, err
-		}
-		_, err = w.Write(dataToCompress)
-		if err != nil {
-			return nil, err
-		}
-		err = w.Close()
-		if err != nil {
-			return nil, err
-		}
-		return b.Bytes(), nil
+		return gzipCompress(dataToCompress, level, blockSize)
 	case "snappy":
 		return snappy.Encode(nil, dataToCompress), nil
+	case "zstd":
+		return zstdCompress(dataToCompress, level)
+	case "lz4":
+		return lz4Compress(dataToCompress, level)
 	}
 	return dataToCompress, nil
 }
@@ -0,0 +1,148 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	usageCacheSize = 100000
+)
+
+// Usage is the storage taken by one or more files. Size counts the bytes of the
+// files and CompressedSize the bytes of their blocks as they are stored.
+type Usage struct {
+	Files          uint64 `json:"files"`
+	Blocks         uint64 `json:"blocks"`
+	Size           uint64 `json:"size"`
+	CompressedSize uint64 `json:"compressed_size"`
+	inode          string // the inode of a single file
+}
+
+// Add adds the usage of other files to u.
+func (u *Usage) Add(other *Usage) {
+	u.Files += other.Files
+	u.Blocks += other.Blocks
+	u.Size += other.Size
+	u.CompressedSize += other.CompressedSize
+}
+
+// UsageCounter adds up the usage of single files. The blocks of an inode shared by
+// copies of a file are stored once, so they are only counted for the first copy.
+type UsageCounter struct {
+	Usage
+	inodes map[string]bool
+}
+
+func NewUsageCounter() *UsageCounter {
+	return &UsageCounter{
+		inodes: make(map[string]bool),
+	}
+}
+
+// Add adds the usage of a file returned by FileUsage or MetaUsage.
+func (c *UsageCounter) Add(file *Usage) {
+	c.Files += file.Files
+	c.Size += file.Size
+	if c.inodes[file.inode] {
+		return
+	}
+	c.inodes[file.inode] = true
+	c.Blocks += file.Blocks
+	c.CompressedSize += file.CompressedSize
+}
+
+// UsageCache keeps the usage of the files looked at last by the reference of their
+// inode. An inode is never changed once uploaded, so the entries never go stale and
+// only the files written since the last time have to be downloaded.
+type UsageCache struct {
+	entries *lru.Cache
+}
+
+func NewUsageCache() *UsageCache {
+	entries, _ := lru.New(usageCacheSize)
+	return &UsageCache{
+		entries: entries,
+	}
+}
+
+func (c *UsageCache) get(inode string) (Usage, bool) {
+	usage, ok := c.entries.Get(inode)
+	if !ok {
+		return Usage{}, false
+	}
+	return usage.(Usage), true
+}
+
+func (c *UsageCache) add(inode string, usage Usage) {
+	c.entries.Add(inode, usage)
+}
+
+// FileUsage returns the usage of a file in the file map. The blocks are read from
+// the inode of the file unless the cache has it already.
+func (f *File) FileUsage(filePath string, cache *UsageCache) (*Usage, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found")
+	}
+	return f.inodeUsage(meta, cache)
+}
+
+// MetaUsage returns the usage of the file whose meta is at metaReference, for files
+// of a pod which is not open.
+func (f *File) MetaUsage(metaReference []byte, cache *UsageCache) (*Usage, error) {
+	meta, err := f.downloadMeta(metaReference)
+	if err != nil {
+		return nil, err
+	}
+	return f.inodeUsage(meta, cache)
+}
+
+func (f *File) inodeUsage(meta *m.FileMetaData, cache *UsageCache) (*Usage, error) {
+	inode := hex.EncodeToString(meta.InodeAddress)
+	if usage, ok := cache.get(inode); ok {
+		return &usage, nil
+	}
+
+	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := Usage{
+		Files: 1,
+		Size:  meta.FileSize,
+		inode: inode,
+	}
+	for _, b := range fileInode.FileBlocks {
+		usage.Blocks++
+		usage.CompressedSize += uint64(b.CompressedSize)
+	}
+	cache.add(inode, usage)
+	return &usage, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// VerifyResult tells which parts of a file could not be downloaded or do not match
+// their checksums.
+type VerifyResult struct {
+	FilePath         string   `json:"file_path"`
+	Ok               bool     `json:"ok"`
+	MissingInode     bool     `json:"missing_inode,omitempty"`
+	MissingBlocks    []string `json:"missing_blocks,omitempty"`
+	CorruptBlocks    []string `json:"corrupt_blocks,omitempty"`
+	ChecksumMismatch bool     `json:"checksum_mismatch,omitempty"` // the blocks are fine but not the file as a whole
+}
+
+// Verify downloads all the blocks of a file and checks them against their checksums
+// and the file against its own. Files uploaded before checksums existed are only
+// checked for blocks which are missing or can not be decompressed.
+func (f *File) Verify(ctx context.Context, podFile string) (*VerifyResult, error) {
+	meta := f.GetFromFileMap(podFile)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	result := &VerifyResult{FilePath: podFile}
+
+	fileInodeBytes, _, err := f.getClient().DownloadBlobContext(ctx, meta.InodeAddress)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.MissingInode = true
+		return result, nil
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil {
+		result.MissingInode = true
+		return result, nil
+	}
+
+	fileHash := sha256.New()
+	var fileSize uint64
+	for _, block := range fileInode.FileBlocks {
+		data, err := downloadBlock(ctx, f.getClient(), block)
+		if err != nil && err != ErrInvalidBlock {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result.MissingBlocks = append(result.MissingBlocks, block.Name)
+			continue
+		}
+		if err == nil {
+			data, err = Decompress(data, blockCompression(block, meta.Compression), meta.BlockSize)
+		}
+		if err != nil || uint32(len(data)) != block.Size || !checkBlock(block, data) {
+			result.CorruptBlocks = append(result.CorruptBlocks, block.Name)
+			continue
+		}
+		fileHash.Write(data)
+		fileSize += uint64(len(data))
+	}
+
+	if len(result.MissingBlocks) == 0 && len(result.CorruptBlocks) == 0 {
+		result.ChecksumMismatch = fileSize != meta.FileSize ||
+			(len(meta.Checksum) > 0 && !bytes.Equal(fileHash.Sum(nil), meta.Checksum))
+		result.Ok = !result.ChecksumMismatch
+	}
+	return result, nil
+}
+
+// VerifyAll verifies all the files of the pod, in the order of their paths.
+func (f *File) VerifyAll(ctx context.Context) ([]*VerifyResult, error) {
+	f.fileMu.RLock()
+	paths := make([]string, 0, len(f.fileMap))
+	for filePath := range f.fileMap {
+		paths = append(paths, filePath)
+	}
+	f.fileMu.RUnlock()
+	sort.Strings(paths)
+
+	results := make([]*VerifyResult, 0, len(paths))
+	for _, filePath := range paths {
+		result, err := f.Verify(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// checkBlock tells if the data of a block matches its checksum, blocks uploaded without
+// one always match.
+func checkBlock(block *FileBlock, data []byte) bool {
+	if len(block.Checksum) == 0 {
+		return true
+	}
+	sum := sha256.Sum256(data)
+	return bytes.Equal(sum[:], block.Checksum)
+}
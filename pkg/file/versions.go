@@ -0,0 +1,397 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	versionsTopicPrefix = "versions:"
+)
+
+var (
+	ErrVersionNotFound     = errors.New("file version not found")
+	ErrInvalidVersionCount = errors.New("at least one version has to be kept")
+)
+
+// FileVersion is an entry of the history of a file. The metas of the versions are
+// immutable blobs, so a version can be read as long as its meta is listed.
+type FileVersion struct {
+	Version          uint32
+	MetaReference    []byte
+	FileSize         uint64
+	ModificationTime int64
+}
+
+// VersionInfo describes a version of a file to the users.
+type VersionInfo struct {
+	Version          string `json:"version"`
+	Reference        string `json:"reference"`
+	FileSize         string `json:"file_size"`
+	ModificationTime string `json:"modification_time"`
+	Current          bool   `json:"current"`
+}
+
+// fileVersionsData is what is stored in the versions feed of a path. The versions
+// are kept inline while they fit in a feed chunk, beyond that Index is the reference
+// of the blob listing them.
+type fileVersionsData struct {
+	Versions []*FileVersion `json:",omitempty"`
+	Index    []byte         `json:",omitempty"`
+}
+
+// versionsTopic is the topic of the feed with the history of the file at filePath.
+// Every update of the history is a new epoch of this feed.
+func versionsTopic(filePath string) []byte {
+	return utils.HashString(versionsTopicPrefix + filePath)
+}
+
+// ListVersions lists the versions of a file, oldest first. A file which was never
+// replaced has only its current version.
+func (f *File) ListVersions(filePath string) ([]*VersionInfo, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	versions, _, err := f.loadVersions(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		versions = []*FileVersion{newFileVersion(1, meta)}
+	}
+
+	infos := make([]*VersionInfo, 0, len(versions))
+	for _, v := range versions {
+		infos = append(infos, &VersionInfo{
+			Version:          strconv.FormatUint(uint64(v.Version), 10),
+			Reference:        utils.NewReference(v.MetaReference).String(),
+			FileSize:         strconv.FormatUint(v.FileSize, 10),
+			ModificationTime: strconv.FormatInt(v.ModificationTime, 10),
+			Current:          string(v.MetaReference) == string(meta.MetaReference),
+		})
+	}
+	return infos, nil
+}
+
+// AddVersion records current as the latest version of the file at filePath. The
+// history of a file starts when it is first replaced, so previous becomes its first
+// version then.
+func (f *File) AddVersion(filePath string, previous, current *m.FileMetaData) error {
+	versions, found, err := f.loadVersions(filePath)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 && previous != nil {
+		versions = append(versions, newFileVersion(1, previous))
+	}
+	next := uint32(1)
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+	versions = append(versions, newFileVersion(next, current))
+	return f.storeVersions(filePath, versions, found)
+}
+
+// GetVersionMeta returns the meta of a version of the file at filePath.
+func (f *File) GetVersionMeta(filePath string, version uint32) (*m.FileMetaData, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	versions, _, err := f.loadVersions(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 && version == 1 {
+		return meta, nil
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return f.downloadMeta(v.MetaReference)
+		}
+	}
+	return nil, ErrVersionNotFound
+}
+
+// OpenVersionForReading returns a reader for a version of the file at filePath.
+func (f *File) OpenVersionForReading(ctx context.Context, filePath string, version uint32) (io.ReadCloser, string, string, error) {
+	meta, err := f.GetVersionMeta(filePath, version)
+	if err != nil {
+		return nil, "", "", err
+	}
+	reader, ref, size, err := f.openMeta(ctx, meta, false)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return reader, ref, size, nil
+}
+
+// RestoreVersion makes a version of the file at filePath its current one again, by
+// adding a new version with the contents of the old one. The new meta shares the
+// inode of the old version, so deleting the file leaves the inode to the garbage
+// collector. It returns the reference of the new meta.
+func (f *File) RestoreVersion(filePath string, version uint32) ([]byte, error) {
+	current := f.GetFromFileMap(filePath)
+	if current == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	old, err := f.GetVersionMeta(filePath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// the old version may have been written under another path before a move
+	now := time.Now().Unix()
+	meta := *old
+	meta.Path = current.Path
+	meta.Name = current.Name
+	meta.SharedInode = true
+	meta.AccessTime = now
+	meta.ModificationTime = now
+	meta.MetaReference = nil
+	fileMetaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	metaReference, err := f.getClient().UploadBlob(fileMetaBytes, true, true)
+	if err != nil {
+		return nil, err
+	}
+	meta.MetaReference = metaReference
+
+	err = f.AddVersion(filePath, current, &meta)
+	if err != nil {
+		return nil, err
+	}
+	f.AddToFileMap(filePath, &meta)
+	return metaReference, nil
+}
+
+// PruneVersions drops all but the newest keep versions of the file at filePath,
+// the current version is always kept. The dropped versions are only taken out of the
+// history, their metas may have been shared with other users and are left to the
+// garbage collector like their inodes and blocks. It returns the number of versions
+// dropped.
+func (f *File) PruneVersions(filePath string, keep int) (int, error) {
+	if keep < 1 {
+		return 0, ErrInvalidVersionCount
+	}
+	current := f.GetFromFileMap(filePath)
+	if current == nil {
+		return 0, fmt.Errorf("file not found in dfs")
+	}
+	versions, found, err := f.loadVersions(filePath)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) <= keep {
+		return 0, nil
+	}
+
+	var kept, dropped []*FileVersion
+	for i, v := range versions {
+		if i >= len(versions)-keep || string(v.MetaReference) == string(current.MetaReference) {
+			kept = append(kept, v)
+		} else {
+			dropped = append(dropped, v)
+		}
+	}
+	err = f.storeVersions(filePath, kept, found)
+	if err != nil {
+		return 0, err
+	}
+	return len(dropped), nil
+}
+
+// DropVersions forgets the history of the file at filePath when it is deleted. An
+// empty history is written, since the earlier epochs of the feed stay readable when
+// its latest update is deleted.
+func (f *File) DropVersions(filePath string) error {
+	versions, found, err := f.loadVersions(filePath)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	return f.storeVersions(filePath, nil, found)
+}
+
+// moveVersions moves the history of a file along with the file. The current version
+// now has the meta written for the new path, at metaReference.
+func (f *File) moveVersions(srcPath, dstPath string, oldReference, metaReference []byte) error {
+	versions, _, err := f.loadVersions(srcPath)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	for _, v := range versions {
+		if string(v.MetaReference) == string(oldReference) {
+			v.MetaReference = metaReference
+		}
+	}
+	_, found, err := f.loadVersions(dstPath)
+	if err != nil {
+		return err
+	}
+	err = f.storeVersions(dstPath, versions, found)
+	if err != nil {
+		return err
+	}
+	return f.DropVersions(srcPath)
+}
+
+// markVersions marks the versions of the file at filePath as live, together with the
+// blob listing them.
+func (f *File) markVersions(filePath string, mark func(reference []byte)) error {
+	topic := versionsTopic(filePath)
+	_, data, err := f.fd.GetFeedData(topic, f.acc.GetAddress())
+	if err != nil {
+		if errors.Is(err, feed.ErrNoFeedUpdates) {
+			return nil
+		}
+		return err
+	}
+	versions, index, err := f.decodeVersions(data)
+	if err != nil {
+		return err
+	}
+	if index != nil {
+		mark(index)
+	}
+	for _, v := range versions {
+		_, err = f.markFile(v.MetaReference, mark)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadVersions returns the history of the file at filePath, and whether its feed
+// exists.
+func (f *File) loadVersions(filePath string) ([]*FileVersion, bool, error) {
+	_, data, err := f.fd.GetFeedData(versionsTopic(filePath), f.acc.GetAddress())
+	if err != nil {
+		if errors.Is(err, feed.ErrNoFeedUpdates) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	versions, _, err := f.decodeVersions(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return versions, true, nil
+}
+
+func (f *File) decodeVersions(data []byte) ([]*FileVersion, []byte, error) {
+	var stored fileVersionsData
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stored.Index == nil {
+		return stored.Versions, nil, nil
+	}
+
+	indexData, respCode, err := f.getClient().DownloadBlob(stored.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("file versions %x not found", stored.Index)
+	}
+	var versions []*FileVersion
+	err = json.Unmarshal(indexData, &versions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return versions, stored.Index, nil
+}
+
+// storeVersions writes the history of the file at filePath as a new update of its
+// feed, or creates the feed if found is false.
+func (f *File) storeVersions(filePath string, versions []*FileVersion, found bool) error {
+	data, err := json.Marshal(&fileVersionsData{Versions: versions})
+	if err != nil {
+		return err
+	}
+	if len(data) > utils.MaxChunkLength {
+		indexData, err := json.Marshal(versions)
+		if err != nil {
+			return err
+		}
+		index, err := f.getClient().UploadBlob(indexData, true, true)
+		if err != nil {
+			return err
+		}
+		data, err = json.Marshal(&fileVersionsData{Index: index})
+		if err != nil {
+			return err
+		}
+	}
+
+	topic := versionsTopic(filePath)
+	if found {
+		_, err = f.fd.UpdateFeed(topic, f.acc.GetAddress(), data)
+	} else {
+		_, err = f.fd.CreateFeed(topic, f.acc.GetAddress(), data)
+	}
+	return err
+}
+
+func (f *File) downloadMeta(metaReference []byte) (*m.FileMetaData, error) {
+	metaBytes, respCode, err := f.getClient().DownloadBlob(metaReference)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("file meta %x not found", metaReference)
+	}
+	var meta m.FileMetaData
+	err = json.Unmarshal(metaBytes, &meta)
+	if err != nil {
+		return nil, err
+	}
+	meta.MetaReference = metaReference
+	return &meta, nil
+}
+
+func newFileVersion(version uint32, meta *m.FileMetaData) *FileVersion {
+	return &FileVersion{
+		Version:          version,
+		MetaReference:    meta.MetaReference,
+		FileSize:         meta.FileSize,
+		ModificationTime: meta.ModificationTime,
+	}
+}
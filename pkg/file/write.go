@@ -0,0 +1,250 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
+)
+
+// Append writes the data from fd at the end of the file at filePath. It returns the
+// reference of the new meta of the file.
+func (f *File) Append(ctx context.Context, filePath string, fd io.Reader) ([]byte, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	return f.WriteAt(ctx, filePath, int64(meta.FileSize), fd)
+}
+
+// WriteAt writes the data from fd over the file at filePath starting at offset, the
+// file grows if the data goes past its end. Only the blocks the data falls in are read
+// and uploaded again, the other blocks are kept in the new inode as they are. The
+// blocks keep their sizes, except the last one which fills up to the block size of the
+// file before new blocks are added. The old meta, inode and blocks are left in place,
+// since a version of the file may still use them, see DeleteReplaced. The whole file
+// is not read again, so the new meta has no file checksum and the file is verified by
+// the checksums of its blocks. It returns the reference of the new meta.
+func (f *File) WriteAt(ctx context.Context, filePath string, offset int64, fd io.Reader) ([]byte, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	if offset < 0 || uint64(offset) > meta.FileSize {
+		return nil, ErrInvalidOffset
+	}
+
+	fileInodeBytes, _, err := f.getClient().DownloadBlobContext(ctx, meta.InodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil {
+		return nil, err
+	}
+	blocks := append([]*FileBlock{}, fileInode.FileBlocks...)
+
+	// find the block the offset falls in, an append goes to the last block while it is
+	// not full
+	index := 0
+	start := int64(0)
+	for index < len(blocks) && start+int64(blocks[index].Size) <= offset {
+		start += int64(blocks[index].Size)
+		index++
+	}
+	if index == len(blocks) && index > 0 && blocks[index-1].Size < meta.BlockSize {
+		index--
+		start -= int64(blocks[index].Size)
+	}
+
+	reader := bufio.NewReader(fd)
+	var written int64
+	rewritten := 0
+	for {
+		// the next block is only read if there is data left to write in it
+		if _, err := reader.Peek(1); err == io.EOF {
+			break
+		}
+		var data []byte
+		if index < len(blocks) {
+			data, err = f.readBlock(ctx, blocks[index], meta)
+			if err != nil {
+				return nil, err
+			}
+		}
+		length := len(data)
+		if index >= len(blocks)-1 && length < int(meta.BlockSize) {
+			length = int(meta.BlockSize)
+		}
+		buf := make([]byte, length)
+		copy(buf, data)
+
+		pos := int(offset + written - start)
+		n, err := io.ReadFull(reader, buf[pos:])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		if pos+n > len(data) {
+			buf = buf[:pos+n]
+		} else {
+			buf = buf[:len(data)]
+		}
+
+		block, err := f.uploadWrittenBlock(ctx, index, buf, meta)
+		if err != nil {
+			return nil, err
+		}
+		if index < len(fileInode.FileBlocks) {
+			rewritten++
+		}
+		if index < len(blocks) {
+			blocks[index] = block
+		} else {
+			blocks = append(blocks, block)
+		}
+		written += int64(n)
+		start += int64(len(buf))
+		index++
+	}
+	if written == 0 {
+		return meta.MetaReference, nil
+	}
+
+	now := time.Now().Unix()
+	newMeta := *meta
+	if uint64(offset+written) > newMeta.FileSize {
+		newMeta.FileSize = uint64(offset + written)
+	}
+	newMeta.Checksum = nil
+	// the new inode is not shared, but the blocks it keeps from a shared one are
+	newMeta.SharedInode = meta.SharedInode && rewritten < len(fileInode.FileBlocks)
+	newMeta.AccessTime = now
+	newMeta.ModificationTime = now
+	newMeta.MetaReference = nil
+	return f.uploadInode(ctx, FileINode{FileBlocks: blocks}, &newMeta, filePath)
+}
+
+// DeleteReplaced deletes the blobs of the file in dirPath which a write made unused: the
+// blocks of previous which are not in the inode of current any more, the inode of
+// previous and then previous itself. Blocks chunked by content, which other files can
+// have too, the blobs of a meta written for another directory or sharing its inode
+// with copies, and every blob of a pod which keeps them or whose files may have
+// versions using them are left alone.
+func (f *File) DeleteReplaced(dirPath string, previous, current *m.FileMetaData) error {
+	if previous.Path != dirPath || previous.SharedInode || f.IsKeepingBlobs() || f.IsTrackingVersions() {
+		return nil
+	}
+	oldInode, err := f.downloadInode(previous.InodeAddress)
+	if err != nil {
+		return err
+	}
+	newInode, err := f.downloadInode(current.InodeAddress)
+	if err != nil {
+		return err
+	}
+	for i, b := range oldInode.FileBlocks {
+		if len(b.Key) > 0 {
+			continue
+		}
+		if i < len(newInode.FileBlocks) && bytes.Equal(newInode.FileBlocks[i].Address, b.Address) {
+			continue
+		}
+		err = f.deleteBlob(b.Address)
+		if err != nil {
+			return err
+		}
+	}
+	err = f.deleteBlob(previous.InodeAddress)
+	if err != nil {
+		return err
+	}
+	return f.deleteBlob(previous.MetaReference)
+}
+
+func (f *File) downloadInode(inodeAddress []byte) (*FileINode, error) {
+	fileInodeBytes, respCode, err := f.getClient().DownloadBlob(inodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("file inode %x not found", inodeAddress)
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInode, nil
+}
+
+// readBlock downloads a block of the file with the given meta and checks it against
+// its checksum.
+func (f *File) readBlock(ctx context.Context, block *FileBlock, meta *m.FileMetaData) ([]byte, error) {
+	data, err := downloadBlock(ctx, f.getClient(), block)
+	if err != nil {
+		return nil, err
+	}
+	data, err = Decompress(data, blockCompression(block, meta.Compression), meta.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(data)) != block.Size {
+		return nil, ErrInvalidBlock
+	}
+	if !checkBlock(block, data) {
+		return nil, ErrChecksumMismatch
+	}
+	return data, nil
+}
+
+// uploadWrittenBlock compresses and uploads the block with the given index of the file
+// with the given meta.
+func (f *File) uploadWrittenBlock(ctx context.Context, index int, data []byte, meta *m.FileMetaData) (*FileBlock, error) {
+	checksum := sha256.Sum256(data)
+	uploadData := data
+	var dataCompression string
+	if meta.Compression != "" {
+		var err error
+		uploadData, dataCompression, err = compressBlock(data, meta.Compression, meta.BlockSize, meta.ContentType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	addr, key, err := f.uploadBlock(ctx, uploadData, meta.Chunking == ChunkingCDC)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBlock{
+		Name:           fmt.Sprintf("block-%05d", index),
+		Size:           uint32(len(data)),
+		CompressedSize: uint32(len(uploadData)),
+		Address:        addr,
+		Compression:    dataCompression,
+		Checksum:       checksum[:],
+		Key:            key,
+	}, nil
+}
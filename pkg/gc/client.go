@@ -0,0 +1,131 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"context"
+	"io"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+type retainKey struct{}
+
+// Retain returns a context whose uploads are not recorded by a RecordingClient.
+// It is meant for blobs which are handed out to others, like sharing references,
+// and which the user tree never points to.
+func Retain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retainKey{}, true)
+}
+
+func isRetained(ctx context.Context) bool {
+	retained, _ := ctx.Value(retainKey{}).(bool)
+	return retained
+}
+
+// RecordingClient is a blockstore.Client decorator which records every blob it
+// uploads in the ledger of a user, so that the garbage collector can find the blobs
+// the user no longer references.
+type RecordingClient struct {
+	blockstore.ClientV2
+	ledger   *Ledger
+	userName string
+	logger   logging.Logger
+}
+
+func NewRecordingClient(client blockstore.Client, ledger *Ledger, userName string, logger logging.Logger) *RecordingClient {
+	return &RecordingClient{
+		ClientV2: blockstore.ToV2(client),
+		ledger:   ledger,
+		userName: userName,
+		logger:   logger,
+	}
+}
+
+func (r *RecordingClient) record(ctx context.Context, reference []byte, size int64) {
+	if isRetained(ctx) {
+		return
+	}
+	// a blob missing from the ledger is only never collected, so the upload does not fail
+	if err := r.ledger.Record(r.userName, reference, size); err != nil {
+		r.logger.Warningf("gc: could not record blob of %s: %v", r.userName, err)
+	}
+}
+
+func (r *RecordingClient) UploadBlob(data []byte, pin, encrypt bool) ([]byte, error) {
+	return r.UploadBlobContext(context.Background(), data, pin, encrypt)
+}
+
+func (r *RecordingClient) UploadBlobContext(ctx context.Context, data []byte, pin, encrypt bool) ([]byte, error) {
+	address, err := r.ClientV2.UploadBlobContext(ctx, data, pin, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	r.record(ctx, address, int64(len(data)))
+	return address, nil
+}
+
+// UploadBlobStream keeps the reader seekable if it was, so that the uploads can still
+// be rewound and retried by the clients below.
+func (r *RecordingClient) UploadBlobStream(ctx context.Context, rd io.Reader, pin, encrypt bool) ([]byte, error) {
+	counter := &countingReader{Reader: rd}
+	var counted io.Reader = counter
+	if seeker, ok := rd.(io.Seeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			counted = &countingReadSeeker{countingReader: counter, seeker: seeker, start: start}
+		}
+	}
+	address, err := r.ClientV2.UploadBlobStream(ctx, counted, pin, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	r.record(ctx, address, counter.n)
+	return address, nil
+}
+
+func (r *RecordingClient) Health() blockstore.Health {
+	return blockstore.GetHealth(r.ClientV2)
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReadSeeker is a countingReader over a seekable reader, the count follows the
+// position so that the bytes read again after a rewind are not counted twice.
+type countingReadSeeker struct {
+	*countingReader
+	seeker io.Seeker
+	start  int64
+}
+
+func (c *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := c.seeker.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	c.n = pos - c.start
+	return pos, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMinAge keeps the blobs of uploads which are still in flight, whose
+	// references are not yet linked in to the tree of the user, out of a sweep.
+	DefaultMinAge = time.Hour
+)
+
+// Report tells what a garbage collection found and, unless it was a dry run, removed.
+type Report struct {
+	User             string `json:"user"`
+	DryRun           bool   `json:"dry_run"`
+	Recorded         int    `json:"recorded"`
+	Live             int    `json:"live"`
+	Young            int    `json:"young"`
+	Shared           int    `json:"shared"`
+	Orphaned         int    `json:"orphaned"`
+	ReclaimableBytes int64  `json:"reclaimable_bytes"`
+	Removed          int    `json:"removed"`
+	Failed           int    `json:"failed"`
+}
+
+// LiveSet holds the references marked as live while walking the tree of a user.
+type LiveSet map[string]struct{}
+
+func NewLiveSet() LiveSet {
+	return make(LiveSet)
+}
+
+func (s LiveSet) Mark(reference []byte) {
+	if len(reference) == 0 {
+		return
+	}
+	s[hex.EncodeToString(reference)] = struct{}{}
+}
+
+func (s LiveSet) Contains(reference []byte) bool {
+	_, ok := s[hex.EncodeToString(reference)]
+	return ok
+}
+
+// Collector sweeps the blobs recorded in the ledger of a user which are not live.
+// Blobs are unpinned or deleted with DeleteBlob. Feed chunks are never collected,
+// since feed lookups walk through the older updates of a feed.
+type Collector struct {
+	ledger *Ledger
+	client blockstore.Client
+	minAge time.Duration
+	logger logging.Logger
+}
+
+func NewCollector(ledger *Ledger, client blockstore.Client, minAge time.Duration, logger logging.Logger) *Collector {
+	return &Collector{
+		ledger: ledger,
+		client: client,
+		minAge: minAge,
+		logger: logger,
+	}
+}
+
+func (c *Collector) Ledger() *Ledger {
+	return c.ledger
+}
+
+// Sweep removes the blobs of the user which are not in live. Blobs younger than the
+// minimum age, and blobs which are also in the ledger of another user, are kept.
+func (c *Collector) Sweep(userName string, live LiveSet, dryRun bool) (*Report, error) {
+	return c.sweep(userName, live, c.minAge, dryRun)
+}
+
+// SweepAll removes every blob of the user which is not in live, regardless of its
+// age. It is used when the user is deleted.
+func (c *Collector) SweepAll(userName string, live LiveSet) (*Report, error) {
+	return c.sweep(userName, live, 0, false)
+}
+
+func (c *Collector) sweep(userName string, live LiveSet, minAge time.Duration, dryRun bool) (*Report, error) {
+	entries, err := c.ledger.Entries(userName)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := c.sharedReferences(userName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		User:     userName,
+		DryRun:   dryRun,
+		Recorded: len(entries),
+	}
+	cutoff := time.Now().Add(-minAge)
+	var removed [][]byte
+	for _, entry := range entries {
+		switch {
+		case live.Contains(entry.Reference):
+			report.Live++
+			continue
+		case entry.Time.After(cutoff):
+			report.Young++
+			continue
+		case shared.Contains(entry.Reference):
+			report.Shared++
+			continue
+		}
+
+		report.Orphaned++
+		report.ReclaimableBytes += entry.Size
+		if dryRun {
+			continue
+		}
+		err := c.client.DeleteBlob(entry.Reference)
+		if err != nil && !errors.Is(err, blockstore.ErrBlobNotFound) {
+			c.logger.Warningf("gc: could not remove blob %x of %s: %v", entry.Reference, userName, err)
+			report.Failed++
+			continue
+		}
+		removed = append(removed, entry.Reference)
+	}
+	report.Removed = len(removed)
+
+	err = c.ledger.Forget(userName, removed)
+	if err != nil {
+		return nil, err
+	}
+	c.logger.WithFields(logrus.Fields{
+		"user":        userName,
+		"dry_run":     dryRun,
+		"orphaned":    report.Orphaned,
+		"reclaimable": report.ReclaimableBytes,
+		"removed":     report.Removed,
+		"failed":      report.Failed,
+	}).Log(logrus.InfoLevel, "gc: sweep done")
+	return report, nil
+}
+
+// sharedReferences returns the references in the ledgers of the other users. The
+// same content uploaded by two users can end up under one reference.
+func (c *Collector) sharedReferences(userName string) (LiveSet, error) {
+	shared := NewLiveSet()
+	users, err := c.ledger.Users()
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range users {
+		if other == userName {
+			continue
+		}
+		entries, err := c.ledger.Entries(other)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			shared.Mark(entry.Reference)
+		}
+	}
+	return shared, nil
+}
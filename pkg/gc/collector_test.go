@@ -0,0 +1,254 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+// rewindingClient reads every streamed upload once before uploading it, as a retry
+// after a failed attempt does.
+type rewindingClient struct {
+	*mock.MockBeeClient
+}
+
+func (c *rewindingClient) UploadBlobStream(ctx context.Context, rd io.Reader, pin, encrypt bool) ([]byte, error) {
+	seeker, ok := rd.(io.Seeker)
+	if !ok {
+		return nil, errors.New("upload stream can not be rewound")
+	}
+	if _, err := ioutil.ReadAll(rd); err != nil {
+		return nil, err
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return c.MockBeeClient.UploadBlobStream(ctx, rd, pin, encrypt)
+}
+
+// wrappingClient wraps the errors of deletes, as the decorating clients do.
+type wrappingClient struct {
+	*mock.MockBeeClient
+}
+
+func (c *wrappingClient) DeleteBlob(address []byte) error {
+	if err := c.MockBeeClient.DeleteBlob(address); err != nil {
+		return fmt.Errorf("delete blob: %w", err)
+	}
+	return nil
+}
+
+func TestCollector(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	upload := func(t *testing.T, client *gc.RecordingClient, size int) []byte {
+		ref, err := client.UploadBlob(make([]byte, size), true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ref
+	}
+	present := func(t *testing.T, client *mock.MockBeeClient, ref []byte) bool {
+		_, respCode, _ := client.DownloadBlob(ref)
+		return respCode == http.StatusOK
+	}
+
+	t.Run("ledger", func(t *testing.T) {
+		ledger := gc.NewLedger(t.TempDir())
+		client := gc.NewRecordingClient(mock.NewMockBeeClient(), ledger, "user1", logger)
+		ref1 := upload(t, client, 10)
+		ref2, err := client.UploadBlobStream(context.Background(), bytes.NewReader(make([]byte, 20)), true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.UploadBlobContext(gc.Retain(context.Background()), make([]byte, 30), true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := ledger.Entries("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(entries))
+		}
+		sizes := map[string]int64{string(ref1): 10, string(ref2): 20}
+		for _, entry := range entries {
+			if sizes[string(entry.Reference)] != entry.Size {
+				t.Fatalf("invalid size %d of %x", entry.Size, entry.Reference)
+			}
+		}
+
+		err = ledger.Forget("user1", [][]byte{ref1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err = ledger.Entries("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || !bytes.Equal(entries[0].Reference, ref2) {
+			t.Fatalf("ref1 not forgotten")
+		}
+
+		err = ledger.Drop("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		users, err := ledger.Users()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 0 {
+			t.Fatalf("ledger not dropped")
+		}
+	})
+
+	t.Run("ledger-rewound-stream", func(t *testing.T) {
+		ledger := gc.NewLedger(t.TempDir())
+		client := gc.NewRecordingClient(&rewindingClient{MockBeeClient: mock.NewMockBeeClient()}, ledger, "user1", logger)
+		_, err := client.UploadBlobStream(context.Background(), bytes.NewReader(make([]byte, 20)), true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := ledger.Entries("user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Size != 20 {
+			t.Fatalf("unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		mockClient := mock.NewMockBeeClient()
+		ledger := gc.NewLedger(t.TempDir())
+		client := gc.NewRecordingClient(mockClient, ledger, "user1", logger)
+		live := upload(t, client, 100)
+		orphan := upload(t, client, 200)
+
+		liveSet := gc.NewLiveSet()
+		liveSet.Mark(live)
+		collector := gc.NewCollector(ledger, mockClient, 0, logger)
+		report, err := collector.Sweep("user1", liveSet, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Live != 1 || report.Orphaned != 1 || report.ReclaimableBytes != 200 || report.Removed != 0 {
+			t.Fatalf("invalid report %+v", report)
+		}
+		if !present(t, mockClient, orphan) {
+			t.Fatalf("dry run removed a blob")
+		}
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		mockClient := mock.NewMockBeeClient()
+		ledger := gc.NewLedger(t.TempDir())
+		client := gc.NewRecordingClient(mockClient, ledger, "user1", logger)
+		live := upload(t, client, 100)
+		orphan := upload(t, client, 200)
+
+		liveSet := gc.NewLiveSet()
+		liveSet.Mark(live)
+		collector := gc.NewCollector(ledger, mockClient, 0, logger)
+		report, err := collector.Sweep("user1", liveSet, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Removed != 1 || report.ReclaimableBytes != 200 {
+			t.Fatalf("invalid report %+v", report)
+		}
+		if present(t, mockClient, orphan) {
+			t.Fatalf("orphan not removed")
+		}
+		if !present(t, mockClient, live) {
+			t.Fatalf("live blob removed")
+		}
+
+		// the removed blob is forgotten, so the next sweep has nothing to do
+		report, err = collector.Sweep("user1", liveSet, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Recorded != 1 || report.Orphaned != 0 {
+			t.Fatalf("invalid report %+v", report)
+		}
+	})
+
+	t.Run("already-removed", func(t *testing.T) {
+		mockClient := mock.NewMockBeeClient()
+		ledger := gc.NewLedger(t.TempDir())
+		client := gc.NewRecordingClient(mockClient, ledger, "user1", logger)
+		orphan := upload(t, client, 100)
+		err := mockClient.DeleteBlob(orphan)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a blob which is gone already counts as removed, however the error is wrapped
+		collector := gc.NewCollector(ledger, &wrappingClient{mockClient}, 0, logger)
+		report, err := collector.Sweep("user1", gc.NewLiveSet(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Removed != 1 || report.Failed != 0 {
+			t.Fatalf("invalid report %+v", report)
+		}
+	})
+
+	t.Run("young-and-shared", func(t *testing.T) {
+		mockClient := mock.NewMockBeeClient()
+		ledger := gc.NewLedger(t.TempDir())
+		young := upload(t, gc.NewRecordingClient(mockClient, ledger, "user1", logger), 100)
+
+		collector := gc.NewCollector(ledger, mockClient, time.Hour, logger)
+		report, err := collector.Sweep("user1", gc.NewLiveSet(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Young != 1 || report.Removed != 0 || !present(t, mockClient, young) {
+			t.Fatalf("young blob removed: %+v", report)
+		}
+
+		// the same reference recorded by another user is kept
+		err = ledger.Record("user2", young, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err = collector.SweepAll("user1", gc.NewLiveSet())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Shared != 1 || report.Removed != 0 || !present(t, mockClient, young) {
+			t.Fatalf("shared blob removed: %+v", report)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a blob uploaded on behalf of a user.
+type Entry struct {
+	Reference []byte
+	Size      int64
+	Time      time.Time
+}
+
+// Ledger keeps, for every user, the blobs that were uploaded for that user. These
+// are the candidates for garbage collection, since anything the user no longer
+// references can only be found through the ledger. Every user has one append only
+// file "<dir>/<user name>" with a line "<hex reference> <size> <unix time>" per upload.
+type Ledger struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewLedger(dir string) *Ledger {
+	return &Ledger{
+		dir: dir,
+	}
+}
+
+func (l *Ledger) path(userName string) string {
+	return filepath.Join(l.dir, userName)
+}
+
+// Record adds an uploaded blob to the ledger of the user.
+func (l *Ledger) Record(userName string, reference []byte, size int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := os.MkdirAll(l.dir, 0700)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path(userName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s %d %d\n", hex.EncodeToString(reference), size, time.Now().Unix())
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns the blobs recorded for the user, one entry per reference with the
+// time of its latest upload.
+func (l *Ledger) Entries(userName string) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.entries(userName)
+}
+
+func (l *Ledger) entries(userName string) ([]Entry, error) {
+	f, err := os.Open(l.path(userName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	byRef := make(map[string]Entry)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue // a line cut short by a crash
+		}
+		ref, err := hex.DecodeString(fields[0])
+		if err != nil {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		entry := Entry{Reference: ref, Size: size, Time: time.Unix(unix, 0)}
+		if old, ok := byRef[fields[0]]; ok && old.Time.After(entry.Time) {
+			continue
+		}
+		byRef[fields[0]] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(byRef))
+	for _, entry := range byRef {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// Forget removes the given references from the ledger of the user.
+func (l *Ledger) Forget(userName string, references [][]byte) error {
+	if len(references) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	forget := make(map[string]bool)
+	for _, ref := range references {
+		forget[hex.EncodeToString(ref)] = true
+	}
+	entries, err := l.entries(userName)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		ref := hex.EncodeToString(entry.Reference)
+		if forget[ref] {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", ref, entry.Size, entry.Time.Unix())
+	}
+
+	// write aside and rename, so that a crash never leaves a half written ledger
+	tmp := l.path(userName) + ".tmp"
+	err = ioutil.WriteFile(tmp, []byte(b.String()), 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path(userName))
+}
+
+// Drop removes the ledger of the user.
+func (l *Ledger) Drop(userName string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := os.Remove(l.path(userName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Users returns the names of the users who have a ledger.
+func (l *Ledger) Users() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	files, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var users []string
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		users = append(users, f.Name())
+	}
+	return users, nil
+}
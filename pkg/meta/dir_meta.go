@@ -17,7 +17,7 @@ limitations under the License.
 package datapod
 
 var (
-	DirMetaVersion uint8 = 1
+	DirMetaVersion uint8 = 2
 )
 
 type DirectoryMetaData struct {
@@ -27,4 +27,7 @@ type DirectoryMetaData struct {
 	CreationTime     int64
 	AccessTime       int64
 	ModificationTime int64
+	Versioning       bool // set on the root of a pod which keeps the versions of its files
+	Versioned        bool // set on the root of a pod which ever had versioning, its files may have versions
+	Snapshots        bool // set on the root of a pod with snapshots, which still use deleted blobs
 }
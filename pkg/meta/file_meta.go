@@ -17,7 +17,7 @@ limitations under the License.
 package datapod
 
 var (
-	FileMetaVersion uint8 = 1
+	FileMetaVersion uint8 = 3
 )
 
 type FileMetaData struct {
@@ -25,7 +25,7 @@ type FileMetaData struct {
 	Path             string
 	Name             string
 	FileSize         uint64
-	BlockSize        uint32
+	BlockSize        uint32 // the average block size with content defined chunking
 	ContentType      string
 	Compression      string
 	CreationTime     int64
@@ -33,4 +33,9 @@ type FileMetaData struct {
 	ModificationTime int64
 	MetaReference    []byte
 	InodeAddress     []byte
+	SharedInode      bool   // the inode and its blocks are shared with copies of the file
+	Chunking         string // how the file is split in to blocks, empty for blocks of BlockSize
+	MinBlockSize     uint32
+	MaxBlockSize     uint32
+	Checksum         []byte // sha256 of the file contents, empty for files uploaded without it
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"crypto/rand"
 	"io/ioutil"
 	"os"
@@ -176,7 +177,7 @@ func createRandomFileInPod(t *testing.T, size int, pod1 *Pod, podName, podDir st
 		t.Fatal(err)
 	}
 	fName := filepath.Base(file.Name())
-	_, err = pod1.UploadFile(podName, fName, int64(size), fd, podDir, "100", "false")
+	_, err = pod1.UploadFile(context.Background(), podName, fName, int64(size), fd, podDir, "100", "false", "")
 	if err != nil {
 		t.Fatalf("createRandomFileInPod failed: %s", err.Error())
 	}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	gopath "path"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// Copy copies the file or directory src of the pod podName to dst in the pod dstPodName,
+// or in podName itself if dstPodName is empty. Both pods have to be open. If dst is a
+// directory src is copied into it, otherwise src is copied to dst, whose parent has to
+// exist. The copies of files point at the inodes of the source files, so no block is
+// uploaded again.
+func (p *Pod) Copy(podName, src, dstPodName, dst string) error {
+	if dstPodName == "" {
+		dstPodName = podName
+	}
+	if !p.isPodOpened(podName) || !p.isPodOpened(dstPodName) {
+		return ErrPodNotOpened
+	}
+
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := p.GetPodInfoFromPodMap(dstPodName)
+	if err != nil {
+		return err
+	}
+
+	if dstInfo.accountInfo.IsReadOnlyPod() {
+		return ErrReadOnlyPod
+	}
+
+	srcPath := p.getFilePath(gopath.Clean(src), podInfo)
+	isFile := podInfo.getFile().IsFileAlreadyPResent(srcPath)
+	if !isFile && podInfo.GetDirectory().GetDirFromDirectoryMap(srcPath) == nil {
+		return ErrFileOrDirNotFound
+	}
+
+	dstPath := p.destinationPath(dstInfo, srcPath, dst)
+	if !isFile && dstPodName == podName && isInTree(srcPath, dstPath) {
+		return ErrCopyIntoItself
+	}
+	err = checkDestination(dstInfo, dstPath)
+	if err != nil {
+		return err
+	}
+	dstParent := gopath.Dir(dstPath)
+	dstName := gopath.Base(dstPath)
+
+	if isFile {
+		meta := podInfo.getFile().GetFromFileMap(srcPath)
+		srcReference, dstReference, err := podInfo.getFile().Copy(meta.MetaReference, dstInfo.getFile(), dstParent, dstName)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(srcReference, meta.MetaReference) {
+			err = p.updateDirEntries(podName, podInfo, gopath.Dir(srcPath), replaceEntry(meta.MetaReference, srcReference))
+			if err != nil {
+				return err
+			}
+		}
+		return p.updateDirEntries(dstPodName, dstInfo, dstParent, addEntry(dstReference))
+	}
+
+	if len(dstName) > utils.MaxDirectoryNameLength {
+		return ErrTooLongDirectoryName
+	}
+	// the cached inode may be stale, the one in Swarm tells what is in the directory
+	_, srcInode, err := podInfo.GetDirectory().GetDirNode(srcPath, podInfo.GetFeed(), podInfo.GetAccountInfo())
+	if err != nil {
+		return err
+	}
+	err = podInfo.GetDirectory().CopyTree(srcPath, srcInode, dstInfo.GetDirectory(), dstPath)
+	if err != nil {
+		return err
+	}
+	return p.updateDirEntries(dstPodName, dstInfo, dstParent, addEntry(utils.HashString(dstPath)))
+}
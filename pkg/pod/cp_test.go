@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_Copy(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podName2 := "test2"
+	podPath1 := utils.PathSeperator + podName1
+	podPath2 := utils.PathSeperator + podName2
+	info1, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	info2, err := pod1.CreatePod(podName2, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName2)
+	}
+	err = pod1.MakeDir(podName1, "dir1/dir2")
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+	file1 := createRandomFileInPod(t, 100, pod1, podName1, podPath1)
+	file2 := createRandomFileInPod(t, 200, pod1, podName1, podPath1+"/dir1/dir2")
+	inode1 := info1.getFile().GetFromFileMap(podPath1 + file1).InodeAddress
+	inode2 := info1.getFile().GetFromFileMap(podPath1 + file2).InodeAddress
+
+	t.Run("cp-file", func(t *testing.T) {
+		err := pod1.Copy(podName1, file1, "", "/copy")
+		if err != nil {
+			t.Fatalf("error copying file: %v", err)
+		}
+		srcMeta := info1.getFile().GetFromFileMap(podPath1 + file1)
+		dstMeta := info1.getFile().GetFromFileMap(podPath1 + "/copy")
+		if srcMeta == nil || dstMeta == nil {
+			t.Fatalf("file not copied")
+		}
+		if !bytes.Equal(dstMeta.InodeAddress, inode1) || !bytes.Equal(srcMeta.InodeAddress, inode1) {
+			t.Fatalf("file inode not shared")
+		}
+		if !srcMeta.SharedInode || !dstMeta.SharedInode {
+			t.Fatalf("file metas not marked as sharing the inode")
+		}
+		assertEntries(t, info1, podPath1, "dir1", file1[1:], "copy")
+		assertFileSize(t, pod1, podName1, "/copy", 100)
+	})
+
+	t.Run("rm-source-keeps-copy", func(t *testing.T) {
+		err := pod1.RemoveFile(podName1, file1)
+		if err != nil {
+			t.Fatalf("error removing file: %v", err)
+		}
+		assertEntries(t, info1, podPath1, "dir1", "copy")
+		assertFileSize(t, pod1, podName1, "/copy", 100)
+	})
+
+	t.Run("cp-dir-across-pods", func(t *testing.T) {
+		err := pod1.Copy(podName1, "/dir1", podName2, "/")
+		if err != nil {
+			t.Fatalf("error copying directory: %v", err)
+		}
+		if info2.GetDirectory().GetDirFromDirectoryMap(podPath2+"/dir1/dir2") == nil {
+			t.Fatalf("directory not copied")
+		}
+		meta := info2.getFile().GetFromFileMap(podPath2 + file2)
+		if meta == nil || meta.Path != podPath2+"/dir1/dir2" {
+			t.Fatalf("file in directory not copied")
+		}
+		if !bytes.Equal(meta.InodeAddress, inode2) {
+			t.Fatalf("file inode not shared")
+		}
+		assertEntries(t, info2, podPath2, "dir1")
+		assertEntries(t, info2, podPath2+"/dir1", "dir2")
+		assertFileSize(t, pod1, podName2, file2, 200)
+	})
+
+	t.Run("rmdir-source-keeps-copy", func(t *testing.T) {
+		err := pod1.RemoveDir(podName1, "dir1", true, nil)
+		if err != nil {
+			t.Fatalf("error removing directory: %v", err)
+		}
+		assertFileSize(t, pod1, podName2, file2, 200)
+	})
+
+	t.Run("cp-errors", func(t *testing.T) {
+		err := pod1.Copy(podName2, "/dir1", "", "/dir1/dir2")
+		if !errors.Is(err, ErrCopyIntoItself) {
+			t.Fatalf("expected %v, got %v", ErrCopyIntoItself, err)
+		}
+		err = pod1.Copy(podName1, "/missing", "", "/other")
+		if !errors.Is(err, ErrFileOrDirNotFound) {
+			t.Fatalf("expected %v, got %v", ErrFileOrDirNotFound, err)
+		}
+		file3 := createRandomFileInPod(t, 100, pod1, podName1, podPath1)
+		err = pod1.Copy(podName1, file3, "", "/copy")
+		if !errors.Is(err, ErrDestinationExists) {
+			t.Fatalf("expected %v, got %v", ErrDestinationExists, err)
+		}
+		err = pod1.Copy(podName1, file3, "missing", "/copy")
+		if !errors.Is(err, ErrPodNotOpened) {
+			t.Fatalf("expected %v, got %v", ErrPodNotOpened, err)
+		}
+	})
+
+	// cleanup pods
+	err = pod1.DeletePod(podName1)
+	if err != nil {
+		t.Fatalf("could not delete pod")
+	}
+	err = pod1.DeletePod(podName2)
+	if err != nil {
+		t.Fatalf("could not delete pod")
+	}
+}
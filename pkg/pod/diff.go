@@ -0,0 +1,427 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	gopath "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	DiffSourcePod      = "pod"      // "pod:<pod name>", the live tree of an open pod
+	DiffSourceSnapshot = "snapshot" // "snapshot:<snapshot name>"
+	DiffSourceLocal    = "local"    // "local:<directory>", only read by the client
+
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+	DiffModified = "modified"
+	DiffMoved    = "moved"
+	DiffUnknown  = "unknown" // a file of the same size whose contents can not be compared
+)
+
+var (
+	ErrInvalidDiffSource = errors.New("invalid diff source")
+)
+
+// TreeEntry is a file or directory of a tree to diff, its path is from the root of
+// the tree. Files of a pod are told apart by the reference of their inode, which
+// only changes with the contents, and local files by their checksum.
+type TreeEntry struct {
+	Path             string `json:"path"`
+	Type             string `json:"type"`
+	Size             uint64 `json:"size,omitempty"`
+	ModificationTime int64  `json:"modification_time,omitempty"`
+	Reference        string `json:"reference,omitempty"`
+	Checksum         string `json:"checksum,omitempty"` // sha256 of the contents, missing for files uploaded without one
+}
+
+// DiffEntry is a change from one tree to the other. Moved entries have the path they
+// were moved from in OldPath, a moved directory stands for everything below it.
+type DiffEntry struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"`
+	Type    string `json:"type"`
+	Change  string `json:"change"`
+	OldSize string `json:"old_size,omitempty"`
+	Size    string `json:"size,omitempty"`
+}
+
+// Tree returns the files and directories below a directory of a pod or a snapshot,
+// given as "pod:<pod name>" or "snapshot:<snapshot name>". A pod has to be open.
+func (p *Pod) Tree(source, dirPath string) ([]*TreeEntry, error) {
+	kind, name := SplitDiffSource(source)
+	switch kind {
+	case DiffSourcePod:
+		if !p.isPodOpened(name) {
+			return nil, ErrPodNotOpened
+		}
+		podInfo, err := p.GetPodInfoFromPodMap(name)
+		if err != nil {
+			return nil, err
+		}
+		return podTree(podInfo, dirPath)
+	case DiffSourceSnapshot:
+		snapshot, manifest, err := p.loadManifest(name)
+		if err != nil {
+			return nil, err
+		}
+		podInfo, err := p.snapshotInfo(snapshot, manifest)
+		if err != nil {
+			return nil, err
+		}
+		podInfo.GetDirectory().AddToDirectoryMap(manifest.PodName, podInfo.GetCurrentPodInode())
+		err = podInfo.SyncPod(manifest.PodName, p.client, p.logger)
+		if err != nil {
+			return nil, err
+		}
+		return podTree(podInfo, dirPath)
+	default:
+		return nil, ErrInvalidDiffSource
+	}
+}
+
+// Diff compares the trees below the same directory of two pods or snapshots.
+func (p *Pod) Diff(from, to, dirPath string) ([]*DiffEntry, error) {
+	fromTree, err := p.Tree(from, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := p.Tree(to, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return DiffTrees(fromTree, toTree), nil
+}
+
+// SplitDiffSource splits a diff source in to its kind and name.
+func SplitDiffSource(source string) (string, string) {
+	i := strings.Index(source, ":")
+	if i < 0 {
+		return "", source
+	}
+	return source[:i], source[i+1:]
+}
+
+// podTree lists the files and directories the pod has in its caches below dirPath.
+func podTree(podInfo *Info, dirPath string) ([]*TreeEntry, error) {
+	root := podInfo.GetCurrentPodPathAndName()
+	if dirPath != "" && dirPath != utils.PathSeperator {
+		root += gopath.Clean(utils.PathSeperator + dirPath)
+	}
+	directory := podInfo.GetDirectory()
+	if directory.GetDirFromDirectoryMap(root) == nil {
+		return nil, fmt.Errorf("directory not present")
+	}
+
+	var tree []*TreeEntry
+	for path, dirInode := range directory.DirectoriesBelow(root) {
+		tree = append(tree, &TreeEntry{
+			Path:             strings.TrimPrefix(path, root),
+			Type:             dir.ListTypeDir,
+			ModificationTime: dirInode.Meta.ModificationTime,
+		})
+	}
+	file := podInfo.getFile()
+	for _, filePath := range file.ListFiles(root + utils.PathSeperator) {
+		meta := file.GetFromFileMap(filePath)
+		if meta == nil {
+			continue
+		}
+		tree = append(tree, &TreeEntry{
+			Path:             strings.TrimPrefix(filePath, root),
+			Type:             dir.ListTypeFile,
+			Size:             meta.FileSize,
+			ModificationTime: meta.ModificationTime,
+			Reference:        hex.EncodeToString(meta.InodeAddress),
+			Checksum:         hex.EncodeToString(meta.Checksum),
+		})
+	}
+	sort.Slice(tree, func(i, j int) bool { return tree[i].Path < tree[j].Path })
+	return tree, nil
+}
+
+// LocalTree lists the files and directories below a local directory, with the
+// checksums of the files. Anything but regular files and directories is left out.
+func LocalTree(root string) ([]*TreeEntry, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	var tree []*TreeEntry
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root || (!info.IsDir() && !info.Mode().IsRegular()) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entry := &TreeEntry{
+			Path:             utils.PathSeperator + filepath.ToSlash(rel),
+			Type:             dir.ListTypeDir,
+			ModificationTime: info.ModTime().Unix(),
+		}
+		if !info.IsDir() {
+			entry.Type = dir.ListTypeFile
+			entry.Size = uint64(info.Size())
+			entry.Checksum, err = fileChecksum(path)
+			if err != nil {
+				return err
+			}
+		}
+		tree = append(tree, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func fileChecksum(path string) (string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DiffTrees returns the changes which turn the tree from in to the tree to, sorted by
+// path. An entry which is gone from one place and has the same contents in another
+// is moved, for directories everything below them has to be the same.
+func DiffTrees(from, to []*TreeEntry) []*DiffEntry {
+	fromMap := treeMap(from)
+	toMap := treeMap(to)
+
+	var diffs []*DiffEntry
+	var removed, added []*TreeEntry
+	for _, entry := range sortedTree(from) {
+		other, ok := toMap[entry.Path]
+		switch {
+		case !ok || other.Type != entry.Type:
+			removed = append(removed, entry)
+		case entry.Type == dir.ListTypeFile && !sameContent(entry, other):
+			diffs = append(diffs, &DiffEntry{
+				Path:    entry.Path,
+				Type:    dir.ListTypeFile,
+				Change:  contentChange(entry, other),
+				OldSize: strconv.FormatUint(entry.Size, 10),
+				Size:    strconv.FormatUint(other.Size, 10),
+			})
+		}
+	}
+	for _, entry := range sortedTree(to) {
+		if other, ok := fromMap[entry.Path]; !ok || other.Type != entry.Type {
+			added = append(added, entry)
+		}
+	}
+
+	// directories first, so the entries below a moved one are not reported again
+	gone := make(map[string]bool)
+	came := make(map[string]bool)
+	for _, oldDir := range removed {
+		if oldDir.Type != dir.ListTypeDir || gone[oldDir.Path] {
+			continue
+		}
+		for _, newDir := range added {
+			if newDir.Type != dir.ListTypeDir || came[newDir.Path] ||
+				!sameSubtree(subtree(from, oldDir.Path), subtree(to, newDir.Path)) {
+				continue
+			}
+			diffs = append(diffs, &DiffEntry{
+				Path:    newDir.Path,
+				OldPath: oldDir.Path,
+				Type:    dir.ListTypeDir,
+				Change:  DiffMoved,
+			})
+			gone[oldDir.Path] = true
+			for rel := range subtree(from, oldDir.Path) {
+				gone[oldDir.Path+rel] = true
+			}
+			came[newDir.Path] = true
+			for rel := range subtree(to, newDir.Path) {
+				came[newDir.Path+rel] = true
+			}
+			break
+		}
+	}
+
+	candidates := make(map[string][]*TreeEntry)
+	for _, entry := range added {
+		if entry.Type != dir.ListTypeFile || came[entry.Path] {
+			continue
+		}
+		for _, key := range contentKeys(entry) {
+			candidates[key] = append(candidates[key], entry)
+		}
+	}
+	for _, entry := range removed {
+		if entry.Type != dir.ListTypeFile || gone[entry.Path] {
+			continue
+		}
+		for _, key := range contentKeys(entry) {
+			var moved *TreeEntry
+			for _, candidate := range candidates[key] {
+				if !came[candidate.Path] && sameContent(entry, candidate) {
+					moved = candidate
+					break
+				}
+			}
+			if moved == nil {
+				continue
+			}
+			diffs = append(diffs, &DiffEntry{
+				Path:    moved.Path,
+				OldPath: entry.Path,
+				Type:    dir.ListTypeFile,
+				Change:  DiffMoved,
+				Size:    strconv.FormatUint(moved.Size, 10),
+			})
+			gone[entry.Path] = true
+			came[moved.Path] = true
+			break
+		}
+	}
+
+	for _, entry := range removed {
+		if gone[entry.Path] {
+			continue
+		}
+		diff := &DiffEntry{Path: entry.Path, Type: entry.Type, Change: DiffRemoved}
+		if entry.Type == dir.ListTypeFile {
+			diff.OldSize = strconv.FormatUint(entry.Size, 10)
+		}
+		diffs = append(diffs, diff)
+	}
+	for _, entry := range added {
+		if came[entry.Path] {
+			continue
+		}
+		diff := &DiffEntry{Path: entry.Path, Type: entry.Type, Change: DiffAdded}
+		if entry.Type == dir.ListTypeFile {
+			diff.Size = strconv.FormatUint(entry.Size, 10)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// sameContent tells if two files surely have the same contents.
+func sameContent(a, b *TreeEntry) bool {
+	return contentChange(a, b) == ""
+}
+
+// contentChange tells how the contents of two files compare, it is empty when they are
+// the same. A local file and a pod file without a checksum, like one finished through
+// an upload session or changed by a write, are DiffUnknown if their sizes match.
+func contentChange(a, b *TreeEntry) string {
+	if a.Reference != "" && a.Reference == b.Reference {
+		return ""
+	}
+	if a.Size != b.Size {
+		return DiffModified
+	}
+	if a.Checksum != "" && b.Checksum != "" {
+		if a.Checksum == b.Checksum {
+			return ""
+		}
+		return DiffModified
+	}
+	if a.Reference == "" || b.Reference == "" {
+		return DiffUnknown
+	}
+	return DiffModified
+}
+
+// contentKeys are the keys a moved file is found by in the other tree.
+func contentKeys(entry *TreeEntry) []string {
+	var keys []string
+	if entry.Reference != "" {
+		keys = append(keys, "ref:"+entry.Reference)
+	}
+	if entry.Checksum != "" {
+		keys = append(keys, "sum:"+entry.Checksum)
+	}
+	return keys
+}
+
+// subtree returns the entries below dirPath by their path relative to it.
+func subtree(tree []*TreeEntry, dirPath string) map[string]*TreeEntry {
+	entries := make(map[string]*TreeEntry)
+	for _, entry := range tree {
+		if strings.HasPrefix(entry.Path, dirPath+utils.PathSeperator) {
+			entries[strings.TrimPrefix(entry.Path, dirPath)] = entry
+		}
+	}
+	return entries
+}
+
+// sameSubtree tells if two directories hold the same entries, empty ones never do.
+func sameSubtree(a, b map[string]*TreeEntry) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for rel, entry := range a {
+		other, ok := b[rel]
+		if !ok || other.Type != entry.Type ||
+			(entry.Type == dir.ListTypeFile && !sameContent(entry, other)) {
+			return false
+		}
+	}
+	return true
+}
+
+func treeMap(tree []*TreeEntry) map[string]*TreeEntry {
+	entries := make(map[string]*TreeEntry, len(tree))
+	for _, entry := range tree {
+		entries[entry.Path] = entry
+	}
+	return entries
+}
+
+func sortedTree(tree []*TreeEntry) []*TreeEntry {
+	sorted := make([]*TreeEntry, len(tree))
+	copy(sorted, tree)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+func TestPod_Diff(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	for _, name := range []string{"/docs", "/docs/old"} {
+		err = pod1.MakeDir(podName1, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	contents := map[string][]byte{
+		"/a":          []byte("contents of a"),
+		"/x":          []byte("contents of x"),
+		"/docs/b":     []byte("contents of b"),
+		"/docs/old/c": []byte("contents of c"),
+	}
+	for podFile, content := range contents {
+		_, err = pod1.UploadFile(context.Background(), podName1, filepath.Base(podFile), int64(len(content)), bytes.NewReader(content), filepath.Dir(podFile), "1024", "", "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+	}
+	_, err = pod1.CreateSnapshot(podName1, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		changes, err := pod1.Diff("snapshot:s1", "pod:"+podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 0 {
+			t.Fatalf("unexpected changes %+v", changes[0])
+		}
+	})
+
+	t.Run("snapshot_and_pod", func(t *testing.T) {
+		err := pod1.AppendFile(context.Background(), podName1, "/a", bytes.NewReader([]byte(" and more")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.Move(podName1, "/docs/old", "/archive")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.Move(podName1, "/docs/b", "/b2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.RemoveFile(podName1, "/x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := []byte("new")
+		_, err = pod1.UploadFile(context.Background(), podName1, "new", int64(len(content)), bytes.NewReader(content), "/docs", "1024", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err := pod1.Diff("snapshot:s1", "pod:"+podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []DiffEntry{
+			{Path: "/a", Type: dir.ListTypeFile, Change: DiffModified},
+			{Path: "/archive", OldPath: "/docs/old", Type: dir.ListTypeDir, Change: DiffMoved},
+			{Path: "/b2", OldPath: "/docs/b", Type: dir.ListTypeFile, Change: DiffMoved},
+			{Path: "/docs/new", Type: dir.ListTypeFile, Change: DiffAdded},
+			{Path: "/x", Type: dir.ListTypeFile, Change: DiffRemoved},
+		}
+		assertChanges(t, changes, expected)
+		if changes[0].OldSize != "13" || changes[0].Size != "22" {
+			t.Fatalf("invalid sizes of modified file %+v", changes[0])
+		}
+
+		// the other way round
+		changes, err = pod1.Diff("pod:"+podName1, "snapshot:s1", "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = []DiffEntry{
+			{Path: "/a", Type: dir.ListTypeFile, Change: DiffModified},
+			{Path: "/docs/b", OldPath: "/b2", Type: dir.ListTypeFile, Change: DiffMoved},
+			{Path: "/docs/new", Type: dir.ListTypeFile, Change: DiffRemoved},
+			{Path: "/docs/old", OldPath: "/archive", Type: dir.ListTypeDir, Change: DiffMoved},
+			{Path: "/x", Type: dir.ListTypeFile, Change: DiffAdded},
+		}
+		assertChanges(t, changes, expected)
+
+		// below a directory
+		changes, err = pod1.Diff("snapshot:s1", "pod:"+podName1, "/docs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = []DiffEntry{
+			{Path: "/b", Type: dir.ListTypeFile, Change: DiffRemoved},
+			{Path: "/new", Type: dir.ListTypeFile, Change: DiffAdded},
+			{Path: "/old", Type: dir.ListTypeDir, Change: DiffRemoved},
+			{Path: "/old/c", Type: dir.ListTypeFile, Change: DiffRemoved},
+		}
+		assertChanges(t, changes, expected)
+	})
+
+	t.Run("pod_and_local", func(t *testing.T) {
+		localDir := t.TempDir()
+		files := map[string]string{
+			"a":           "contents of a and more",
+			"b2":          "changed",
+			"docs/moved":  "new",
+			"archive/c":   "contents of c",
+			"local/extra": "extra",
+		}
+		for name, content := range files {
+			localFile := filepath.Join(localDir, filepath.FromSlash(name))
+			err := os.MkdirAll(filepath.Dir(localFile), 0700)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ioutil.WriteFile(localFile, []byte(content), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		localTree, err := LocalTree(localDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		podTree, err := pod1.Tree("pod:"+podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// /a has no checksum since it was appended to, so only its size can be compared
+		changes := DiffTrees(podTree, localTree)
+		expected := []DiffEntry{
+			{Path: "/a", Type: dir.ListTypeFile, Change: DiffUnknown},
+			{Path: "/b2", Type: dir.ListTypeFile, Change: DiffModified},
+			{Path: "/docs/moved", OldPath: "/docs/new", Type: dir.ListTypeFile, Change: DiffMoved},
+			{Path: "/local", Type: dir.ListTypeDir, Change: DiffAdded},
+			{Path: "/local/extra", Type: dir.ListTypeFile, Change: DiffAdded},
+		}
+		assertChanges(t, changes, expected)
+	})
+
+	t.Run("missing_checksum", func(t *testing.T) {
+		// a pod file finished through an upload session has no checksum
+		podTree := []*TreeEntry{
+			{Path: "/a", Type: dir.ListTypeFile, Size: 5, Reference: "01"},
+			{Path: "/b", Type: dir.ListTypeFile, Size: 5, Reference: "02"},
+		}
+		localTree := []*TreeEntry{
+			{Path: "/a", Type: dir.ListTypeFile, Size: 5, Checksum: "03"},
+			{Path: "/b", Type: dir.ListTypeFile, Size: 6, Checksum: "04"},
+		}
+		changes := DiffTrees(podTree, localTree)
+		expected := []DiffEntry{
+			{Path: "/a", Type: dir.ListTypeFile, Change: DiffUnknown},
+			{Path: "/b", Type: dir.ListTypeFile, Change: DiffModified},
+		}
+		assertChanges(t, changes, expected)
+	})
+
+	t.Run("invalid_source", func(t *testing.T) {
+		_, err := pod1.Diff("s1", "pod:"+podName1, "/")
+		if err != ErrInvalidDiffSource {
+			t.Fatalf("expected %v, got %v", ErrInvalidDiffSource, err)
+		}
+		_, err = pod1.Diff("snapshot:s2", "pod:"+podName1, "/")
+		if err != ErrSnapshotNotFound {
+			t.Fatalf("expected %v, got %v", ErrSnapshotNotFound, err)
+		}
+		_, err = pod1.Diff("snapshot:s1", "pod:test2", "/")
+		if err != ErrPodNotOpened {
+			t.Fatalf("expected %v, got %v", ErrPodNotOpened, err)
+		}
+	})
+}
+
+func assertChanges(t *testing.T, changes []*DiffEntry, expected []DiffEntry) {
+	t.Helper()
+	if len(changes) != len(expected) {
+		for _, change := range changes {
+			t.Logf("%+v", change)
+		}
+		t.Fatalf("expected %d changes, got %d", len(expected), len(changes))
+	}
+	for i, change := range changes {
+		if change.Path != expected[i].Path || change.OldPath != expected[i].OldPath ||
+			change.Type != expected[i].Type || change.Change != expected[i].Change {
+			t.Fatalf("expected %+v, got %+v", expected[i], change)
+		}
+	}
+}
@@ -17,13 +17,14 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
-func (p *Pod) DownloadFile(podName, podFile string) (io.ReadCloser, string, string, error) {
+func (p *Pod) DownloadFile(ctx context.Context, podName, podFile string) (io.ReadCloser, string, string, error) {
 	if !p.isPodOpened(podName) {
 		return nil, "", "", fmt.Errorf("login to pod to do this operation")
 	}
@@ -44,7 +45,7 @@ func (p *Pod) DownloadFile(podName, podFile string) (io.ReadCloser, string, stri
 		return nil, "", "", fmt.Errorf("file not present in pod")
 	}
 
-	reader, ref, size, err := podInfo.getFile().Download(path)
+	reader, ref, size, err := podInfo.getFile().Download(ctx, path)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"fmt"
+	gopath "path"
+	"strings"
+
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// DiskUsage is the storage taken by the files and directories below a directory,
+// at any depth.
+type DiskUsage struct {
+	Path        string `json:"path,omitempty"`
+	Directories uint64 `json:"directories"`
+	file.Usage
+}
+
+// UserDiskUsage is the storage taken by each pod of a user and by all of them.
+type UserDiskUsage struct {
+	Pods  map[string]*DiskUsage `json:"pods"`
+	Total *DiskUsage            `json:"total"`
+}
+
+// DiskUsage adds up the usage of the files below a directory of the pod, the root
+// of the pod if dirPath is empty. The usage of every file is cached, so asking again
+// only reads the inodes of the files changed since.
+func (p *Pod) DiskUsage(podName, dirPath string) (*DiskUsage, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	path := gopath.Clean(utils.PathSeperator + dirPath)
+	root := strings.TrimSuffix(podInfo.GetCurrentPodPathAndName()+path, utils.PathSeperator)
+	directory := podInfo.GetDirectory()
+	if directory.GetDirFromDirectoryMap(root) == nil {
+		return nil, fmt.Errorf("directory not present")
+	}
+
+	usage := &DiskUsage{
+		Path:        path,
+		Directories: uint64(len(directory.DirectoriesBelow(root))),
+	}
+	counter := file.NewUsageCounter()
+	podFile := podInfo.getFile()
+	for _, filePath := range podFile.ListFiles(root + utils.PathSeperator) {
+		fileUsage, err := podFile.FileUsage(filePath, p.usage)
+		if err != nil {
+			return nil, err
+		}
+		counter.Add(fileUsage)
+	}
+	usage.Usage = counter.Usage
+	return usage, nil
+}
+
+// UserDiskUsage adds up the usage of every pod of the user. The pods which are not
+// open are read from their directory feeds with the password, without opening them.
+func (p *Pod) UserDiskUsage(passPhrase string) (*UserDiskUsage, error) {
+	pods, _, err := p.loadUserPods()
+	if err != nil {
+		return nil, err
+	}
+
+	userUsage := &UserDiskUsage{
+		Pods:  make(map[string]*DiskUsage),
+		Total: &DiskUsage{},
+	}
+	total := file.NewUsageCounter()
+	for index, podName := range pods {
+		podName = strings.Trim(podName, "\n")
+		if podName == "" {
+			continue
+		}
+
+		usage := &DiskUsage{Path: utils.PathSeperator}
+		counter := file.NewUsageCounter()
+		add := func(fileUsage *file.Usage) {
+			counter.Add(fileUsage)
+			total.Add(fileUsage)
+		}
+		if podInfo, err := p.GetPodInfoFromPodMap(podName); err == nil {
+			root := podInfo.GetCurrentPodPathAndName()
+			usage.Directories = uint64(len(podInfo.GetDirectory().DirectoriesBelow(root)))
+			podFile := podInfo.getFile()
+			for _, filePath := range podFile.ListFiles(root + utils.PathSeperator) {
+				fileUsage, err := podFile.FileUsage(filePath, p.usage)
+				if err != nil {
+					return nil, err
+				}
+				add(fileUsage)
+			}
+		} else {
+			usage.Directories, err = p.storedPodUsage(index, podName, passPhrase, add)
+			if err != nil {
+				return nil, err
+			}
+		}
+		usage.Usage = counter.Usage
+
+		userUsage.Pods[podName] = usage
+		userUsage.Total.Directories += usage.Directories
+	}
+	userUsage.Total.Usage = total.Usage
+	return userUsage, nil
+}
+
+// storedPodUsage reads the tree of a pod which is not open from Swarm and passes the
+// usage of every file in it to add. It returns the number of directories in the pod.
+func (p *Pod) storedPodUsage(index int, podName, passPhrase string, add func(*file.Usage)) (uint64, error) {
+	err := p.acc.CreatePodAccount(index, passPhrase, false)
+	if err != nil {
+		return 0, err
+	}
+	accountInfo, err := p.acc.GetPodAccountInfo(index)
+	if err != nil {
+		return 0, err
+	}
+	podFile := file.NewFile(podName, p.client, p.fd, accountInfo, p.logger)
+	directory := d.NewDirectory(podName, p.client, p.fd, accountInfo, podFile, p.logger)
+	root := utils.PathSeperator + podName
+	_, dirInode, err := directory.GetDirNode(root, p.fd, accountInfo)
+	if err != nil {
+		return 0, err
+	}
+	tree, err := directory.LoadTree(root, dirInode)
+	if err != nil {
+		return 0, err
+	}
+
+	var walk func(tree *d.Tree) error
+	walk = func(tree *d.Tree) error {
+		for _, ref := range tree.Files {
+			fileUsage, err := podFile.MetaUsage(ref, p.usage)
+			if err != nil {
+				return err
+			}
+			add(fileUsage)
+		}
+		for _, child := range tree.Dirs {
+			err := walk(child)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = walk(tree)
+	if err != nil {
+		return 0, err
+	}
+	_, dirs := tree.Count()
+	return uint64(dirs - 1), nil
+}
@@ -0,0 +1,191 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+func TestPod_DiskUsage(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podName2 := "test2"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	for _, name := range []string{"/docs", "/docs/old"} {
+		err = pod1.MakeDir(podName1, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []struct {
+		dir, name, compression string
+		size                   int
+	}{
+		{"/", "a", "", 2000},
+		{"/docs", "b", "snappy", 500},
+		{"/docs/old", "c", "", 300},
+	}
+	for _, f := range files {
+		content := bytes.Repeat([]byte("x"), f.size)
+		_, err = pod1.UploadFile(context.Background(), podName1, f.name, int64(f.size), bytes.NewReader(content), f.dir, "1024", f.compression, "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+	}
+
+	t.Run("pod", func(t *testing.T) {
+		usage, err := pod1.DiskUsage(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Path != "/" || usage.Directories != 2 || usage.Files != 3 || usage.Blocks != 4 || usage.Size != 2800 {
+			t.Fatalf("unexpected usage %+v", usage)
+		}
+		if usage.CompressedSize == 0 || usage.CompressedSize >= usage.Size {
+			t.Fatalf("unexpected compressed size %d", usage.CompressedSize)
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		usage, err := pod1.DiskUsage(podName1, "docs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Path != "/docs" || usage.Directories != 1 || usage.Files != 2 || usage.Blocks != 2 || usage.Size != 800 {
+			t.Fatalf("unexpected usage %+v", usage)
+		}
+
+		_, err = pod1.DiskUsage(podName1, "/missing")
+		if err == nil {
+			t.Fatalf("usage of a missing directory")
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		// the inode of a counted file is not read again
+		podInfo, err := pod1.GetPodInfoFromPodMap(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := podInfo.getFile().GetFromFileMap("/" + podName1 + "/a")
+		err = mockClient.DeleteBlob(meta.InodeAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		usage, err := pod1.DiskUsage(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Size != 2800 {
+			t.Fatalf("unexpected size %d", usage.Size)
+		}
+
+		// a changed file is counted again
+		err = pod1.AppendFile(context.Background(), podName1, "/docs/old/c", bytes.NewReader(bytes.Repeat([]byte("y"), 1000)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		usage, err = pod1.DiskUsage(podName1, "/docs/old")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Files != 1 || usage.Blocks != 2 || usage.Size != 1300 {
+			t.Fatalf("unexpected usage %+v", usage)
+		}
+	})
+
+	t.Run("copies", func(t *testing.T) {
+		// the blocks of a copy are stored once
+		before, err := pod1.DiskUsage(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.Copy(podName1, "/a", podName1, "/docs/a2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		usage, err := pod1.DiskUsage(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage.Files != before.Files+1 || usage.Size != before.Size+2000 ||
+			usage.Blocks != before.Blocks || usage.CompressedSize != before.CompressedSize {
+			t.Fatalf("unexpected usage %+v, was %+v", usage, before)
+		}
+		err = pod1.RemoveFile(podName1, "/docs/a2")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("user", func(t *testing.T) {
+		_, err := pod1.CreatePod(podName2, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName2)
+		}
+		err = pod1.MakeDir(podName2, "/e")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := bytes.Repeat([]byte("z"), 100)
+		_, err = pod1.UploadFile(context.Background(), podName2, "d", int64(len(content)), bytes.NewReader(content), "/e", "1024", "", "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		err = pod1.ClosePod(podName2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		usage, err := pod1.UserDiskUsage("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(usage.Pods) != 2 || usage.Pods[podName1].Size != 3800 || usage.Pods[podName2].Size != 100 {
+			t.Fatalf("unexpected pods %+v", usage.Pods)
+		}
+		if usage.Pods[podName2].Files != 1 || usage.Pods[podName2].Directories != 1 || usage.Pods[podName2].Blocks != 1 {
+			t.Fatalf("unexpected usage %+v", usage.Pods[podName2])
+		}
+		if usage.Total.Files != 4 || usage.Total.Directories != 3 || usage.Total.Size != 3900 {
+			t.Fatalf("unexpected total %+v", usage.Total)
+		}
+		if pod1.isPodOpened(podName2) || !pod1.isPodOpened(podName1) {
+			t.Fatalf("pods left open or closed")
+		}
+	})
+}
@@ -27,4 +27,13 @@ var (
 	ErrInvalidDirectory     = errors.New("invalid directory name")
 	ErrTooLongDirectoryName = errors.New("directory name too long")
 	ErrReadOnlyPod          = errors.New("operation not permitted: read only pod")
+	ErrDirectoryNotEmpty    = errors.New("directory not empty")
+	ErrFileOrDirNotFound    = errors.New("no such file or directory")
+	ErrDestinationExists    = errors.New("destination already exists")
+	ErrMoveIntoItself       = errors.New("can not move a directory into itself")
+	ErrCopyIntoItself       = errors.New("can not copy a directory into itself")
+	ErrInvalidSnapshotName  = errors.New("invalid snapshot name")
+	ErrSnapshotExists       = errors.New("snapshot already exists")
+	ErrSnapshotNotFound     = errors.New("snapshot not found")
+	ErrSnapshotPodOpened    = errors.New("the pod of the snapshot is open")
 )
@@ -0,0 +1,137 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"errors"
+	"fmt"
+	gopath "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+var (
+	ErrInvalidFindOption = errors.New("invalid find option")
+)
+
+// FindOptions are the conditions a file or directory has to meet to be found, all of
+// the ones which are set.
+type FindOptions struct {
+	Path           string // directory to search below, the root of the pod if empty
+	Name           string // glob the name matches, in the syntax of path.Match
+	Type           string // dir.ListTypeFile or dir.ListTypeDir, empty for both
+	ContentType    string // prefix of the content type, like "image/"
+	MinSize        uint64 // directories have no size and are not found by size
+	MaxSize        uint64 // no upper bound if 0
+	ModifiedAfter  int64  // unix time, no bound if 0
+	ModifiedBefore int64  // unix time, no bound if 0
+	Limit          int    // results returned at most, all of them if 0
+}
+
+// FindResult is a file or directory found, its path is from the root of the pod.
+type FindResult struct {
+	Path             string `json:"path"`
+	Type             string `json:"type"`
+	ContentType      string `json:"content_type"`
+	Size             string `json:"size,omitempty"`
+	ModificationTime string `json:"modification_time"`
+}
+
+// Find searches the files and directories below a directory of the pod. The whole
+// tree of a pod is loaded in to its caches when it is opened, so they are searched
+// without going to Swarm. The results are sorted by path, truncated tells if there
+// were more than the limit.
+func (p *Pod) Find(podName string, opts *FindOptions) ([]*FindResult, bool, error) {
+	if !p.isPodOpened(podName) {
+		return nil, false, ErrPodNotOpened
+	}
+	if _, err := gopath.Match(opts.Name, ""); err != nil {
+		return nil, false, ErrInvalidFindOption
+	}
+	if (opts.Type != "" && opts.Type != dir.ListTypeFile && opts.Type != dir.ListTypeDir) ||
+		opts.Limit < 0 || (opts.MaxSize > 0 && opts.MaxSize < opts.MinSize) {
+		return nil, false, ErrInvalidFindOption
+	}
+
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, false, err
+	}
+	root := podInfo.GetCurrentPodPathAndName()
+	if opts.Path != "" && opts.Path != utils.PathSeperator {
+		root += gopath.Clean(utils.PathSeperator + opts.Path)
+	}
+	directory := podInfo.GetDirectory()
+	if directory.GetDirFromDirectoryMap(root) == nil {
+		return nil, false, fmt.Errorf("directory not present")
+	}
+
+	sized := opts.MinSize > 0 || opts.MaxSize > 0
+	match := func(name, contentType string, mtime int64) bool {
+		if opts.Name != "" {
+			if ok, _ := gopath.Match(opts.Name, name); !ok {
+				return false
+			}
+		}
+		return strings.HasPrefix(contentType, opts.ContentType) &&
+			(opts.ModifiedAfter == 0 || mtime >= opts.ModifiedAfter) &&
+			(opts.ModifiedBefore == 0 || mtime <= opts.ModifiedBefore)
+	}
+
+	var results []*FindResult
+	if opts.Type != dir.ListTypeFile && !sized {
+		for dirPath, dirInode := range directory.DirectoriesBelow(root) {
+			meta := dirInode.Meta
+			if !match(gopath.Base(dirPath), dir.MineTypeDirectory, meta.ModificationTime) {
+				continue
+			}
+			results = append(results, &FindResult{
+				Path:             strings.TrimPrefix(dirPath, podInfo.GetCurrentPodPathAndName()),
+				Type:             dir.ListTypeDir,
+				ContentType:      dir.MineTypeDirectory,
+				ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
+			})
+		}
+	}
+	if opts.Type != dir.ListTypeDir {
+		file := podInfo.getFile()
+		for _, filePath := range file.ListFiles(root + utils.PathSeperator) {
+			meta := file.GetFromFileMap(filePath)
+			if meta == nil || !match(meta.Name, meta.ContentType, meta.ModificationTime) ||
+				meta.FileSize < opts.MinSize || (opts.MaxSize > 0 && meta.FileSize > opts.MaxSize) {
+				continue
+			}
+			results = append(results, &FindResult{
+				Path:             strings.TrimPrefix(filePath, podInfo.GetCurrentPodPathAndName()),
+				Type:             dir.ListTypeFile,
+				ContentType:      meta.ContentType,
+				Size:             strconv.FormatUint(meta.FileSize, 10),
+				ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		return results[:opts.Limit], true, nil
+	}
+	return results, false, nil
+}
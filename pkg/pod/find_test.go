@@ -0,0 +1,141 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+func TestPod_Find(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	for _, name := range []string{"/docs", "/docs/old", "/pics"} {
+		err = pod1.MakeDir(podName1, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []struct {
+		dir, name string
+		content   []byte
+	}{
+		{"/", "readme.txt", bytes.Repeat([]byte("x"), 100)},
+		{"/docs", "a.txt", bytes.Repeat([]byte("x"), 2000)},
+		{"/docs/old", "b.txt", bytes.Repeat([]byte("x"), 500)},
+		{"/pics", "c.png", append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), make([]byte, 800)...)},
+	}
+	for _, f := range files {
+		_, err = pod1.UploadFile(context.Background(), podName1, f.name, int64(len(f.content)), bytes.NewReader(f.content), f.dir, "1024", "", "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+	}
+
+	find := func(t *testing.T, opts *FindOptions) (string, bool) {
+		results, truncated, err := pod1.Find(podName1, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, result := range results {
+			paths = append(paths, result.Path)
+		}
+		return strings.Join(paths, ","), truncated
+	}
+
+	t.Run("all", func(t *testing.T) {
+		got, truncated := find(t, &FindOptions{})
+		want := "/docs,/docs/a.txt,/docs/old,/docs/old/b.txt,/pics,/pics/c.png,/readme.txt"
+		if got != want || truncated {
+			t.Fatalf("found %s", got)
+		}
+	})
+
+	t.Run("predicates", func(t *testing.T) {
+		for _, tc := range []struct {
+			opts *FindOptions
+			want string
+		}{
+			{&FindOptions{Name: "*.txt"}, "/docs/a.txt,/docs/old/b.txt,/readme.txt"},
+			{&FindOptions{Type: dir.ListTypeDir}, "/docs,/docs/old,/pics"},
+			{&FindOptions{Type: dir.ListTypeFile, Name: "?.*"}, "/docs/a.txt,/docs/old/b.txt,/pics/c.png"},
+			{&FindOptions{MinSize: 400, MaxSize: 1000}, "/docs/old/b.txt,/pics/c.png"},
+			{&FindOptions{ContentType: "image/"}, "/pics/c.png"},
+			{&FindOptions{Path: "/docs", Name: "*.txt"}, "/docs/a.txt,/docs/old/b.txt"},
+			{&FindOptions{Path: "docs/old"}, "/docs/old/b.txt"},
+			{&FindOptions{Name: "*.txt", MaxSize: 1000}, "/docs/old/b.txt,/readme.txt"},
+			{&FindOptions{ModifiedBefore: 1}, ""},
+		} {
+			got, _ := find(t, tc.opts)
+			if got != tc.want {
+				t.Fatalf("options %+v: found %s", tc.opts, got)
+			}
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		got, truncated := find(t, &FindOptions{Name: "*.txt", Limit: 2})
+		if got != "/docs/a.txt,/docs/old/b.txt" || !truncated {
+			t.Fatalf("found %s, truncated %v", got, truncated)
+		}
+		_, truncated = find(t, &FindOptions{Name: "*.txt", Limit: 3})
+		if truncated {
+			t.Fatalf("truncated with all results")
+		}
+	})
+
+	t.Run("invalid-options", func(t *testing.T) {
+		for _, opts := range []*FindOptions{
+			{Name: "["},
+			{Type: "link"},
+			{Limit: -1},
+			{MinSize: 10, MaxSize: 5},
+		} {
+			_, _, err := pod1.Find(podName1, opts)
+			if err != ErrInvalidFindOption {
+				t.Fatalf("options %+v: expected invalid find option, got %v", opts, err)
+			}
+		}
+		_, _, err := pod1.Find(podName1, &FindOptions{Path: "/missing"})
+		if err == nil {
+			t.Fatalf("found in a missing directory")
+		}
+	})
+}
@@ -41,6 +41,7 @@ type Info struct {
 	curDirMu        sync.RWMutex
 	kvStore         *collection.KeyValue
 	docStore        *collection.Document
+	snapshot        string // name of the snapshot, if the pod is opened from one
 }
 
 func (i *Info) GetDirectory() *di.Directory {
@@ -109,6 +110,12 @@ func (i *Info) GetCurrentDirPathAndName() string {
 	return i.currentDirInode.Meta.Path + utils.PathSeperator + i.currentDirInode.Meta.Name
 }
 
+// GetSnapshot returns the name of the snapshot the pod is opened from, or an empty
+// string for a pod opened as it is now.
+func (i *Info) GetSnapshot() string {
+	return i.snapshot
+}
+
 func (i *Info) GetKVStore() *collection.KeyValue {
 	return i.kvStore
 }
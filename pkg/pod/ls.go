@@ -53,10 +53,29 @@ func (p *Pod) ListEntiesInDir(podName, dirName string) ([]dir.DirOrFileEntry, er
 	}
 
 	directory := info.GetDirectory()
-	printNames := false
+	path := lsPath(info, podName, dirName)
+	return directory.ListDir(podName, path, dirName == ""), nil
+}
+
+// ListDirPage lists a page of the entries of a directory of the pod, selected and
+// ordered by opts.
+func (p *Pod) ListDirPage(podName, dirName string, opts *dir.ListOptions) (*dir.ListPage, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+
+	info, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	return info.GetDirectory().ListDirPage(lsPath(info, podName, dirName), opts)
+}
+
+// lsPath returns the path of the directory to list, the current directory if dirName
+// is empty.
+func lsPath(info *Info, podName, dirName string) string {
 	path := dirName // dirname is supplied in API, in REPL it is picked up from the current dir
 	if path == "" {
-		printNames = true
 		path = info.GetCurrentDirPathAndName()
 		if info.IsCurrentDirRoot() {
 			path = info.GetCurrentPodPathAndName()
@@ -65,6 +84,5 @@ func (p *Pod) ListEntiesInDir(podName, dirName string) ([]dir.DirOrFileEntry, er
 		path = utils.PathSeperator + podName + path
 		path = strings.TrimSuffix(path, utils.PathSeperator)
 	}
-
-	return directory.ListDir(podName, path, printNames), nil
+	return path
 }
@@ -17,11 +17,16 @@ limitations under the License.
 package pod
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 )
@@ -71,3 +76,206 @@ func TestPod_ListPods(t *testing.T) {
 		}
 	})
 }
+
+// countingClient counts the blobs downloaded.
+type countingClient struct {
+	*mock.MockBeeClient
+	downloads int
+}
+
+func (c *countingClient) DownloadBlob(address []byte) ([]byte, int, error) {
+	c.downloads++
+	return c.MockBeeClient.DownloadBlob(address)
+}
+
+func TestPod_ListDirPage(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	for _, name := range []string{"dirB", "dirA"} {
+		err = pod1.MakeDir(podName1, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, size := range map[string]int{"a1": 300, "b2": 100, "a3": 200} {
+		content := bytes.Repeat([]byte("x"), size)
+		_, err = pod1.UploadFile(context.Background(), podName1, name, int64(size), bytes.NewReader(content), "/", "1024", "", "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+	}
+
+	list := func(t *testing.T, opts *dir.ListOptions) ([]dir.DirOrFileEntry, string) {
+		page, err := pod1.ListDirPage(podName1, "/", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.Entries, page.NextCursor
+	}
+	names := func(entries []dir.DirOrFileEntry) string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	t.Run("sort", func(t *testing.T) {
+		for _, tc := range []struct {
+			opts *dir.ListOptions
+			want string
+		}{
+			{&dir.ListOptions{}, "a1,a3,b2,dirA,dirB"},
+			{&dir.ListOptions{SortBy: dir.ListSortSize}, "dirA,dirB,b2,a3,a1"},
+			{&dir.ListOptions{SortBy: dir.ListSortSize, Reverse: true}, "a1,a3,b2,dirB,dirA"},
+			{&dir.ListOptions{Reverse: true}, "dirB,dirA,b2,a3,a1"},
+		} {
+			entries, cursor := list(t, tc.opts)
+			if names(entries) != tc.want || cursor != "" {
+				t.Fatalf("sorted by %q reverse %v: got %s", tc.opts.SortBy, tc.opts.Reverse, names(entries))
+			}
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		entries, _ := list(t, &dir.ListOptions{Prefix: "a"})
+		if names(entries) != "a1,a3" {
+			t.Fatalf("unexpected entries %s", names(entries))
+		}
+		entries, _ = list(t, &dir.ListOptions{Type: dir.ListTypeDir})
+		if names(entries) != "dirA,dirB" {
+			t.Fatalf("unexpected entries %s", names(entries))
+		}
+		entries, _ = list(t, &dir.ListOptions{Type: dir.ListTypeFile, Prefix: "b"})
+		if names(entries) != "b2" {
+			t.Fatalf("unexpected entries %s", names(entries))
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		opts := &dir.ListOptions{SortBy: dir.ListSortSize, Limit: 2}
+		entries, cursor := list(t, opts)
+		if names(entries) != "dirA,dirB" || cursor == "" {
+			t.Fatalf("unexpected first page %s", names(entries))
+		}
+
+		// the next page starts after the last entry, also when entries before it are gone
+		err := pod1.RemoveDir(podName1, "/dirA", false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Cursor = cursor
+		entries, cursor = list(t, opts)
+		if names(entries) != "b2,a3" || cursor == "" {
+			t.Fatalf("unexpected second page %s", names(entries))
+		}
+		opts.Cursor = cursor
+		entries, cursor = list(t, opts)
+		if names(entries) != "a1" || cursor != "" {
+			t.Fatalf("unexpected last page %s", names(entries))
+		}
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		entries, _ := list(t, &dir.ListOptions{Prefix: "a1"})
+		if len(entries) != 1 || entries[0].Size != "300" || entries[0].Reference != "" || entries[0].Checksum != "" {
+			t.Fatalf("unexpected entry %+v", entries)
+		}
+		entries, _ = list(t, &dir.ListOptions{Prefix: "a1", Detail: true})
+		if len(entries) != 1 || entries[0].Reference == "" || entries[0].Checksum == "" {
+			t.Fatalf("details missing in %+v", entries)
+		}
+	})
+
+	t.Run("invalid-options", func(t *testing.T) {
+		_, err := pod1.ListDirPage(podName1, "/", &dir.ListOptions{SortBy: "owner"})
+		if err != dir.ErrInvalidListOption {
+			t.Fatalf("expected invalid list option, got %v", err)
+		}
+		_, err = pod1.ListDirPage(podName1, "/", &dir.ListOptions{Cursor: "%%"})
+		if err != dir.ErrInvalidCursor {
+			t.Fatalf("expected invalid cursor, got %v", err)
+		}
+	})
+	t.Run("reverse-pages", func(t *testing.T) {
+		var got []string
+		opts := &dir.ListOptions{Reverse: true, Limit: 2}
+		for {
+			entries, cursor := list(t, opts)
+			got = append(got, names(entries))
+			if cursor == "" {
+				break
+			}
+			opts.Cursor = cursor
+		}
+		if strings.Join(got, ",") != "dirB,b2,a3,a1" {
+			t.Fatalf("unexpected pages %v", got)
+		}
+	})
+
+	t.Run("pages-read-only-the-page", func(t *testing.T) {
+		client := &countingClient{MockBeeClient: mock.NewMockBeeClient()}
+		acc := account.New(logger)
+		_, _, err := acc.CreateUserAccount("password", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pod2 := NewPod(client, feed.New(acc.GetUserAccountInfo(), client, logger), acc, logger)
+		_, err = pod2.CreatePod(podName1, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("file%02d", i)
+			_, err = pod2.UploadFile(context.Background(), podName1, name, 10, bytes.NewReader(make([]byte, 10)), "/", "1024", "", "")
+			if err != nil {
+				t.Fatalf("error uploading file: %v", err)
+			}
+		}
+
+		// the pod holds the names and metas of its files
+		client.downloads = 0
+		page, err := pod2.ListDirPage(podName1, "/", &dir.ListOptions{Limit: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(page.Entries) != "file00,file01,file02" || page.NextCursor == "" {
+			t.Fatalf("unexpected first page %s", names(page.Entries))
+		}
+		if client.downloads > 3 {
+			t.Fatalf("%d blobs downloaded for a page of 3", client.downloads)
+		}
+
+		// a listing sorted by size reads the directory once for all its pages
+		opts := &dir.ListOptions{SortBy: dir.ListSortSize, Limit: 3}
+		page, err = pod2.ListDirPage(podName1, "/", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.downloads = 0
+		opts.Cursor = page.NextCursor
+		page, err = pod2.ListDirPage(podName1, "/", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(page.Entries) != "file03,file04,file05" {
+			t.Fatalf("unexpected second page %s", names(page.Entries))
+		}
+		if client.downloads != 0 {
+			t.Fatalf("%d blobs downloaded for the second page", client.downloads)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"strings"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	c "github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// MarkLiveReferences marks everything reachable from the pods of the user as live:
+// the files below every pod root, the snapshots of the pods and the manifests and
+// values of the key value tables and document dbs. Shared pods belong to other users
+// and are skipped.
+func (p *Pod) MarkLiveReferences(passPhrase string, mark func(reference []byte)) error {
+	pods, _, err := p.loadUserPods()
+	if err != nil {
+		return err
+	}
+
+	for index, podName := range pods {
+		podName = strings.Trim(podName, "\n")
+		if podName == "" {
+			continue
+		}
+
+		err = p.acc.CreatePodAccount(index, passPhrase, false)
+		if err != nil {
+			return err
+		}
+		accountInfo, err := p.acc.GetPodAccountInfo(index)
+		if err != nil {
+			return err
+		}
+		file := f.NewFile(podName, p.client, p.fd, accountInfo, p.logger)
+		dir := d.NewDirectory(podName, p.client, p.fd, accountInfo, file, p.logger)
+		_, podInode, err := dir.GetDirNode(utils.PathSeperator+podName, p.fd, accountInfo)
+		if err != nil {
+			return err
+		}
+		err = dir.MarkLiveReferences(podInode, p.fd, accountInfo, mark)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.markSnapshots(mark)
+	if err != nil {
+		return err
+	}
+
+	// the collections are stored under the user account, not under the pods
+	user := p.acc.GetAddress(account.UserAccountIndex)
+	userInfo := p.acc.GetUserAccountInfo()
+	kvStore := c.NewKeyValueStore(p.fd, userInfo, user, p.client, p.logger)
+	err = kvStore.MarkLiveReferences(mark)
+	if err != nil {
+		return err
+	}
+	docStore := c.NewDocumentStore(p.fd, userInfo, user, nil, p.client, p.logger)
+	return docStore.MarkLiveReferences(mark)
+}
+
+// markSnapshots marks the list of snapshots, their manifests and the files and
+// directories in them as live.
+func (p *Pod) markSnapshots(mark func(reference []byte)) error {
+	snapshots, listRef, err := p.loadSnapshots()
+	if err != nil {
+		return err
+	}
+	if listRef != nil {
+		mark(listRef)
+	}
+	for _, snapshot := range snapshots {
+		manifest, err := p.downloadManifest(snapshot)
+		if err != nil {
+			return err
+		}
+		ref, err := utils.ParseHexReference(snapshot.Reference)
+		if err != nil {
+			return err
+		}
+		mark(ref.Bytes())
+
+		info, err := p.snapshotInfo(snapshot, manifest)
+		if err != nil {
+			return err
+		}
+		err = info.GetDirectory().MarkLiveReferences(info.GetCurrentPodInode(), info.GetFeed(), info.GetAccountInfo(), mark)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
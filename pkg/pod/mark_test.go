@@ -0,0 +1,124 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_MarkLiveReferences(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	ledger := gc.NewLedger(t.TempDir())
+	client := gc.NewRecordingClient(mockClient, ledger, "user1", logger)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+	collector := gc.NewCollector(ledger, mockClient, 0, logger)
+
+	podName1 := "test1"
+	firstDir := "dir1"
+
+	t.Run("sweep_keeps_live_tree", func(t *testing.T) {
+		info, err := pod1.CreatePod(podName1, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		err = pod1.MakeDir(podName1, firstDir)
+		if err != nil {
+			t.Fatalf("error creating directory %s", firstDir)
+		}
+		dirPath := utils.PathSeperator + podName1 + utils.PathSeperator + firstDir
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, dirPath)
+
+		// every put replaces the manifest of the table, leaving the old one behind
+		kvStore := info.GetKVStore()
+		err = kvStore.CreateKVTable("kv_table_0", collection.StringIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_table_0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"key1", "key2", "key3"} {
+			err = kvStore.KVPut("kv_table_0", key, []byte("value of "+key))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		live := gc.NewLiveSet()
+		err = pod1.MarkLiveReferences("password", live.Mark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := collector.Sweep("user1", live, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Removed == 0 || report.Failed != 0 {
+			t.Fatalf("old manifests not removed: %+v", report)
+		}
+
+		// the file and the table are still intact
+		err = pod1.CopyToLocal(podName1, podFile, os.TempDir())
+		if err != nil {
+			t.Fatalf("error copying file to local dir %s", err.Error())
+		}
+		err = os.Remove(filepath.Join(os.TempDir(), filepath.Base(podFile)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, value, err := kvStore.KVGet("kv_table_0", "key2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "value of key2" {
+			t.Fatalf("invalid value %s", value)
+		}
+
+		// nothing is left to collect
+		live = gc.NewLiveSet()
+		err = pod1.MarkLiveReferences("password", live.Mark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err = collector.Sweep("user1", live, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Orphaned != 0 {
+			t.Fatalf("live blobs orphaned: %+v", report)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	gopath "path"
+	"strings"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// Move moves the file or directory src to dst within the pod. If dst is a directory
+// src is moved into it, otherwise src is renamed to dst, whose parent has to exist.
+// A file keeps its inode and blocks and only its meta is written again. A directory
+// is created again under its new path with everything below it, linked in place of
+// the old one and then the old directory inodes are deleted.
+func (p *Pod) Move(podName, src, dst string) error {
+	if !p.isPodOpened(podName) {
+		return ErrPodNotOpened
+	}
+
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return err
+	}
+
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return ErrReadOnlyPod
+	}
+
+	directory := podInfo.GetDirectory()
+
+	srcPath := p.getFilePath(gopath.Clean(src), podInfo)
+	isFile := podInfo.getFile().IsFileAlreadyPResent(srcPath)
+	if !isFile && directory.GetDirFromDirectoryMap(srcPath) == nil {
+		return ErrFileOrDirNotFound
+	}
+
+	dstPath := p.destinationPath(podInfo, srcPath, dst)
+	if !isFile && isInTree(srcPath, dstPath) {
+		return ErrMoveIntoItself
+	}
+	if dstPath == srcPath {
+		return nil
+	}
+	err = checkDestination(podInfo, dstPath)
+	if err != nil {
+		return err
+	}
+	dstParent := gopath.Dir(dstPath)
+	dstName := gopath.Base(dstPath)
+
+	if isFile {
+		meta := podInfo.getFile().GetFromFileMap(srcPath)
+		newReference, err := podInfo.getFile().Move(meta.MetaReference, dstParent, dstName)
+		if err != nil {
+			return err
+		}
+		return p.relink(podName, podInfo, gopath.Dir(srcPath), dstParent, meta.MetaReference, newReference)
+	}
+
+	if len(dstName) > utils.MaxDirectoryNameLength {
+		return ErrTooLongDirectoryName
+	}
+	// the cached inode may be stale, the one in Swarm tells what is in the directory
+	_, srcInode, err := directory.GetDirNode(srcPath, podInfo.GetFeed(), podInfo.GetAccountInfo())
+	if err != nil {
+		return err
+	}
+	tree, err := directory.MoveTree(srcPath, dstPath, srcInode)
+	if err != nil {
+		return err
+	}
+	err = p.relink(podName, podInfo, gopath.Dir(srcPath), dstParent, utils.HashString(srcPath), utils.HashString(dstPath))
+	if err != nil {
+		return err
+	}
+	err = directory.RemoveDirInodes(tree)
+	if err != nil {
+		return err
+	}
+
+	// follow the current directory if it was moved
+	curPath := podInfo.GetCurrentDirPathAndName()
+	if isInTree(srcPath, curPath) {
+		curInode := directory.GetDirFromDirectoryMap(dstPath + strings.TrimPrefix(curPath, srcPath))
+		if curInode != nil {
+			podInfo.SetCurrentDirInode(curInode)
+		}
+	}
+	return nil
+}
+
+// relink replaces oldEntry in the directory srcDir by newEntry in the directory dstDir
+// and updates the parents of both up to the pod. The new entry is linked before the
+// old one is unlinked, so an interrupted move leaves the entry in both directories
+// rather than in none.
+func (p *Pod) relink(podName string, podInfo *Info, srcDir, dstDir string, oldEntry, newEntry []byte) error {
+	if srcDir == dstDir {
+		return p.updateDirEntries(podName, podInfo, srcDir, replaceEntry(oldEntry, newEntry))
+	}
+	err := p.updateDirEntries(podName, podInfo, dstDir, addEntry(newEntry))
+	if err != nil {
+		return err
+	}
+	return p.updateDirEntries(podName, podInfo, srcDir, removeEntry(oldEntry))
+}
+
+func addEntry(entry []byte) func([][]byte) [][]byte {
+	return func(hashes [][]byte) [][]byte {
+		return append(hashes, entry)
+	}
+}
+
+func removeEntry(entry []byte) func([][]byte) [][]byte {
+	return func(hashes [][]byte) [][]byte {
+		var newHashes [][]byte
+		for _, hash := range hashes {
+			if !bytes.Equal(hash, entry) {
+				newHashes = append(newHashes, hash)
+			}
+		}
+		return newHashes
+	}
+}
+
+func replaceEntry(oldEntry, newEntry []byte) func([][]byte) [][]byte {
+	return func(hashes [][]byte) [][]byte {
+		for i, hash := range hashes {
+			if bytes.Equal(hash, oldEntry) {
+				hashes[i] = newEntry
+			}
+		}
+		return hashes
+	}
+}
+
+// destinationPath returns the path in the pod of podInfo which srcPath is moved or
+// copied to for dst: into dst if it is a directory, otherwise dst itself.
+func (p *Pod) destinationPath(podInfo *Info, srcPath, dst string) string {
+	dstPath := p.getFilePath(gopath.Clean(dst), podInfo)
+	if podInfo.GetDirectory().GetDirFromDirectoryMap(dstPath) != nil {
+		return dstPath + utils.PathSeperator + gopath.Base(srcPath)
+	}
+	return dstPath
+}
+
+// checkDestination checks that nothing is at dstPath in the pod of podInfo yet and that
+// its parent directory exists.
+func checkDestination(podInfo *Info, dstPath string) error {
+	directory := podInfo.GetDirectory()
+	if podInfo.getFile().IsFileAlreadyPResent(dstPath) || directory.GetDirFromDirectoryMap(dstPath) != nil {
+		return ErrDestinationExists
+	}
+	if directory.GetDirFromDirectoryMap(gopath.Dir(dstPath)) == nil {
+		return ErrFileOrDirNotFound
+	}
+	return nil
+}
+
+// isInTree tells if path is the directory dirPath or below it.
+func isInTree(dirPath, path string) bool {
+	return path == dirPath || strings.HasPrefix(path, dirPath+utils.PathSeperator)
+}
+
+func (p *Pod) updateDirEntries(podName string, podInfo *Info, path string, update func([][]byte) [][]byte) error {
+	directory := podInfo.GetDirectory()
+	_, dirInode, err := directory.GetDirNode(path, podInfo.GetFeed(), podInfo.GetAccountInfo())
+	if err != nil {
+		return err
+	}
+	dirInode.Hashes = update(dirInode.Hashes)
+	dirInode.Meta.ModificationTime = time.Now().Unix()
+	topic, err := directory.UpdateDirectory(dirInode)
+	if err != nil {
+		return err
+	}
+	return p.UpdateTillThePod(podName, directory, topic, path, true)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_Move(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	info, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	err = pod1.MakeDir(podName1, "dir1/dir2")
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+	file1 := createRandomFileInPod(t, 100, pod1, podName1, podPath)
+	file2 := createRandomFileInPod(t, 200, pod1, podName1, podPath+"/dir1/dir2")
+	inode1 := info.getFile().GetFromFileMap(podPath + file1).InodeAddress
+	inode2 := info.getFile().GetFromFileMap(podPath + file2).InodeAddress
+
+	t.Run("mv-file-rename", func(t *testing.T) {
+		err := pod1.Move(podName1, file1, "/renamed")
+		if err != nil {
+			t.Fatalf("error moving file: %v", err)
+		}
+		if info.getFile().IsFileAlreadyPResent(podPath + file1) {
+			t.Fatalf("old file still present")
+		}
+		meta := info.getFile().GetFromFileMap(podPath + "/renamed")
+		if meta == nil || meta.Name != "renamed" || meta.Path != podPath {
+			t.Fatalf("file not renamed")
+		}
+		if !bytes.Equal(meta.InodeAddress, inode1) {
+			t.Fatalf("file inode not reused")
+		}
+		assertEntries(t, info, podPath, "dir1", "renamed")
+		assertFileSize(t, pod1, podName1, "/renamed", 100)
+	})
+
+	t.Run("mv-file-into-dir", func(t *testing.T) {
+		err := pod1.Move(podName1, "/renamed", "/dir1")
+		if err != nil {
+			t.Fatalf("error moving file: %v", err)
+		}
+		if info.getFile().GetFromFileMap(podPath+"/dir1/renamed") == nil {
+			t.Fatalf("file not moved")
+		}
+		assertEntries(t, info, podPath, "dir1")
+		assertEntries(t, info, podPath+"/dir1", "dir2", "renamed")
+		assertFileSize(t, pod1, podName1, "/dir1/renamed", 100)
+	})
+
+	t.Run("mv-dir", func(t *testing.T) {
+		err := pod1.Move(podName1, "dir1", "moved")
+		if err != nil {
+			t.Fatalf("error moving directory: %v", err)
+		}
+
+		directory := info.GetDirectory()
+		if directory.GetDirFromDirectoryMap(podPath+"/dir1") != nil ||
+			directory.GetDirFromDirectoryMap(podPath+"/dir1/dir2") != nil {
+			t.Fatalf("old directories still cached")
+		}
+		if directory.GetDirFromDirectoryMap(podPath+"/moved/dir2") == nil {
+			t.Fatalf("moved directory not cached")
+		}
+		meta := info.getFile().GetFromFileMap(podPath + "/moved" + file2[len("/dir1"):])
+		if meta == nil || meta.Path != podPath+"/moved/dir2" {
+			t.Fatalf("file in moved directory not updated")
+		}
+		if !bytes.Equal(meta.InodeAddress, inode2) {
+			t.Fatalf("file inode not reused")
+		}
+		assertEntries(t, info, podPath, "moved")
+		assertEntries(t, info, podPath+"/moved", "dir2", "renamed")
+		assertFileSize(t, pod1, podName1, "/moved"+file2[len("/dir1"):], 200)
+	})
+
+	t.Run("mv-dir-back", func(t *testing.T) {
+		err := pod1.Move(podName1, "/moved/dir2", "/dir1")
+		if err != nil {
+			t.Fatalf("error moving directory: %v", err)
+		}
+		assertEntries(t, info, podPath, "moved", "dir1")
+		assertEntries(t, info, podPath+"/moved", "renamed")
+		assertFileSize(t, pod1, podName1, "/dir1"+file2[len("/dir1/dir2"):], 200)
+	})
+
+	t.Run("mv-errors", func(t *testing.T) {
+		err := pod1.Move(podName1, "/moved", "/moved/sub")
+		if !errors.Is(err, ErrMoveIntoItself) {
+			t.Fatalf("expected %v, got %v", ErrMoveIntoItself, err)
+		}
+		err = pod1.Move(podName1, "/missing", "/other")
+		if !errors.Is(err, ErrFileOrDirNotFound) {
+			t.Fatalf("expected %v, got %v", ErrFileOrDirNotFound, err)
+		}
+		err = pod1.Move(podName1, "/moved/renamed", "/missing/renamed")
+		if !errors.Is(err, ErrFileOrDirNotFound) {
+			t.Fatalf("expected %v, got %v", ErrFileOrDirNotFound, err)
+		}
+		file3 := createRandomFileInPod(t, 100, pod1, podName1, podPath)
+		err = pod1.Move(podName1, file3, "/moved/renamed")
+		if !errors.Is(err, ErrDestinationExists) {
+			t.Fatalf("expected %v, got %v", ErrDestinationExists, err)
+		}
+	})
+
+	// cleanup pod
+	err = pod1.DeletePod(podName1)
+	if err != nil {
+		t.Fatalf("could not delete pod")
+	}
+}
+
+func assertEntries(t *testing.T, info *Info, path string, names ...string) {
+	t.Helper()
+	entries := info.GetDirectory().ListDir("", path, false)
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		found[entry.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			t.Fatalf("%s not listed in %s", name, path)
+		}
+	}
+	if len(entries) != len(names) {
+		t.Fatalf("expected %d entries in %s, got %d", len(names), path, len(entries))
+	}
+}
+
+func assertFileSize(t *testing.T, pod1 *Pod, podName, podFile string, size int) {
+	t.Helper()
+	reader, _, _, err := pod1.DownloadFile(context.Background(), podName, podFile)
+	if err != nil {
+		t.Fatalf("error downloading %s: %v", podFile, err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != size {
+		t.Fatalf("expected %d bytes in %s, got %d", size, podFile, len(data))
+	}
+}
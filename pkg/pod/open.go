@@ -99,6 +99,8 @@ func (p *Pod) OpenPod(podName, passPhrase string) (*Info, error) {
 		if err != nil {
 			return nil, err
 		}
+		file.KeepBlobs(dirInode.Meta.Snapshots)
+		file.TrackVersions(dirInode.Meta.Versioning || dirInode.Meta.Versioned)
 		user = p.acc.GetAddress(account.UserAccountIndex)
 	}
 
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"crypto/rand"
 	"io/ioutil"
 	"os"
@@ -98,7 +99,7 @@ func TestPod_LoginPod(t *testing.T) {
 			t.Fatal(err)
 		}
 		defer fd.Close()
-		_, err = pod1.UploadFile(podName1, fileName, 540, fd, podDir, "100", "false")
+		_, err = pod1.UploadFile(context.Background(), podName1, fileName, 540, fd, podDir, "100", "false", "")
 		if err != nil {
 			t.Fatalf("upload failed: %s", err.Error())
 		}
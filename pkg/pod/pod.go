@@ -23,6 +23,7 @@ import (
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
@@ -39,6 +40,7 @@ type Pod struct {
 	client blockstore.Client
 	podMap map[string]*Info //  podName -> dir
 	podMu  *sync.RWMutex
+	usage  *file.UsageCache
 	logger logging.Logger
 }
 
@@ -49,6 +51,7 @@ func NewPod(client blockstore.Client, feed *feed.API, account *account.Account,
 		client: client,
 		podMap: make(map[string]*Info),
 		podMu:  &sync.RWMutex{},
+		usage:  file.NewUsageCache(),
 		logger: logger,
 	}
 }
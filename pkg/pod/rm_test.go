@@ -17,6 +17,8 @@ limitations under the License.
 package pod
 
 import (
+	"context"
+	"encoding/hex"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -58,6 +60,10 @@ func TestPod_RemoveFile(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 		podFile := createRandomFileInPod(t, 540, pod1, podName1, dirPath)
+		meta := info.getFile().GetFromFileMap(utils.PathSeperator + podName1 + podFile)
+		if meta == nil {
+			t.Fatalf("file %s not uploaded", podFile)
+		}
 
 		err = pod1.CopyToLocal(podName1, podFile, os.TempDir())
 		if err != nil {
@@ -78,6 +84,11 @@ func TestPod_RemoveFile(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+		for _, ref := range [][]byte{meta.MetaReference, meta.InodeAddress} {
+			if _, _, err := mockClient.DownloadBlob(ref); err == nil {
+				t.Fatalf("blob %x not deleted", ref)
+			}
+		}
 
 		os.Remove(fileInfo.Name())
 		err = pod1.DeletePod(podName1)
@@ -85,4 +96,102 @@ func TestPod_RemoveFile(t *testing.T) {
 			t.Fatalf("could not delete pod")
 		}
 	})
+
+	t.Run("remove_file_keeps_sub_directories", func(t *testing.T) {
+		_, err := pod1.CreatePod(podName1, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		for _, dirName := range []string{firstDir, firstDir + "/sub"} {
+			err = pod1.MakeDir(podName1, dirName)
+			if err != nil {
+				t.Fatalf("error creating directory %s", dirName)
+			}
+		}
+		dirPath := utils.PathSeperator + podName1 + utils.PathSeperator + firstDir
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, dirPath)
+
+		err = pod1.RemoveFile(podName1, podFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the parent is read again from the pod when it is opened
+		err = pod1.ClosePod(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := pod1.OpenPod(podName1, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.GetDirectory().GetDirFromDirectoryMap(dirPath+"/sub") == nil {
+			t.Fatalf("sub directory removed with the file")
+		}
+		if info.getFile().IsFileAlreadyPResent(podFile) {
+			t.Fatalf("file not removed")
+		}
+
+		err = pod1.DeletePod(podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+	t.Run("remove_received_file", func(t *testing.T) {
+		podName2 := "test2"
+		podName3 := "test3"
+		senderInfo, err := pod1.CreatePod(podName2, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName2)
+		}
+		podFile := createRandomFileInPod(t, 100, pod1, podName2, utils.PathSeperator+podName2)
+		meta := senderInfo.getFile().GetFromFileMap(utils.PathSeperator + podName2 + podFile)
+		if meta == nil {
+			t.Fatalf("file %s not uploaded", podFile)
+		}
+
+		info, err := pod1.CreatePod(podName3, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName3)
+		}
+		err = pod1.MakeDir(podName3, "inbox")
+		if err != nil {
+			t.Fatalf("error creating directory inbox")
+		}
+		err = pod1.ReceiveFileAndStore(podName3, "/inbox", meta.Name, hex.EncodeToString(meta.MetaReference))
+		if err != nil {
+			t.Fatal(err)
+		}
+		receivedFile := "/inbox/" + meta.Name
+		if info.getFile().GetFromFileMap(utils.PathSeperator+podName3+receivedFile) == nil {
+			t.Fatalf("file not received")
+		}
+
+		err = pod1.RemoveFile(podName3, receivedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.getFile().GetFromFileMap(utils.PathSeperator+podName3+receivedFile) != nil {
+			t.Fatalf("received file not removed from the file map")
+		}
+
+		// the blobs belong to the sender
+		for _, ref := range [][]byte{meta.MetaReference, meta.InodeAddress} {
+			if _, _, err := mockClient.DownloadBlob(ref); err != nil {
+				t.Fatalf("blob %x of the sender deleted", ref)
+			}
+		}
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName2, podFile)
+		if err != nil {
+			t.Fatalf("error downloading %s: %v", podFile, err)
+		}
+		defer reader.Close()
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 100 {
+			t.Fatalf("invalid size %d of %s", len(data), podFile)
+		}
+	})
 }
@@ -24,7 +24,11 @@ import (
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
-func (p *Pod) RemoveDir(podName, dirName string) error {
+// RemoveDir removes the directory dirName. A directory which is not empty is only
+// removed when recursive is set, together with everything below it. The directory
+// is first unlinked from its parent, so that it disappears at once, and then its
+// tree is deleted depth first, reporting every removal to progress if it is not nil.
+func (p *Pod) RemoveDir(podName, dirName string, recursive bool, progress func(*d.RemoveProgress)) error {
 	if !p.isPodOpened(podName) {
 		return ErrPodNotOpened
 	}
@@ -54,18 +58,31 @@ func (p *Pod) RemoveDir(podName, dirName string) error {
 	if info.IsCurrentDirRoot() {
 		topic = info.GetCurrentPodPathAndName() + utils.PathSeperator + dirName
 	}
+
+	// the cached inode may be stale, the one in Swarm tells what is in the directory
+	_, dirInode, err = directory.GetDirNode(topic, info.GetFeed(), info.GetAccountInfo())
+	if err != nil {
+		return err
+	}
+	if len(dirInode.Hashes) > 0 && !recursive {
+		return ErrDirectoryNotEmpty
+	}
+	tree, err := directory.LoadTree(topic, dirInode)
+	if err != nil {
+		return err
+	}
+
 	topicBytes := utils.HashString(topic)
 	err = p.UpdateTillThePod(podName, directory, topicBytes, dirInode.GetDirInodePathOnly(), false)
 	if err != nil {
 		return err
 	}
-	directory.GetPrefixPodFromPathMap(topic)
 
-	// delete the directory inode
-	err = info.dir.DeletePodInode(topic)
+	err = directory.RemoveTree(tree, progress)
 	if err != nil {
 		return err
 	}
+	directory.RemoveDirectoriesBelow(topic)
 	return nil
 }
 
@@ -17,11 +17,14 @@ limitations under the License.
 package pod
 
 import (
+	"context"
+	"encoding/hex"
 	"io/ioutil"
 	"testing"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
@@ -43,6 +46,9 @@ func TestPod_RemoveDir(t *testing.T) {
 	podName3 := "test3"
 	podName4 := "test4"
 	podName5 := "test5"
+	podName6 := "test6"
+	podName7 := "test7"
+	podName8 := "test8"
 	firstDir := "dir1"
 	secondDir := "dir2"
 	thirdAndFourthDir := "dir3/dir4"
@@ -63,7 +69,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName1, firstDir)
+		err = pod1.RemoveDir(podName1, firstDir, false, nil)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -104,7 +110,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName2, secondDir)
+		err = pod1.RemoveDir(podName2, secondDir, false, nil)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -141,7 +147,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName3, firstDir+utils.PathSeperator+secondDir)
+		err = pod1.RemoveDir(podName3, firstDir+utils.PathSeperator+secondDir, false, nil)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -187,7 +193,11 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName4, "dir3")
+		err = pod1.RemoveDir(podName4, "dir3", false, nil)
+		if err != ErrDirectoryNotEmpty {
+			t.Fatalf("non empty directory removed without recursive")
+		}
+		err = pod1.RemoveDir(podName4, "dir3", true, nil)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -225,7 +235,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName5, fifthDir)
+		err = pod1.RemoveDir(podName5, fifthDir, false, nil)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -241,4 +251,124 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("could not delete pod")
 		}
 	})
+	t.Run("rmdir-recursive-with-files", func(t *testing.T) {
+		info, err := pod1.CreatePod(podName6, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName6)
+		}
+
+		err = pod1.MakeDir(podName6, thirdAndFourthDir)
+		if err != nil {
+			t.Fatalf("error creating directory %s", thirdAndFourthDir)
+		}
+		thirdDirPath := utils.PathSeperator + podName6 + utils.PathSeperator + "dir3"
+		fourthDirPath := utils.PathSeperator + podName6 + utils.PathSeperator + thirdAndFourthDir
+		file1 := createRandomFileInPod(t, 100, pod1, podName6, thirdDirPath)
+		file2 := createRandomFileInPod(t, 200, pod1, podName6, fourthDirPath)
+		file3 := createRandomFileInPod(t, 300, pod1, podName6, fourthDirPath)
+
+		var refs [][]byte
+		for _, podFile := range []string{file1, file2, file3} {
+			meta := info.getFile().GetFromFileMap(utils.PathSeperator + podName6 + podFile)
+			if meta == nil {
+				t.Fatalf("file %s not uploaded", podFile)
+			}
+			refs = append(refs, meta.MetaReference, meta.InodeAddress)
+		}
+
+		var last d.RemoveProgress
+		calls := 0
+		err = pod1.RemoveDir(podName6, "dir3", true, func(progress *d.RemoveProgress) {
+			last = *progress
+			calls++
+		})
+		if err != nil {
+			t.Fatalf("error removing directory: %v", err)
+		}
+		if calls != 5 || last.FilesRemoved != 3 || last.DirsRemoved != 2 ||
+			last.TotalFiles != 3 || last.TotalDirs != 2 || last.Path != thirdDirPath {
+			t.Fatalf("invalid progress %+v after %d calls", last, calls)
+		}
+
+		// the whole subtree is gone, from the caches and from the store
+		if info.GetDirectory().GetDirFromDirectoryMap(thirdDirPath) != nil ||
+			info.GetDirectory().GetDirFromDirectoryMap(fourthDirPath) != nil {
+			t.Fatalf("directory not removed")
+		}
+		for _, podFile := range []string{file1, file2, file3} {
+			if info.getFile().GetFromFileMap(utils.PathSeperator+podName6+podFile) != nil {
+				t.Fatalf("file %s not removed from the file map", podFile)
+			}
+		}
+		for _, ref := range refs {
+			if _, _, err := mockClient.DownloadBlob(ref); err == nil {
+				t.Fatalf("blob %x not deleted", ref)
+			}
+		}
+		if len(info.GetCurrentPodInode().Hashes) != 0 {
+			t.Fatalf("directory not unlinked from the pod")
+		}
+
+		// cleanup pod
+		err = pod1.DeletePod(podName6)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("rmdir-keeps-received-files", func(t *testing.T) {
+		senderInfo, err := pod1.CreatePod(podName7, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName7)
+		}
+		podFile := createRandomFileInPod(t, 100, pod1, podName7, utils.PathSeperator+podName7)
+		meta := senderInfo.getFile().GetFromFileMap(utils.PathSeperator + podName7 + podFile)
+		if meta == nil {
+			t.Fatalf("file %s not uploaded", podFile)
+		}
+
+		info, err := pod1.CreatePod(podName8, "password", "")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName8)
+		}
+		err = pod1.MakeDir(podName8, "inbox")
+		if err != nil {
+			t.Fatalf("error creating directory inbox")
+		}
+		err = pod1.ReceiveFileAndStore(podName8, "/inbox", meta.Name, hex.EncodeToString(meta.MetaReference))
+		if err != nil {
+			t.Fatal(err)
+		}
+		receivedPath := utils.PathSeperator + podName8 + "/inbox/" + meta.Name
+		if info.getFile().GetFromFileMap(receivedPath) == nil {
+			t.Fatalf("file not received")
+		}
+
+		err = pod1.RemoveDir(podName8, "inbox", true, nil)
+		if err != nil {
+			t.Fatalf("error removing directory: %v", err)
+		}
+		if info.getFile().GetFromFileMap(receivedPath) != nil {
+			t.Fatalf("received file not removed from the file map")
+		}
+
+		// the blobs belong to the sender
+		for _, ref := range [][]byte{meta.MetaReference, meta.InodeAddress} {
+			if _, _, err := mockClient.DownloadBlob(ref); err != nil {
+				t.Fatalf("blob %x of the sender deleted", ref)
+			}
+		}
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName7, podFile)
+		if err != nil {
+			t.Fatalf("error downloading %s: %v", podFile, err)
+		}
+		defer reader.Close()
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 100 {
+			t.Fatalf("invalid size %d of %s", len(data), podFile)
+		}
+	})
 }
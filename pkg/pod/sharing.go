@@ -17,12 +17,15 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"path/filepath"
 	"time"
 
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
@@ -140,7 +143,8 @@ func (p *Pod) PodShare(podName, passPhrase, userName string) (string, error) {
 		return "", err
 	}
 
-	ref, err := p.client.UploadBlob(data, true, true)
+	// only the receiver knows the reference, so the blob is kept out of garbage collection
+	ref, err := blockstore.ToV2(p.client).UploadBlobContext(gc.Retain(context.Background()), data, true, true)
 	if err != nil {
 		return "", err
 	}
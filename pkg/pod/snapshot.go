@@ -0,0 +1,330 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	c "github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	snapshotFile = "Snapshots"
+)
+
+// Snapshot is the state of a pod frozen at a point in time. Reference is where its
+// manifest is stored.
+type Snapshot struct {
+	Name         string `json:"name"`
+	PodName      string `json:"pod_name"`
+	Reference    string `json:"reference"`
+	CreationTime string `json:"creation_time"`
+}
+
+// snapshotManifest is stored as the blob of a snapshot. Inodes holds the feed data of
+// every directory of the pod by the hex of the topic of its feed, which is enough to
+// find the files since their metas, inodes and blocks are never changed in place.
+type snapshotManifest struct {
+	Name         string
+	PodName      string
+	CreationTime int64
+	Inodes       map[string][]byte
+}
+
+// CreateSnapshot freezes the directories and files of the pod under the snapshot
+// name. The pod is marked to leave the blobs of deleted files to the garbage
+// collector from now on, which keeps the ones a snapshot uses.
+func (p *Pod) CreateSnapshot(podName, name string) (*Snapshot, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > utils.MaxPodNameLength {
+		return nil, ErrInvalidSnapshotName
+	}
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return nil, ErrReadOnlyPod
+	}
+
+	snapshots, _, err := p.loadSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	if findSnapshot(snapshots, name) != nil {
+		return nil, ErrSnapshotExists
+	}
+
+	directory := podInfo.GetDirectory()
+	_, podInode, err := directory.GetDirNode(utils.PathSeperator+podName, podInfo.GetFeed(), podInfo.GetAccountInfo())
+	if err != nil {
+		return nil, err
+	}
+	if !podInode.Meta.Snapshots {
+		podInode.Meta.Snapshots = true
+		_, err = directory.UpdateDirectory(podInode)
+		if err != nil {
+			return nil, err
+		}
+		podInfo.SetCurrentPodInode(podInode)
+		if podInfo.IsCurrentDirRoot() {
+			podInfo.SetCurrentDirInode(podInode)
+		}
+	}
+	podInfo.getFile().KeepBlobs(true)
+
+	manifest := &snapshotManifest{
+		Name:         name,
+		PodName:      podName,
+		CreationTime: time.Now().Unix(),
+		Inodes:       make(map[string][]byte),
+	}
+	err = directory.CollectInodes(utils.PathSeperator+podName, manifest.Inodes)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := p.client.UploadBlob(data, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Name:         name,
+		PodName:      podName,
+		Reference:    utils.NewReference(ref).String(),
+		CreationTime: strconv.FormatInt(manifest.CreationTime, 10),
+	}
+	err = p.storeSnapshots(append(snapshots, snapshot))
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots returns the snapshots of all the pods of the user, oldest first.
+func (p *Pod) ListSnapshots() ([]*Snapshot, error) {
+	snapshots, _, err := p.loadSnapshots()
+	return snapshots, err
+}
+
+// DeleteSnapshot forgets a snapshot. The blobs only it used are removed by the next
+// garbage collection.
+func (p *Pod) DeleteSnapshot(name string) error {
+	snapshots, _, err := p.loadSnapshots()
+	if err != nil {
+		return err
+	}
+	var kept []*Snapshot
+	for _, snapshot := range snapshots {
+		if snapshot.Name != name {
+			kept = append(kept, snapshot)
+		}
+	}
+	if len(kept) == len(snapshots) {
+		return ErrSnapshotNotFound
+	}
+	return p.storeSnapshots(kept)
+}
+
+// OpenSnapshot opens the pod of a snapshot as it was then, read only like a shared
+// pod. It is opened under the name of the pod, which can not be open at the same time.
+func (p *Pod) OpenSnapshot(name string) (*Info, error) {
+	snapshot, manifest, err := p.loadManifest(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.isPodOpened(manifest.PodName) {
+		return nil, ErrSnapshotPodOpened
+	}
+
+	podInfo, err := p.snapshotInfo(snapshot, manifest)
+	if err != nil {
+		return nil, err
+	}
+	p.addPodToPodMap(manifest.PodName, podInfo)
+	podInfo.GetDirectory().AddToDirectoryMap(manifest.PodName, podInfo.GetCurrentPodInode())
+
+	// sync the pod's files and directories
+	err = p.SyncPod(manifest.PodName)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo, nil
+}
+
+// RestoreSnapshot creates the pod podName with the directories and files of a
+// snapshot and opens it. The files share the inodes and blocks of the snapshot, so
+// nothing but metas and directories is uploaded.
+func (p *Pod) RestoreSnapshot(name, podName, passPhrase string) (*Info, error) {
+	snapshot, manifest, err := p.loadManifest(name)
+	if err != nil {
+		return nil, err
+	}
+	src, err := p.snapshotInfo(snapshot, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	podInfo, err := p.CreatePod(podName, passPhrase, "")
+	if err != nil {
+		return nil, err
+	}
+	podInode := podInfo.GetCurrentPodInode()
+	podInode.Hashes, err = src.GetDirectory().CopyEntries(src.GetCurrentPodPathAndName(), src.GetCurrentPodInode(),
+		podInfo.GetDirectory(), podInfo.GetCurrentPodPathAndName())
+	if err != nil {
+		return nil, err
+	}
+	_, err = podInfo.GetDirectory().UpdateDirectory(podInode)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo, nil
+}
+
+// snapshotInfo returns the read only structures of the pod of a snapshot, which find
+// the directories in the manifest instead of in their feeds.
+func (p *Pod) snapshotInfo(snapshot *Snapshot, manifest *snapshotManifest) (*Info, error) {
+	accountInfo := p.acc.GetEmptyAccountInfo()
+	fd := feed.NewPinned(manifest.Inodes, p.client, p.logger)
+	file := f.NewFile(manifest.PodName, p.client, fd, accountInfo, p.logger)
+	dir := d.NewDirectory(manifest.PodName, p.client, fd, accountInfo, file, p.logger)
+	_, dirInode, err := dir.GetDirNode(utils.PathSeperator+manifest.PodName, fd, accountInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	user := p.acc.GetAddress(account.UserAccountIndex)
+	return &Info{
+		podName:         manifest.PodName,
+		user:            user,
+		accountInfo:     accountInfo,
+		feed:            fd,
+		dir:             dir,
+		file:            file,
+		currentPodInode: dirInode,
+		curPodMu:        sync.RWMutex{},
+		currentDirInode: dirInode,
+		curDirMu:        sync.RWMutex{},
+		kvStore:         c.NewKeyValueStore(fd, accountInfo, user, p.client, p.logger),
+		docStore:        c.NewDocumentStore(fd, accountInfo, user, file, p.client, p.logger),
+		snapshot:        snapshot.Name,
+	}, nil
+}
+
+func (p *Pod) loadManifest(name string) (*Snapshot, *snapshotManifest, error) {
+	snapshots, _, err := p.loadSnapshots()
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := findSnapshot(snapshots, name)
+	if snapshot == nil {
+		return nil, nil, ErrSnapshotNotFound
+	}
+	manifest, err := p.downloadManifest(snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, manifest, nil
+}
+
+func (p *Pod) downloadManifest(snapshot *Snapshot) (*snapshotManifest, error) {
+	ref, err := utils.ParseHexReference(snapshot.Reference)
+	if err != nil {
+		return nil, err
+	}
+	data, respCode, err := p.client.DownloadBlob(ref.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("manifest of snapshot %s not found", snapshot.Name)
+	}
+	var manifest snapshotManifest
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// loadSnapshots returns the snapshots of the user and the reference of the blob
+// listing them, which the feed of the user points at.
+func (p *Pod) loadSnapshots() ([]*Snapshot, []byte, error) {
+	topic := utils.HashString(snapshotFile)
+	_, ref, err := p.fd.GetFeedData(topic, p.acc.GetAddress(account.UserAccountIndex))
+	if err != nil {
+		if err.Error() == "no feed updates found" {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	data, respCode, err := p.client.DownloadBlob(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("snapshot list not found")
+	}
+	var snapshots []*Snapshot
+	err = json.Unmarshal(data, &snapshots)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshots, ref, nil
+}
+
+func (p *Pod) storeSnapshots(snapshots []*Snapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+	ref, err := p.client.UploadBlob(data, true, true)
+	if err != nil {
+		return err
+	}
+	topic := utils.HashString(snapshotFile)
+	_, err = p.fd.UpdateFeed(topic, p.acc.GetAddress(account.UserAccountIndex), ref)
+	return err
+}
+
+func findSnapshot(snapshots []*Snapshot, name string) *Snapshot {
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return snapshot
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+func TestPod_Snapshot(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	ledger := gc.NewLedger(t.TempDir())
+	client := gc.NewRecordingClient(mockClient, ledger, "user1", logger)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+	collector := gc.NewCollector(ledger, mockClient, 0, logger)
+
+	podName1 := "test1"
+	podName2 := "test2"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	err = pod1.MakeDir(podName1, "/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldContent := bytes.Repeat([]byte("old"), 700)
+	for _, dir := range []string{"/", "/docs"} {
+		_, err = pod1.UploadFile(context.Background(), podName1, "a", int64(len(oldContent)), bytes.NewReader(oldContent), dir, "1024", "", "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		snapshot, err := pod1.CreateSnapshot(podName1, "s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.Name != "s1" || snapshot.PodName != podName1 || snapshot.Reference == "" {
+			t.Fatalf("unexpected snapshot %+v", snapshot)
+		}
+		_, err = pod1.CreateSnapshot(podName1, "s1")
+		if err != ErrSnapshotExists {
+			t.Fatalf("expected %v, got %v", ErrSnapshotExists, err)
+		}
+		_, err = pod1.CreateSnapshot(podName1, " ")
+		if err != ErrInvalidSnapshotName {
+			t.Fatalf("expected %v, got %v", ErrInvalidSnapshotName, err)
+		}
+		snapshots, err := pod1.ListSnapshots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != 1 || snapshots[0].Name != "s1" {
+			t.Fatalf("unexpected snapshots %+v", snapshots)
+		}
+	})
+
+	t.Run("survives_changes_and_gc", func(t *testing.T) {
+		err := pod1.RemoveFile(podName1, "/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		newContent := []byte("new")
+		_, err = pod1.UploadFile(context.Background(), podName1, "b", int64(len(newContent)), bytes.NewReader(newContent), "/docs", "1024", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		live := gc.NewLiveSet()
+		err = pod1.MarkLiveReferences("password", live.Mark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := collector.Sweep("user1", live, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Failed != 0 {
+			t.Fatalf("sweep failed: %+v", report)
+		}
+
+		_, err = pod1.OpenSnapshot("s1")
+		if err != ErrSnapshotPodOpened {
+			t.Fatalf("expected %v, got %v", ErrSnapshotPodOpened, err)
+		}
+		err = pod1.ClosePod(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := pod1.OpenSnapshot("s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.GetSnapshot() != "s1" {
+			t.Fatalf("invalid snapshot %s", info.GetSnapshot())
+		}
+		assertFileContent(t, pod1, podName1, "/a", oldContent)
+		assertFileContent(t, pod1, podName1, "/docs/a", oldContent)
+		if info.getFile().IsFileAlreadyPResent("/" + podName1 + "/docs/b") {
+			t.Fatalf("file uploaded after the snapshot is present")
+		}
+		err = pod1.MakeDir(podName1, "/new")
+		if err != ErrReadOnlyPod {
+			t.Fatalf("expected %v, got %v", ErrReadOnlyPod, err)
+		}
+		err = pod1.ClosePod(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		_, err := pod1.RestoreSnapshot("s1", podName2, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFileContent(t, pod1, podName2, "/a", oldContent)
+		assertFileContent(t, pod1, podName2, "/docs/a", oldContent)
+		_, err = pod1.RestoreSnapshot("s2", podName2, "password")
+		if err != ErrSnapshotNotFound {
+			t.Fatalf("expected %v, got %v", ErrSnapshotNotFound, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := pod1.DeleteSnapshot("s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.DeleteSnapshot("s1")
+		if err != ErrSnapshotNotFound {
+			t.Fatalf("expected %v, got %v", ErrSnapshotNotFound, err)
+		}
+		snapshots, err := pod1.ListSnapshots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != 0 {
+			t.Fatalf("snapshot not deleted")
+		}
+	})
+}
+
+func assertFileContent(t *testing.T, pod1 *Pod, podName, podFile string, content []byte) {
+	t.Helper()
+	reader, _, _, err := pod1.DownloadFile(context.Background(), podName, podFile)
+	if err != nil {
+		t.Fatalf("error downloading %s: %v", podFile, err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("invalid content of %s", podFile)
+	}
+}
@@ -34,6 +34,8 @@ type PodStat struct {
 	CreationTime     string
 	AccessTime       string
 	ModificationTime string
+	Versioning       bool
+	Snapshot         string
 }
 
 func (p *Pod) PodStat(podName string) (*PodStat, error) {
@@ -49,6 +51,8 @@ func (p *Pod) PodStat(podName string) (*PodStat, error) {
 		CreationTime:     strconv.FormatInt(podInode.Meta.CreationTime, 10),
 		AccessTime:       strconv.FormatInt(podInode.Meta.AccessTime, 10),
 		ModificationTime: strconv.FormatInt(podInode.Meta.AccessTime, 10),
+		Versioning:       podInode.Meta.Versioning,
+		Snapshot:         podInfo.GetSnapshot(),
 	}, nil
 }
 
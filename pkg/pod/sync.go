@@ -23,7 +23,6 @@ import (
 	"sync"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
-	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	m "github.com/fairdatasociety/fairOS-dfs/pkg/meta"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
@@ -87,8 +86,7 @@ func (pi *Info) SyncPod(podName string, client blockstore.Client, logger logging
 				return
 			}
 
-			var dirInode *d.DirInode
-			err = json.Unmarshal(data, &dirInode)
+			dirInode, err := pi.GetDirectory().DecodeDirInode(data)
 			if err != nil {
 				logger.Warningf("sync: unmarshall error: %w", err)
 				return
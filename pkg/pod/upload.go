@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -24,10 +25,11 @@ import (
 
 	"github.com/dustin/go-humanize"
 
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
-func (p *Pod) UploadFile(podName, fileName string, fileSize int64, fd io.Reader, podDir, blockSize, compression string) (string, error) {
+func (p *Pod) UploadFile(ctx context.Context, podName, fileName string, fileSize int64, fd io.Reader, podDir, blockSize, compression, chunking string) (string, error) {
 	if !p.isPodOpened(podName) {
 		return "", fmt.Errorf("login to pod to do this operation")
 	}
@@ -47,6 +49,10 @@ func (p *Pod) UploadFile(podName, fileName string, fileSize int64, fd io.Reader,
 	if err != nil {
 		return "", err
 	}
+	err = f.CheckBlockSize(bs)
+	if err != nil {
+		return "", err
+	}
 
 	path := p.getFilePath(podDir, podInfo)
 
@@ -55,15 +61,25 @@ func (p *Pod) UploadFile(podName, fileName string, fileSize int64, fd io.Reader,
 		return "", err
 	}
 
+	// a pod with versioning replaces the file and keeps the old one as a version
 	fpath := path + utils.PathSeperator + fileName
-	if podInfo.file.IsFileAlreadyPResent(fpath) {
+	previous := podInfo.file.GetFromFileMap(fpath)
+	if previous != nil && !podInfo.GetCurrentPodInode().Meta.Versioning {
 		return "", fmt.Errorf("file already present in the destination dir")
 	}
-	ref, err := podInfo.file.Upload(fd, fileName, fileSize, uint32(bs), fpath, compression)
+	ref, err := podInfo.file.Upload(ctx, fd, fileName, fileSize, uint32(bs), fpath, compression, chunking)
 	if err != nil {
 		return "", err
 	}
-	dirInode.Hashes = append(dirInode.Hashes, ref)
+	if previous != nil {
+		err = podInfo.file.AddVersion(fpath, previous, podInfo.file.GetFromFileMap(fpath))
+		if err != nil {
+			return "", err
+		}
+		dirInode.Hashes = replaceEntry(previous.MetaReference, ref)(dirInode.Hashes)
+	} else {
+		dirInode.Hashes = append(dirInode.Hashes, ref)
+	}
 
 	dirInode.Meta.ModificationTime = time.Now().Unix()
 	topic, err := dir.UpdateDirectory(dirInode)
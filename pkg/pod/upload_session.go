@@ -0,0 +1,107 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	gopath "path"
+
+	"github.com/dustin/go-humanize"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// NewUploadSession starts the upload of a file to podDir in blocks. The blocks are
+// sent with UploadSessionBlock in any order and the file is created by
+// FinishUploadSession once all of them arrived.
+func (p *Pod) NewUploadSession(podName, fileName string, fileSize int64, podDir, blockSize, compression string) (*f.UploadSession, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return nil, ErrReadOnlyPod
+	}
+
+	bs, err := humanize.ParseBytes(blockSize)
+	if err != nil {
+		return nil, err
+	}
+	if fileSize < 0 {
+		return nil, f.ErrInvalidFileSize
+	}
+	err = f.CheckBlockSize(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath := p.getFilePath(podDir, podInfo) + utils.PathSeperator + fileName
+	err = checkDestination(podInfo, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewUploadSession(podName, filePath, uint64(fileSize), uint32(bs), compression)
+}
+
+// UploadSessionBlock uploads the block with the given index of an upload session.
+func (p *Pod) UploadSessionBlock(ctx context.Context, podName string, session *f.UploadSession, index int, data []byte, checksum string) (*f.FileBlock, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return nil, ErrReadOnlyPod
+	}
+	return podInfo.getFile().UploadBlock(ctx, session, index, data, checksum)
+}
+
+// FinishUploadSession creates the file of an upload session and adds it to its
+// directory.
+func (p *Pod) FinishUploadSession(ctx context.Context, podName string, session *f.UploadSession) (string, error) {
+	if !p.isPodOpened(podName) {
+		return "", ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return "", err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return "", ErrReadOnlyPod
+	}
+
+	// the directory may have changed while the blocks were sent
+	err = checkDestination(podInfo, session.FilePath)
+	if err != nil {
+		return "", err
+	}
+	ref, err := podInfo.getFile().FinishUpload(ctx, session)
+	if err != nil {
+		return "", err
+	}
+	err = p.updateDirEntries(podName, podInfo, gopath.Dir(session.FilePath), addEntry(ref))
+	if err != nil {
+		return "", err
+	}
+	return utils.NewReference(ref).String(), nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_UploadSession(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	info, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	err = pod1.MakeDir(podName1, "dir1")
+	if err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+
+	content := make([]byte, 2500)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := [][]byte{content[:1000], content[1000:2000], content[2000:]}
+	checksum := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	ctx := context.Background()
+
+	t.Run("upload-blocks-out-of-order", func(t *testing.T) {
+		session, err := pod1.NewUploadSession(podName1, "file1", int64(len(content)), "/dir1", "1000", "snappy")
+		if err != nil {
+			t.Fatalf("error creating upload session: %v", err)
+		}
+		if session.NoOfBlocks() != 3 {
+			t.Fatalf("expected 3 blocks, got %d", session.NoOfBlocks())
+		}
+		for _, index := range []int{2, 0} {
+			block, err := pod1.UploadSessionBlock(ctx, podName1, session, index, blocks[index], checksum(blocks[index]))
+			if err != nil {
+				t.Fatalf("error uploading block %d: %v", index, err)
+			}
+			session.Blocks[index] = block
+		}
+		missing := session.MissingBlocks()
+		if len(missing) != 1 || missing[0] != 1 {
+			t.Fatalf("expected block 1 missing, got %v", missing)
+		}
+		_, err = pod1.FinishUploadSession(ctx, podName1, session)
+		if !errors.Is(err, f.ErrMissingBlocks) {
+			t.Fatalf("expected missing blocks, got %v", err)
+		}
+
+		block, err := pod1.UploadSessionBlock(ctx, podName1, session, 1, blocks[1], checksum(blocks[1]))
+		if err != nil {
+			t.Fatalf("error uploading block 1: %v", err)
+		}
+		session.Blocks[1] = block
+		_, err = pod1.FinishUploadSession(ctx, podName1, session)
+		if err != nil {
+			t.Fatalf("error finishing upload: %v", err)
+		}
+
+		assertEntries(t, info, podPath+"/dir1", "file1")
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/dir1/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("uploaded file differs")
+		}
+	})
+
+	t.Run("invalid-blocks", func(t *testing.T) {
+		session, err := pod1.NewUploadSession(podName1, "file2", int64(len(content)), "/dir1", "1000", "")
+		if err != nil {
+			t.Fatalf("error creating upload session: %v", err)
+		}
+		_, err = pod1.UploadSessionBlock(ctx, podName1, session, 0, blocks[0], checksum(blocks[1]))
+		if !errors.Is(err, f.ErrChecksumMismatch) {
+			t.Fatalf("expected checksum mismatch, got %v", err)
+		}
+		_, err = pod1.UploadSessionBlock(ctx, podName1, session, 1, blocks[2], checksum(blocks[2]))
+		if !errors.Is(err, f.ErrInvalidBlockLength) {
+			t.Fatalf("expected invalid block length, got %v", err)
+		}
+		_, err = pod1.UploadSessionBlock(ctx, podName1, session, 3, blocks[2], checksum(blocks[2]))
+		if !errors.Is(err, f.ErrInvalidBlockIndex) {
+			t.Fatalf("expected invalid block index, got %v", err)
+		}
+	})
+
+	t.Run("invalid-destination", func(t *testing.T) {
+		_, err := pod1.NewUploadSession(podName1, "file1", int64(len(content)), "/dir1", "1000", "")
+		if !errors.Is(err, ErrDestinationExists) {
+			t.Fatalf("expected destination exists, got %v", err)
+		}
+		_, err = pod1.NewUploadSession(podName1, "file1", int64(len(content)), "/dir2", "1000", "")
+		if !errors.Is(err, ErrFileOrDirNotFound) {
+			t.Fatalf("expected directory not found, got %v", err)
+		}
+	})
+}
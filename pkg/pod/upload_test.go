@@ -0,0 +1,436 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/beetest"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_UploadCDC(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	info, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+
+	// the same random part twice, so that the blocks of the second are shared
+	part := make([]byte, 64*1024)
+	_, err = rand.Read(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := append(append([]byte{}, part...), part...)
+	ctx := context.Background()
+
+	t.Run("upload-cdc", func(t *testing.T) {
+		_, err := pod1.UploadFile(ctx, podName1, "file1", int64(len(content)), bytes.NewReader(content), "/", "1024", "", f.ChunkingCDC)
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		meta := info.getFile().GetFromFileMap(podPath + "/file1")
+		if meta == nil || meta.Chunking != f.ChunkingCDC || meta.MinBlockSize != 256 || meta.MaxBlockSize != 4096 {
+			t.Fatalf("chunking not recorded in the meta")
+		}
+
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("downloaded file differs")
+		}
+	})
+
+	t.Run("seek-variable-blocks", func(t *testing.T) {
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		seeker := reader.(io.ReadSeeker)
+		for _, offset := range []int64{100000, 5, 70000, int64(len(content)) - 10} {
+			_, err = seeker.Seek(offset, io.SeekStart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 10)
+			_, err = io.ReadFull(seeker, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf, content[offset:offset+10]) {
+				t.Fatalf("contents after seek to %d differ", offset)
+			}
+		}
+	})
+
+	t.Run("stat-dedup-ratio", func(t *testing.T) {
+		stat, err := pod1.FileStat(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Chunking != f.ChunkingCDC {
+			t.Fatalf("chunking not in stat")
+		}
+		// everything but the blocks around the start of the second part is shared
+		ratio, err := strconv.ParseFloat(stat.DedupRatio, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ratio < 1.8 || ratio > 2 {
+			t.Fatalf("unexpected dedup ratio %s", stat.DedupRatio)
+		}
+	})
+}
+
+// TestPod_UploadCDCAcrossVersions uploads an edited file again over a fake bee node,
+// which encrypts with random keys like bee, to see that the unchanged blocks are stored
+// once.
+func TestPod_UploadCDCAcrossVersions(t *testing.T) {
+	srv, err := beetest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	logger := logging.New(ioutil.Discard, 0)
+	host, port := srv.HostPort()
+	client := bee.NewBeeClient(host, port, nil, logger)
+	acc := account.New(logger)
+	_, _, err = acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	info, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	err = pod1.SetVersioning(podName1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// the second version has a few bytes inserted in the middle
+	content := make([]byte, 128*1024)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := append(append(append([]byte{}, content[:60000]...), []byte("inserted")...), content[60000:]...)
+
+	blocks := func(t *testing.T) map[string]bool {
+		meta := info.getFile().GetFromFileMap(podPath + "/file1")
+		if meta == nil {
+			t.Fatalf("file not found")
+		}
+		data, _, err := client.DownloadBlob(meta.InodeAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var inode f.FileINode
+		err = json.Unmarshal(data, &inode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := make(map[string]bool)
+		for _, block := range inode.FileBlocks {
+			if len(block.Key) == 0 {
+				t.Fatalf("block not encrypted with a key of its own")
+			}
+			refs[string(block.Address)] = true
+		}
+		return refs
+	}
+
+	_, err = pod1.UploadFile(ctx, podName1, "file1", int64(len(content)), bytes.NewReader(content), "/", "1024", "", f.ChunkingCDC)
+	if err != nil {
+		t.Fatalf("error uploading file: %v", err)
+	}
+	first := blocks(t)
+	_, err = pod1.UploadFile(ctx, podName1, "file1", int64(len(edited)), bytes.NewReader(edited), "/", "1024", "", f.ChunkingCDC)
+	if err != nil {
+		t.Fatalf("error uploading file: %v", err)
+	}
+	second := blocks(t)
+
+	shared := 0
+	for ref := range second {
+		if first[ref] {
+			shared++
+		}
+	}
+	if shared < len(second)-4 {
+		t.Fatalf("only %d of %d blocks shared with the earlier upload", shared, len(second))
+	}
+
+	stat, err := pod1.FileStat(podName1, "/file1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratio, err := strconv.ParseFloat(stat.DedupRatio, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ratio < 1.8 {
+		t.Fatalf("unexpected dedup ratio %s", stat.DedupRatio)
+	}
+
+	for version, want := range map[uint32][]byte{1: content, 2: edited} {
+		reader, _, _, err := pod1.DownloadFileVersion(ctx, podName1, "/file1", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("version %d differs", version)
+		}
+	}
+
+	// a copy of the contents shares the blocks, removing it keeps them
+	err = pod1.SetVersioning(podName1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pod1.UploadFile(ctx, podName1, "file2", int64(len(edited)), bytes.NewReader(edited), "/", "1024", "", f.ChunkingCDC)
+	if err != nil {
+		t.Fatalf("error uploading file: %v", err)
+	}
+	err = pod1.RemoveFile(podName1, "/file2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range srv.Requests() {
+		if req.Method != http.MethodDelete {
+			continue
+		}
+		ref, err := hex.DecodeString(path.Base(req.Path))
+		if err == nil && second[string(ref)] {
+			t.Fatalf("shared block %s deleted", path.Base(req.Path))
+		}
+	}
+	assertFileContent(t, pod1, podName1, "/file1", edited)
+}
+
+func TestPod_UploadAutoCompression(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	ctx := context.Background()
+
+	uploadAndCheck := func(t *testing.T, name string, content []byte) *f.FileStats {
+		_, err := pod1.UploadFile(ctx, podName1, name, int64(len(content)), bytes.NewReader(content), "/", "1024", f.CompressionAuto, "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("downloaded file differs")
+		}
+		stat, err := pod1.FileStat(podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stat
+	}
+
+	t.Run("text-and-random-blocks", func(t *testing.T) {
+		// a block of text followed by a block of random data, only the first shrinks
+		random := make([]byte, 1024)
+		_, err := rand.Read(random)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := append(bytes.Repeat([]byte("plain text "), 1024/11+1)[:1024], random...)
+		stat := uploadAndCheck(t, "mixed", content)
+		if len(stat.Blocks) != 2 {
+			t.Fatalf("expected 2 blocks, got %d", len(stat.Blocks))
+		}
+		if stat.Blocks[0].Compression != "zstd" || stat.Blocks[1].Compression != "none" {
+			t.Fatalf("unexpected block compressions %s and %s", stat.Blocks[0].Compression, stat.Blocks[1].Compression)
+		}
+	})
+
+	t.Run("compressed-content-type", func(t *testing.T) {
+		// compressible data behind a jpeg signature is still not compressed
+		content := append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, 2048)...)
+		stat := uploadAndCheck(t, "image", content)
+		if stat.ContentType != "image/jpeg" {
+			t.Fatalf("unexpected content type %s", stat.ContentType)
+		}
+		for _, b := range stat.Blocks {
+			if b.Compression != "none" || b.Size != b.CompressedSize {
+				t.Fatalf("block %s of a jpeg compressed", b.Name)
+			}
+		}
+	})
+}
+
+func TestPod_UploadUnknownSize(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	info, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	ctx := context.Background()
+
+	// the data arrives through a pipe, like a stream from another program
+	stream := func(t *testing.T, name, chunking string, content []byte) {
+		pr, pw := io.Pipe()
+		go func() {
+			for i := 0; i < len(content); i += 700 {
+				end := i + 700
+				if end > len(content) {
+					end = len(content)
+				}
+				_, _ = pw.Write(content[i:end])
+			}
+			_ = pw.Close()
+		}()
+		_, err := pod1.UploadFile(ctx, podName1, name, f.UnknownFileSize, pr, "/", "1024", "", chunking)
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+
+		meta := info.getFile().GetFromFileMap(podPath + "/" + name)
+		if meta == nil || meta.FileSize != uint64(len(content)) {
+			t.Fatalf("file size not set at the end of the upload")
+		}
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("file contents do not match")
+		}
+		if chunking == "" {
+			stat, err := pod1.FileStat(podName1, "/"+name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, block := range stat.Blocks {
+				if i < len(stat.Blocks)-1 && block.Size != "1024" {
+					t.Fatalf("block %s is not full", block.Name)
+				}
+			}
+		}
+	}
+
+	content := make([]byte, 5000)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("upload-stream", func(t *testing.T) {
+		stream(t, "file1", "", content)
+	})
+
+	t.Run("upload-stream-cdc", func(t *testing.T) {
+		stream(t, "file2", f.ChunkingCDC, content)
+	})
+
+	t.Run("upload-empty-stream", func(t *testing.T) {
+		stream(t, "file3", "", nil)
+	})
+
+	t.Run("upload-short-known-size", func(t *testing.T) {
+		_, err := pod1.UploadFile(ctx, podName1, "file4", int64(len(content)+1), bytes.NewReader(content), "/", "1024", "", "")
+		if err == nil {
+			t.Fatal("upload shorter than its size succeeded")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"fmt"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+)
+
+// VerifyFile downloads a file of the pod and checks its blocks against their checksums.
+func (p *Pod) VerifyFile(ctx context.Context, podName, podFile string) (*f.VerifyResult, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+
+	path := p.getDirectoryPath(podFile, podInfo)
+	if !podInfo.getFile().IsFileAlreadyPResent(path) {
+		return nil, fmt.Errorf("file not present in pod")
+	}
+	return podInfo.getFile().Verify(ctx, path)
+}
+
+// VerifyPod verifies all the files of the pod.
+func (p *Pod) VerifyPod(ctx context.Context, podName string) ([]*f.VerifyResult, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo.getFile().VerifyAll(ctx)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_Verify(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	ctx := context.Background()
+
+	upload := func(t *testing.T, name string) []byte {
+		content := make([]byte, 4096)
+		_, err := rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pod1.UploadFile(ctx, podName1, name, int64(len(content)), bytes.NewReader(content), "/", "1024", "", "")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		return content
+	}
+	blockAddress := func(t *testing.T, name string, index int) []byte {
+		stat, err := pod1.FileStat(podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := hex.DecodeString(stat.Blocks[index].Reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+
+	t.Run("verify-ok", func(t *testing.T) {
+		content := upload(t, "file1")
+		stat, err := pod1.FileStat(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(content)
+		if stat.Checksum != hex.EncodeToString(sum[:]) {
+			t.Fatalf("unexpected file checksum %s", stat.Checksum)
+		}
+
+		result, err := pod1.VerifyFile(ctx, podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Ok {
+			t.Fatalf("file not verified: %+v", result)
+		}
+	})
+
+	t.Run("verify-corrupt-and-missing-blocks", func(t *testing.T) {
+		upload(t, "file2")
+
+		// replace the data of a block and delete another
+		corrupt := make([]byte, 1024)
+		_, err := mockClient.UploadChunk(swarm.NewChunk(swarm.NewAddress(blockAddress(t, "file2", 1)), corrupt), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mockClient.DeleteBlob(blockAddress(t, "file2", 3))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := pod1.VerifyFile(ctx, podName1, "/file2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Ok || len(result.CorruptBlocks) != 1 || result.CorruptBlocks[0] != "block-00001" ||
+			len(result.MissingBlocks) != 1 || result.MissingBlocks[0] != "block-00003" {
+			t.Fatalf("unexpected result %+v", result)
+		}
+
+		// reading the file fails at the corrupt block
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/file2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		_, err = ioutil.ReadAll(reader)
+		if !errors.Is(err, f.ErrChecksumMismatch) {
+			t.Fatalf("expected checksum mismatch, got %v", err)
+		}
+	})
+
+	t.Run("verify-pod", func(t *testing.T) {
+		results, err := pod1.VerifyPod(ctx, podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].FilePath != podPath+"/file1" || !results[0].Ok ||
+			results[1].FilePath != podPath+"/file2" || results[1].Ok {
+			t.Fatalf("unexpected results %+v", results)
+		}
+	})
+}
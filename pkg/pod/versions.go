@@ -0,0 +1,131 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"io"
+	gopath "path"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// SetVersioning turns the versioning of the files of a pod on or off. With versioning
+// a file uploaded over an existing one replaces it and the old one is kept as an
+// earlier version. Turning it off keeps the versions recorded so far.
+func (p *Pod) SetVersioning(podName string, enable bool) error {
+	if !p.isPodOpened(podName) {
+		return ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return ErrReadOnlyPod
+	}
+
+	directory := podInfo.GetDirectory()
+	_, podInode, err := directory.GetDirNode(utils.PathSeperator+podName, podInfo.GetFeed(), podInfo.GetAccountInfo())
+	if err != nil {
+		return err
+	}
+	podInode.Meta.Versioning = enable
+	if enable {
+		podInode.Meta.Versioned = true
+	}
+	_, err = directory.UpdateDirectory(podInode)
+	if err != nil {
+		return err
+	}
+	if enable {
+		podInfo.getFile().TrackVersions(true)
+	}
+	podInfo.SetCurrentPodInode(podInode)
+	if podInfo.IsCurrentDirRoot() {
+		podInfo.SetCurrentDirInode(podInode)
+	}
+	return nil
+}
+
+// ListFileVersions lists the versions of a file of the pod, oldest first.
+func (p *Pod) ListFileVersions(podName, podFile string) ([]*f.VersionInfo, error) {
+	podInfo, path, err := p.versionedFile(podName, podFile)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo.getFile().ListVersions(path)
+}
+
+// DownloadFileVersion returns a reader for a version of a file of the pod.
+func (p *Pod) DownloadFileVersion(ctx context.Context, podName, podFile string, version uint32) (io.ReadCloser, string, string, error) {
+	podInfo, path, err := p.versionedFile(podName, podFile)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return podInfo.getFile().OpenVersionForReading(ctx, path, version)
+}
+
+// RestoreFileVersion makes a version of a file of the pod its current one again, as
+// the newest version of the file.
+func (p *Pod) RestoreFileVersion(podName, podFile string, version uint32) error {
+	podInfo, path, err := p.versionedFile(podName, podFile)
+	if err != nil {
+		return err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return ErrReadOnlyPod
+	}
+
+	current := podInfo.getFile().GetFromFileMap(path)
+	metaReference, err := podInfo.getFile().RestoreVersion(path, version)
+	if err != nil {
+		return err
+	}
+	return p.updateDirEntries(podName, podInfo, gopath.Dir(path), replaceEntry(current.MetaReference, metaReference))
+}
+
+// PruneFileVersions drops all but the newest keep versions of a file of the pod. It
+// returns the number of versions dropped.
+func (p *Pod) PruneFileVersions(podName, podFile string, keep int) (int, error) {
+	podInfo, path, err := p.versionedFile(podName, podFile)
+	if err != nil {
+		return 0, err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return 0, ErrReadOnlyPod
+	}
+	return podInfo.getFile().PruneVersions(path, keep)
+}
+
+// versionedFile returns the info of an open pod and the path of one of its files.
+func (p *Pod) versionedFile(podName, podFile string) (*Info, string, error) {
+	if !p.isPodOpened(podName) {
+		return nil, "", ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, "", err
+	}
+	path := p.getDirectoryPath(podFile, podInfo)
+	if !podInfo.getFile().IsFileAlreadyPResent(path) {
+		return nil, "", fmt.Errorf("file not present in pod")
+	}
+	return podInfo, path, nil
+}
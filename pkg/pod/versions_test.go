@@ -0,0 +1,279 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+)
+
+func TestPod_Versions(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	_, err = pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	ctx := context.Background()
+
+	upload := func(t *testing.T, name, podDir string) ([]byte, error) {
+		content := make([]byte, 4096)
+		_, err := rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pod1.UploadFile(ctx, podName1, name, int64(len(content)), bytes.NewReader(content), podDir, "1024", "", "")
+		return content, err
+	}
+	download := func(t *testing.T, reader io.ReadCloser) []byte {
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	current := func(t *testing.T, podFile string) []byte {
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, podFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return download(t, reader)
+	}
+	version := func(t *testing.T, podFile string, v uint32) []byte {
+		reader, _, _, err := pod1.DownloadFileVersion(ctx, podName1, podFile, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return download(t, reader)
+	}
+
+	var v1, v2 []byte
+	t.Run("overwrite-without-versioning", func(t *testing.T) {
+		v1, err = upload(t, "file1", "/")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		_, err = upload(t, "file1", "/")
+		if err == nil {
+			t.Fatal("file overwritten without versioning")
+		}
+
+		versions, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 || versions[0].Version != "1" || !versions[0].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+	})
+
+	t.Run("upload-new-version", func(t *testing.T) {
+		err := pod1.SetVersioning(podName1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := pod1.PodStat(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stat.Versioning {
+			t.Fatal("versioning not set in the pod stat")
+		}
+
+		v2, err = upload(t, "file1", "/")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		versions, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 || versions[0].Current || versions[1].Version != "2" || !versions[1].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+		if !bytes.Equal(current(t, "/file1"), v2) || !bytes.Equal(version(t, "/file1", 1), v1) {
+			t.Fatal("version contents do not match")
+		}
+
+		// the directory lists the file once
+		entries, err := pod1.ListEntiesInDir(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("unexpected directory entries %v", entries)
+		}
+	})
+
+	t.Run("restore-version", func(t *testing.T) {
+		err := pod1.RestoreFileVersion(podName1, "/file1", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 3 || versions[2].Version != "3" || !versions[2].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+		if !bytes.Equal(current(t, "/file1"), v1) {
+			t.Fatal("restored contents do not match")
+		}
+
+		_, _, _, err = pod1.DownloadFileVersion(ctx, podName1, "/file1", 4)
+		if err == nil {
+			t.Fatal("downloaded a version which does not exist")
+		}
+	})
+
+	t.Run("prune-versions", func(t *testing.T) {
+		// a shared older version stays readable for the receiver
+		podInfo, err := pod1.GetPodInfoFromPodMap(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shared, err := podInfo.getFile().GetVersionMeta("/"+podName1+"/file1", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pruned, err := pod1.PruneFileVersions(podName1, "/file1", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pruned != 2 {
+			t.Fatalf("expected 2 versions pruned, got %d", pruned)
+		}
+		versions, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 || versions[0].Version != "3" || !versions[0].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+		if !bytes.Equal(current(t, "/file1"), v1) {
+			t.Fatal("current contents changed by prune")
+		}
+		_, _, err = mockClient.DownloadBlob(shared.MetaReference)
+		if err != nil {
+			t.Fatalf("meta of a pruned version deleted: %v", err)
+		}
+	})
+
+	t.Run("move-keeps-versions", func(t *testing.T) {
+		_, err := upload(t, "file1", "/")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		err = pod1.MakeDir(podName1, "dir1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.Move(podName1, "/file1", "/dir1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err := pod1.ListFileVersions(podName1, "/dir1/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 || !versions[1].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+		if !bytes.Equal(version(t, "/dir1/file1", 3), v1) {
+			t.Fatal("moved version contents do not match")
+		}
+	})
+
+	t.Run("remove-drops-versions", func(t *testing.T) {
+		err := pod1.RemoveFile(podName1, "/dir1/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := upload(t, "file1", "/dir1")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		versions, err := pod1.ListFileVersions(podName1, "/dir1/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 || versions[0].Version != "1" || !versions[0].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+		if !bytes.Equal(version(t, "/dir1/file1", 1), content) {
+			t.Fatal("version contents do not match")
+		}
+	})
+	t.Run("remove-drops-versions-after-versioning-off", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := upload(t, "file2", "/")
+			if err != nil {
+				t.Fatalf("error uploading file: %v", err)
+			}
+		}
+		err := pod1.SetVersioning(podName1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the pod is read again when it is opened
+		err = pod1.ClosePod(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pod1.OpenPod(podName1, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.RemoveFile(podName1, "/file2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = upload(t, "file2", "/")
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+		versions, err := pod1.ListFileVersions(podName1, "/file2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 || versions[0].Version != "1" || !versions[0].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"io"
+	gopath "path"
+)
+
+// AppendFile writes the data from fd at the end of a file of the pod.
+func (p *Pod) AppendFile(ctx context.Context, podName, podFile string, fd io.Reader) error {
+	return p.writeFile(podName, podFile, func(podInfo *Info, path string) ([]byte, error) {
+		return podInfo.getFile().Append(ctx, path, fd)
+	})
+}
+
+// WriteFileAt writes the data from fd over a file of the pod starting at offset, the
+// file grows if the data goes past its end.
+func (p *Pod) WriteFileAt(ctx context.Context, podName, podFile string, offset int64, fd io.Reader) error {
+	return p.writeFile(podName, podFile, func(podInfo *Info, path string) ([]byte, error) {
+		return podInfo.getFile().WriteAt(ctx, path, offset, fd)
+	})
+}
+
+// writeFile changes a file of the pod with write and points its directory to the new
+// meta. With versioning the file before the change is kept as a version, otherwise
+// the blobs the change made unused are deleted.
+func (p *Pod) writeFile(podName, podFile string, write func(podInfo *Info, path string) ([]byte, error)) error {
+	podInfo, path, err := p.versionedFile(podName, podFile)
+	if err != nil {
+		return err
+	}
+	if podInfo.accountInfo.IsReadOnlyPod() {
+		return ErrReadOnlyPod
+	}
+
+	previous := podInfo.getFile().GetFromFileMap(path)
+	ref, err := write(podInfo, path)
+	if err != nil {
+		return err
+	}
+	if string(ref) == string(previous.MetaReference) {
+		return nil
+	}
+	current := podInfo.getFile().GetFromFileMap(path)
+	versioning := podInfo.GetCurrentPodInode().Meta.Versioning
+	if versioning {
+		err = podInfo.getFile().AddVersion(path, previous, current)
+		if err != nil {
+			return err
+		}
+	}
+	err = p.updateDirEntries(podName, podInfo, gopath.Dir(path), replaceEntry(previous.MetaReference, ref))
+	if err != nil {
+		return err
+	}
+	if versioning {
+		return nil
+	}
+	return podInfo.getFile().DeleteReplaced(gopath.Dir(path), previous, current)
+}
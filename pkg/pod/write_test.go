@@ -0,0 +1,296 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+func TestPod_Write(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podPath := utils.PathSeperator + podName1
+	info, err := pod1.CreatePod(podName1, "password", "")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	ctx := context.Background()
+
+	random := func(t *testing.T, size int) []byte {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	upload := func(t *testing.T, name string, content []byte, compression, chunking string) {
+		_, err := pod1.UploadFile(ctx, podName1, name, int64(len(content)), bytes.NewReader(content), "/", "1024", compression, chunking)
+		if err != nil {
+			t.Fatalf("error uploading file: %v", err)
+		}
+	}
+	check := func(t *testing.T, name string, content []byte) {
+		reader, _, _, err := pod1.DownloadFile(ctx, podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("file contents do not match")
+		}
+		meta := info.getFile().GetFromFileMap(podPath + "/" + name)
+		if meta.FileSize != uint64(len(content)) {
+			t.Fatalf("expected file size %d, got %d", len(content), meta.FileSize)
+		}
+		result, err := pod1.VerifyFile(ctx, podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Ok {
+			t.Fatalf("file not verified: %+v", result)
+		}
+	}
+	blockRefs := func(t *testing.T, name string) []string {
+		stat, err := pod1.FileStat(podName1, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := make([]string, 0, len(stat.Blocks))
+		for _, block := range stat.Blocks {
+			refs = append(refs, block.Reference)
+		}
+		return refs
+	}
+
+	present := func(ref []byte) bool {
+		_, _, err := mockClient.DownloadBlob(ref)
+		return err == nil
+	}
+	inodeBlocks := func(t *testing.T, inodeAddress []byte) []*f.FileBlock {
+		data, _, err := mockClient.DownloadBlob(inodeAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var inode f.FileINode
+		err = json.Unmarshal(data, &inode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inode.FileBlocks
+	}
+
+	t.Run("append", func(t *testing.T) {
+		content := random(t, 3000)
+		upload(t, "file1", content, "", "")
+		before := blockRefs(t, "file1")
+		previous := info.getFile().GetFromFileMap(podPath + "/file1")
+		previousBlocks := inodeBlocks(t, previous.InodeAddress)
+
+		data := random(t, 1500)
+		err := pod1.AppendFile(ctx, podName1, "/file1", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = append(content, data...)
+		check(t, "file1", content)
+
+		// only the partial last block is uploaded again
+		after := blockRefs(t, "file1")
+		if len(after) != 5 || after[0] != before[0] || after[1] != before[1] || after[2] == before[2] {
+			t.Fatalf("unexpected blocks %v, before %v", after, before)
+		}
+
+		// without versioning the replaced blobs are deleted, the kept blocks stay
+		for _, ref := range [][]byte{previous.MetaReference, previous.InodeAddress, previousBlocks[2].Address} {
+			if present(ref) {
+				t.Fatalf("replaced blob %x not deleted", ref)
+			}
+		}
+		for _, block := range previousBlocks[:2] {
+			if !present(block.Address) {
+				t.Fatalf("kept block %x deleted", block.Address)
+			}
+		}
+
+		entries, err := pod1.ListEntiesInDir(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("unexpected directory entries %v", entries)
+		}
+	})
+
+	t.Run("write-at", func(t *testing.T) {
+		content := random(t, 4096)
+		upload(t, "file2", content, "", "")
+		before := blockRefs(t, "file2")
+
+		data := random(t, 200)
+		err := pod1.WriteFileAt(ctx, podName1, "/file2", 1000, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		copy(content[1000:], data)
+		check(t, "file2", content)
+
+		after := blockRefs(t, "file2")
+		if len(after) != 4 || after[0] == before[0] || after[1] == before[1] || after[2] != before[2] || after[3] != before[3] {
+			t.Fatalf("unexpected blocks %v, before %v", after, before)
+		}
+	})
+
+	t.Run("write-past-end", func(t *testing.T) {
+		content := random(t, 2048)
+		upload(t, "file3", content, "", "")
+
+		data := random(t, 1000)
+		err := pod1.WriteFileAt(ctx, podName1, "/file3", 1500, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = append(content[:1500], data...)
+		check(t, "file3", content)
+
+		err = pod1.WriteFileAt(ctx, podName1, "/file3", int64(len(content))+1, bytes.NewReader(data))
+		if !errors.Is(err, f.ErrInvalidOffset) {
+			t.Fatalf("expected invalid offset, got %v", err)
+		}
+	})
+
+	t.Run("append-compressed-and-cdc", func(t *testing.T) {
+		content := random(t, 3000)
+		upload(t, "file4", content, "snappy", "")
+		data := random(t, 3000)
+		err := pod1.AppendFile(ctx, podName1, "/file4", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, "file4", append(content, data...))
+
+		content = random(t, 8192)
+		upload(t, "file5", content, "", f.ChunkingCDC)
+		err = pod1.AppendFile(ctx, podName1, "/file5", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = append(content, data...)
+		check(t, "file5", content)
+
+		err = pod1.WriteFileAt(ctx, podName1, "/file5", 4000, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		copy(content[4000:], data)
+		check(t, "file5", content)
+	})
+
+	t.Run("write-shared-copy", func(t *testing.T) {
+		content := random(t, 2048)
+		upload(t, "file7", content, "", "")
+		err := pod1.Copy(podName1, "/file7", "", "/file8")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the copy keeps the old inode and blocks
+		data := random(t, 1024)
+		err = pod1.AppendFile(ctx, podName1, "/file7", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, "file7", append(append([]byte{}, content...), data...))
+		check(t, "file8", content)
+		if !info.getFile().GetFromFileMap(podPath + "/file7").SharedInode {
+			t.Fatal("blocks kept from a shared inode not marked as shared")
+		}
+
+		// a write which keeps no block of the shared inode is not shared any more
+		data = random(t, 3072)
+		err = pod1.WriteFileAt(ctx, podName1, "/file7", 0, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, "file7", data)
+		check(t, "file8", content)
+		if info.getFile().GetFromFileMap(podPath + "/file7").SharedInode {
+			t.Fatal("rewritten file still marked as shared")
+		}
+	})
+
+	t.Run("write-with-versioning", func(t *testing.T) {
+		err := pod1.SetVersioning(podName1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := random(t, 1000)
+		upload(t, "file6", content, "", "")
+		data := random(t, 100)
+		err = pod1.AppendFile(ctx, podName1, "/file6", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, "file6", append(append([]byte{}, content...), data...))
+
+		versions, err := pod1.ListFileVersions(podName1, "/file6")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 || versions[0].FileSize != "1000" || !versions[1].Current {
+			t.Fatalf("unexpected versions %+v", versions)
+		}
+		reader, _, _, err := pod1.DownloadFileVersion(ctx, podName1, "/file6", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatal("old version changed by the append")
+		}
+	})
+}
@@ -37,8 +37,14 @@ func (u *Users) DeleteUser(userName, dataDir, password, sessionId string, respon
 		return ErrInvalidPassword
 	}
 
+	// remove the blobs of the user, nobody can reach them once the user is gone
+	err := u.collectAllGarbage(userInfo)
+	if err != nil {
+		return err
+	}
+
 	// Logout user
-	err := u.Logout(sessionId, response)
+	err = u.Logout(sessionId, response)
 	if err != nil {
 		return err
 	}
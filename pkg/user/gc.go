@@ -0,0 +1,121 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	gcDirectoryName = "gc"
+)
+
+// CollectGarbage removes the blobs uploaded for the user which are no longer
+// referenced from the pods, the collections or the sharing boxes of the user. With
+// dryRun nothing is removed and the report tells what would be reclaimed. The
+// password is needed to open the pods of the user.
+func (u *Users) CollectGarbage(userInfo *Info, passPhrase string, dryRun bool) (*gc.Report, error) {
+	if passPhrase == "" || !userInfo.account.Authorise(passPhrase) {
+		return nil, ErrInvalidPassword
+	}
+
+	live := gc.NewLiveSet()
+	err := userInfo.GetPod().MarkLiveReferences(passPhrase, live.Mark)
+	if err != nil {
+		return nil, err
+	}
+	err = u.markSharingBoxes(userInfo, live)
+	if err != nil {
+		return nil, err
+	}
+	err = u.markUploadSessions(userInfo, live)
+	if err != nil {
+		return nil, err
+	}
+	return u.collector.Sweep(userInfo.name, live, dryRun)
+}
+
+// collectAllGarbage removes every blob uploaded for a user who is being deleted,
+// except the files the user shared with others.
+func (u *Users) collectAllGarbage(userInfo *Info) error {
+	live := gc.NewLiveSet()
+	err := u.markSharingBoxes(userInfo, live)
+	if err != nil {
+		return err
+	}
+	report, err := u.collector.SweepAll(userInfo.name, live)
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		u.logger.Warningf("delete user: %d blobs of %s could not be removed", report.Failed, userInfo.name)
+	}
+	err = u.uploads.Drop(userInfo.name)
+	if err != nil {
+		return err
+	}
+	return u.collector.Ledger().Drop(userInfo.name)
+}
+
+// markSharingBoxes marks the inbox and the outbox as live, together with the files
+// listed in the outbox, since the receivers link to them from their own pods.
+func (u *Users) markSharingBoxes(userInfo *Info, live gc.LiveSet) error {
+	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
+	inboxRef, err := getFeedData(inboxFeedName, rootReference, userInfo.GetFeed())
+	if err != nil {
+		return err
+	}
+	live.Mark(inboxRef)
+
+	outboxRef, err := getFeedData(outboxFeedName, rootReference, userInfo.GetFeed())
+	if err != nil {
+		return err
+	}
+	if len(outboxRef) < utils.ReferenceLength {
+		return nil
+	}
+	outboxFileBytes, respCode, err := u.client.DownloadBlob(outboxRef)
+	if err != nil {
+		return err
+	}
+	if respCode != http.StatusOK {
+		return ErrSharingBoxNotFound
+	}
+	outbox := &Outbox{}
+	err = json.Unmarshal(outboxFileBytes, outbox)
+	if err != nil {
+		return err
+	}
+	live.Mark(outboxRef)
+
+	for _, entry := range outbox.Entries {
+		metaRef, err := utils.ParseHexReference(entry.FileMetaHash)
+		if err != nil {
+			return err
+		}
+		err = userInfo.file.MarkLiveReferences(metaRef.Bytes(), live.Mark)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
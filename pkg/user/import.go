@@ -34,6 +34,11 @@ func (u *Users) ImportUsingAddress(userName, passPhrase, addressString, dataDir
 		return ErrUserAlreadyPresent
 	}
 
+	if sessionId == "" {
+		sessionId = cookie.GetUniqueSessionId()
+	}
+	client = u.sessionClient(client, userName, sessionId)
+
 	acc := account.New(u.logger)
 	accountInfo := acc.GetUserAccountInfo()
 	fd := feed.New(accountInfo, client, u.logger)
@@ -61,10 +66,6 @@ func (u *Users) ImportUsingAddress(userName, passPhrase, addressString, dataDir
 	}
 	dir := d.NewDirectory(userName, client, fd, accountInfo, file, u.logger)
 
-	if sessionId == "" {
-		sessionId = cookie.GetUniqueSessionId()
-	}
-
 	ui := &Info{
 		name:      userName,
 		sessionId: sessionId,
@@ -72,7 +73,7 @@ func (u *Users) ImportUsingAddress(userName, passPhrase, addressString, dataDir
 		account:   acc,
 		file:      file,
 		dir:       dir,
-		pods:      pod.NewPod(u.client, fd, acc, u.logger),
+		pods:      pod.NewPod(client, fd, acc, u.logger),
 	}
 
 	// set cookie and add user to map
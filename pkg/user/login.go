@@ -37,6 +37,11 @@ func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstor
 		return ErrInvalidUserName
 	}
 
+	if sessionId == "" {
+		sessionId = cookie.GetUniqueSessionId()
+	}
+	client = u.sessionClient(client, userName, sessionId)
+
 	acc := account.New(u.logger)
 	accountInfo := acc.GetUserAccountInfo()
 	fd := feed.New(accountInfo, client, u.logger)
@@ -63,10 +68,6 @@ func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstor
 	}
 	dir := d.NewDirectory(userName, client, fd, accountInfo, file, u.logger)
 
-	if sessionId == "" {
-		sessionId = cookie.GetUniqueSessionId()
-	}
-
 	ui := &Info{
 		name:      userName,
 		sessionId: sessionId,
@@ -74,7 +75,7 @@ func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstor
 		account:   acc,
 		file:      file,
 		dir:       dir,
-		pods:      pod.NewPod(u.client, fd, acc, u.logger),
+		pods:      pod.NewPod(client, fd, acc, u.logger),
 	}
 
 	// set cookie and add user to map
@@ -32,6 +32,11 @@ func (u *Users) CreateNewUser(userName, passPhrase, mnemonic, dataDir string, cl
 	if u.IsUsernameAvailable(userName, dataDir) {
 		return "", "", nil, ErrUserAlreadyPresent
 	}
+	if sessionId == "" {
+		sessionId = cookie.GetUniqueSessionId()
+	}
+	client = u.sessionClient(client, userName, sessionId)
+
 	acc := account.New(u.logger)
 	accountInfo := acc.GetUserAccountInfo()
 	fd := feed.New(accountInfo, client, u.logger)
@@ -56,10 +61,6 @@ func (u *Users) CreateNewUser(userName, passPhrase, mnemonic, dataDir string, cl
 
 	dir := d.NewDirectory(userName, client, fd, accountInfo, file, u.logger)
 
-	if sessionId == "" {
-		sessionId = cookie.GetUniqueSessionId()
-	}
-
 	ui := &Info{
 		name:      userName,
 		sessionId: sessionId,
@@ -67,7 +68,7 @@ func (u *Users) CreateNewUser(userName, passPhrase, mnemonic, dataDir string, cl
 		account:   acc,
 		file:      file,
 		dir:       dir,
-		pods:      pod.NewPod(u.client, fd, acc, u.logger),
+		pods:      pod.NewPod(client, fd, acc, u.logger),
 	}
 
 	// set cookie and add user to map
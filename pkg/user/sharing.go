@@ -110,8 +110,8 @@ func (u *Users) ShareFileWithUser(podName, podFilePath, destinationRef string, u
 		return "", err
 	}
 
-	// store the new outbox file data
-	newOutboxRef, err := u.client.UploadBlob(outData, true, true)
+	// store the new outbox file data, the old one is left to garbage collection
+	newOutboxRef, err := pod.GetClient().UploadBlob(outData, true, true)
 	if err != nil {
 		return "", err
 	}
@@ -201,8 +201,8 @@ func (u *Users) ReceiveFileFromUser(podName string, sharingRef utils.SharingRefe
 		return "", "", err
 	}
 
-	// store the new inbox file data
-	newInboxRef, err := u.client.UploadBlob(inData, true, true)
+	// store the new inbox file data, the old one is left to garbage collection
+	newInboxRef, err := pod.GetClient().UploadBlob(inData, true, true)
 	if err != nil {
 		return "", "", err
 	}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
+)
+
+const (
+	uploadsDirectoryName = "uploads"
+)
+
+// GetUploadSessions returns the store of the upload sessions of all the users.
+func (u *Users) GetUploadSessions() *f.SessionStore {
+	return u.uploads
+}
+
+// markUploadSessions marks the blocks of the unfinished uploads of the user as live,
+// nothing links to them till the upload is finished. Expired sessions are dropped on
+// the way, so their blocks are collected.
+func (u *Users) markUploadSessions(userInfo *Info, live gc.LiveSet) error {
+	sessions, err := u.uploads.Sessions(userInfo.name)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		for _, block := range session.Blocks {
+			live.Mark(block.Address)
+		}
+	}
+	return nil
+}
@@ -17,13 +17,16 @@ limitations under the License.
 package user
 
 import (
+	"path/filepath"
 	"sync"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/blockstore/postage"
 	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/gc"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
 )
@@ -42,16 +45,22 @@ type Info struct {
 type Users struct {
 	dataDir      string
 	client       blockstore.Client
+	batches      *postage.Manager
+	collector    *gc.Collector
+	uploads      *f.SessionStore
 	userMap      map[string]*Info
 	userMu       *sync.RWMutex
 	cookieDomain string
 	logger       logging.Logger
 }
 
-func NewUsers(dataDir string, client blockstore.Client, cookieDomain string, logger logging.Logger) *Users {
+func NewUsers(dataDir string, client blockstore.Client, batches *postage.Manager, cookieDomain string, logger logging.Logger) *Users {
 	return &Users{
 		dataDir:      dataDir,
 		client:       client,
+		batches:      batches,
+		collector:    gc.NewCollector(gc.NewLedger(filepath.Join(dataDir, gcDirectoryName)), client, gc.DefaultMinAge, logger),
+		uploads:      f.NewSessionStore(filepath.Join(dataDir, uploadsDirectoryName)),
 		userMap:      make(map[string]*Info),
 		userMu:       &sync.RWMutex{},
 		cookieDomain: cookieDomain,
@@ -59,6 +68,23 @@ func NewUsers(dataDir string, client blockstore.Client, cookieDomain string, log
 	}
 }
 
+// sessionClient returns the client used for everything the user of the session does.
+// Its blob uploads are recorded for garbage collection and use the postage batch
+// chosen for the user and the pod the user works on.
+func (u *Users) sessionClient(client blockstore.Client, userName, sessionId string) blockstore.Client {
+	client = gc.NewRecordingClient(client, u.collector.Ledger(), userName, u.logger)
+	if u.batches == nil {
+		return client
+	}
+	return postage.NewBatchClient(client, func() string {
+		podName := ""
+		if ui := u.getUserFromMap(sessionId); ui != nil {
+			podName = ui.GetPodName()
+		}
+		return u.batches.Resolve(userName, podName)
+	})
+}
+
 func (u *Users) addUserToMap(info *Info) {
 	u.userMu.Lock()
 	defer u.userMu.Unlock()